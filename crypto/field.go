@@ -0,0 +1,268 @@
+package crypto
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// fieldVal is an element of the secp256k1 base field stored as four 64 bits
+// little-endian limbs. All operations keep values fully reduced modulo P and
+// run in constant time with respect to the values involved.
+type fieldVal [4]uint64
+
+// fieldP is the field prime 2^256 - 2^32 - 977.
+var fieldP = fieldVal{0xFFFFFFFEFFFFFC2F, 0xFFFFFFFFFFFFFFFF, 0xFFFFFFFFFFFFFFFF, 0xFFFFFFFFFFFFFFFF}
+
+// fieldC is 2^256 mod P, used to fold the high half of products.
+const fieldC = 0x1000003D1
+
+var (
+	fieldOne = fieldVal{1, 0, 0, 0}
+	fieldB3  = fieldVal{21, 0, 0, 0} // 3 * b, used by the point formulas
+)
+
+// setBytes sets f to the big-endian value b reduced modulo P.
+func (f *fieldVal) setBytes(b *[32]byte) *fieldVal {
+	for i := 0; i < 4; i++ {
+		f[i] = uint64(b[31-8*i]) | uint64(b[30-8*i])<<8 | uint64(b[29-8*i])<<16 |
+			uint64(b[28-8*i])<<24 | uint64(b[27-8*i])<<32 | uint64(b[26-8*i])<<40 |
+			uint64(b[25-8*i])<<48 | uint64(b[24-8*i])<<56
+	}
+	f.reduce(0)
+	return f
+}
+
+// setInt sets f to n modulo P.
+func (f *fieldVal) setInt(n *big.Int) *fieldVal {
+	var b [32]byte
+	if n.Sign() < 0 || n.BitLen() > 256 {
+		n = new(big.Int).Mod(n, secp256k1.P)
+	}
+	n.FillBytes(b[:])
+	return f.setBytes(&b)
+}
+
+// bytes returns the 32 bytes big-endian encoding of f.
+func (f *fieldVal) bytes() [32]byte {
+	var b [32]byte
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			b[31-8*i-j] = byte(f[i] >> (8 * uint(j)))
+		}
+	}
+	return b
+}
+
+// toInt returns f as a big.Int.
+func (f *fieldVal) toInt() *big.Int {
+	b := f.bytes()
+	return new(big.Int).SetBytes(b[:])
+}
+
+// reduce subtracts P once if f (with an extra carry bit) is not below P.
+func (f *fieldVal) reduce(carry uint64) {
+	var t fieldVal
+	var borrow uint64
+	t[0], borrow = bits.Sub64(f[0], fieldP[0], 0)
+	t[1], borrow = bits.Sub64(f[1], fieldP[1], borrow)
+	t[2], borrow = bits.Sub64(f[2], fieldP[2], borrow)
+	t[3], borrow = bits.Sub64(f[3], fieldP[3], borrow)
+
+	// Keep t when the subtraction didn't borrow or the value overflowed 2^256
+	f.cmov(&t, carry|(borrow^1))
+}
+
+// cmov sets f to a when flag is 1 and leaves it unchanged when flag is 0.
+func (f *fieldVal) cmov(a *fieldVal, flag uint64) {
+	mask := -flag
+	f[0] ^= (f[0] ^ a[0]) & mask
+	f[1] ^= (f[1] ^ a[1]) & mask
+	f[2] ^= (f[2] ^ a[2]) & mask
+	f[3] ^= (f[3] ^ a[3]) & mask
+}
+
+// add sets f = a + b.
+func (f *fieldVal) add(a, b *fieldVal) *fieldVal {
+	var carry uint64
+	f[0], carry = bits.Add64(a[0], b[0], 0)
+	f[1], carry = bits.Add64(a[1], b[1], carry)
+	f[2], carry = bits.Add64(a[2], b[2], carry)
+	f[3], carry = bits.Add64(a[3], b[3], carry)
+	f.reduce(carry)
+	return f
+}
+
+// sub sets f = a - b.
+func (f *fieldVal) sub(a, b *fieldVal) *fieldVal {
+	var borrow, carry uint64
+	f[0], borrow = bits.Sub64(a[0], b[0], 0)
+	f[1], borrow = bits.Sub64(a[1], b[1], borrow)
+	f[2], borrow = bits.Sub64(a[2], b[2], borrow)
+	f[3], borrow = bits.Sub64(a[3], b[3], borrow)
+
+	// Add P back when the subtraction wrapped around
+	mask := -borrow
+	f[0], carry = bits.Add64(f[0], fieldP[0]&mask, 0)
+	f[1], carry = bits.Add64(f[1], fieldP[1]&mask, carry)
+	f[2], carry = bits.Add64(f[2], fieldP[2]&mask, carry)
+	f[3], _ = bits.Add64(f[3], fieldP[3]&mask, carry)
+	return f
+}
+
+// neg sets f = -a.
+func (f *fieldVal) neg(a *fieldVal) *fieldVal {
+	var zero fieldVal
+	return f.sub(&zero, a)
+}
+
+// mulAdd returns the 128 bits value a * b + c + d as hi, lo.
+func mulAdd(a, b, c, d uint64) (uint64, uint64) {
+	hi, lo := bits.Mul64(a, b)
+	var carry uint64
+	lo, carry = bits.Add64(lo, c, 0)
+	hi += carry
+	lo, carry = bits.Add64(lo, d, 0)
+	hi += carry
+	return hi, lo
+}
+
+// mul sets f = a * b.
+func (f *fieldVal) mul(a, b *fieldVal) *fieldVal {
+	var t0, t1, t2, t3, t4, t5, t6, t7, c uint64
+
+	// Schoolbook 256 x 256 bits product, one row per limb of a
+	c, t0 = mulAdd(a[0], b[0], 0, 0)
+	c, t1 = mulAdd(a[0], b[1], 0, c)
+	c, t2 = mulAdd(a[0], b[2], 0, c)
+	t4, t3 = mulAdd(a[0], b[3], 0, c)
+
+	c, t1 = mulAdd(a[1], b[0], t1, 0)
+	c, t2 = mulAdd(a[1], b[1], t2, c)
+	c, t3 = mulAdd(a[1], b[2], t3, c)
+	t5, t4 = mulAdd(a[1], b[3], t4, c)
+
+	c, t2 = mulAdd(a[2], b[0], t2, 0)
+	c, t3 = mulAdd(a[2], b[1], t3, c)
+	c, t4 = mulAdd(a[2], b[2], t4, c)
+	t6, t5 = mulAdd(a[2], b[3], t5, c)
+
+	c, t3 = mulAdd(a[3], b[0], t3, 0)
+	c, t4 = mulAdd(a[3], b[1], t4, c)
+	c, t5 = mulAdd(a[3], b[2], t5, c)
+	t7, t6 = mulAdd(a[3], b[3], t6, c)
+
+	// lo + hi * 2^256 ≡ lo + hi * C (mod P)
+	var r0, r1, r2, r3, r4 uint64
+	c, r0 = mulAdd(t4, fieldC, t0, 0)
+	c, r1 = mulAdd(t5, fieldC, t1, c)
+	c, r2 = mulAdd(t6, fieldC, t2, c)
+	r4, r3 = mulAdd(t7, fieldC, t3, c)
+
+	// Fold the remaining high limb, which is below 2^34
+	hi, lo := bits.Mul64(r4, fieldC)
+	f[0], c = bits.Add64(r0, lo, 0)
+	f[1], c = bits.Add64(r1, hi, c)
+	f[2], c = bits.Add64(r2, 0, c)
+	f[3], c = bits.Add64(r3, 0, c)
+
+	// A last carry leaves a small value so it can't overflow again
+	f[0], c = bits.Add64(f[0], fieldC&-c, 0)
+	f[1], c = bits.Add64(f[1], 0, c)
+	f[2], c = bits.Add64(f[2], 0, c)
+	f[3], _ = bits.Add64(f[3], 0, c)
+
+	f.reduce(0)
+	return f
+}
+
+// square sets f = a².
+func (f *fieldVal) square(a *fieldVal) *fieldVal {
+	return f.mul(a, a)
+}
+
+// squareN sets f = a^(2^n).
+func (f *fieldVal) squareN(a *fieldVal, n int) *fieldVal {
+	*f = *a
+	for i := 0; i < n; i++ {
+		f.square(f)
+	}
+	return f
+}
+
+// pow223 returns a^(2^223 - 1) together with a^(2^22 - 1) and a^3, the
+// common prefix of the addition chains for P - 2 and (P + 1) / 4 used by
+// libsecp256k1.
+func (f *fieldVal) pow223(a *fieldVal) (x223, x22, x2 fieldVal) {
+	var x3, x6, x9, x11, x44, x88, x176, x220 fieldVal
+	x2.square(a)
+	x2.mul(&x2, a)
+	x3.square(&x2)
+	x3.mul(&x3, a)
+	x6.squareN(&x3, 3)
+	x6.mul(&x6, &x3)
+	x9.squareN(&x6, 3)
+	x9.mul(&x9, &x3)
+	x11.squareN(&x9, 2)
+	x11.mul(&x11, &x2)
+	x22.squareN(&x11, 11)
+	x22.mul(&x22, &x11)
+	x44.squareN(&x22, 22)
+	x44.mul(&x44, &x22)
+	x88.squareN(&x44, 44)
+	x88.mul(&x88, &x44)
+	x176.squareN(&x88, 88)
+	x176.mul(&x176, &x88)
+	x220.squareN(&x176, 44)
+	x220.mul(&x220, &x44)
+	x223.squareN(&x220, 3)
+	x223.mul(&x223, &x3)
+	return
+}
+
+// inverse sets f = a^(P-2) = a⁻¹ using Fermat's little theorem; the inverse
+// of zero is zero.
+func (f *fieldVal) inverse(a *fieldVal) *fieldVal {
+	x223, x22, x2 := f.pow223(a)
+	var t fieldVal
+	t.squareN(&x223, 23)
+	t.mul(&t, &x22)
+	t.squareN(&t, 5)
+	t.mul(&t, a)
+	t.squareN(&t, 3)
+	t.mul(&t, &x2)
+	t.squareN(&t, 2)
+	f.mul(&t, a)
+	return f
+}
+
+// sqrt sets f to a square root of a and reports whether one exists.
+func (f *fieldVal) sqrt(a *fieldVal) bool {
+	// P ≡ 3 (mod 4) so a^((P+1)/4) is a root whenever a is a square
+	x223, x22, x2 := f.pow223(a)
+	var r, check fieldVal
+	r.squareN(&x223, 23)
+	r.mul(&r, &x22)
+	r.squareN(&r, 6)
+	r.mul(&r, &x2)
+	r.squareN(&r, 2)
+	check.square(&r)
+	*f = r
+	return check.equal(a) == 1
+}
+
+// isZero returns 1 if f is zero and 0 otherwise.
+func (f *fieldVal) isZero() uint64 {
+	v := f[0] | f[1] | f[2] | f[3]
+	return 1 ^ ((v | -v) >> 63)
+}
+
+// equal returns 1 if f == a and 0 otherwise.
+func (f *fieldVal) equal(a *fieldVal) uint64 {
+	d := fieldVal{f[0] ^ a[0], f[1] ^ a[1], f[2] ^ a[2], f[3] ^ a[3]}
+	return d.isZero()
+}
+
+// isOdd returns 1 if f is odd and 0 otherwise.
+func (f *fieldVal) isOdd() uint64 {
+	return f[0] & 1
+}
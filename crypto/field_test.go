@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// randInt returns a random value below m, biased towards the all zero and
+// all one byte patterns where carries and reductions go wrong.
+func randInt(r *rand.Rand, m *big.Int) *big.Int {
+	b := make([]byte, 32)
+	r.Read(b)
+	switch r.Intn(8) {
+	case 0:
+		for i := range b {
+			b[i] = 0xff
+		}
+	case 1:
+		for i := range b {
+			b[i] = 0
+		}
+	case 2:
+		// just below the modulus
+		return new(big.Int).Sub(m, big.NewInt(int64(r.Intn(4)+1)))
+	}
+	return new(big.Int).Mod(new(big.Int).SetBytes(b), m)
+}
+
+func TestFieldArithmetic(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	p := secp256k1.P
+	for i := 0; i < 10000; i++ {
+		a, b := randInt(r, p), randInt(r, p)
+		var fa, fb, f fieldVal
+		fa.setInt(a)
+		fb.setInt(b)
+
+		check := func(op string, got *fieldVal, want *big.Int) {
+			t.Helper()
+			want.Mod(want, p)
+			if got.toInt().Cmp(want) != 0 {
+				t.Fatalf("%s(%x, %x) = %x, want %x", op, a, b, got.toInt(), want)
+			}
+		}
+		check("add", f.add(&fa, &fb), new(big.Int).Add(a, b))
+		check("sub", f.sub(&fa, &fb), new(big.Int).Sub(a, b))
+		check("mul", f.mul(&fa, &fb), new(big.Int).Mul(a, b))
+		check("square", f.square(&fa), new(big.Int).Mul(a, a))
+		check("neg", f.neg(&fa), new(big.Int).Neg(a))
+		if a.Sign() != 0 && i%20 == 0 {
+			check("inverse", f.inverse(&fa), new(big.Int).ModInverse(a, p))
+		}
+
+		if (fa.isZero() == 1) != (a.Sign() == 0) {
+			t.Fatalf("isZero(%x) = %d", a, fa.isZero())
+		}
+		if (fa.isOdd() == 1) != (a.Bit(0) == 1) {
+			t.Fatalf("isOdd(%x) = %d", a, fa.isOdd())
+		}
+		if (fa.equal(&fb) == 1) != (a.Cmp(b) == 0) {
+			t.Fatalf("equal(%x, %x) = %d", a, b, fa.equal(&fb))
+		}
+	}
+}
+
+func TestFieldSqrt(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	p := secp256k1.P
+	for i := 0; i < 500; i++ {
+		a := randInt(r, p)
+		var fa, f fieldVal
+		fa.setInt(a)
+		want := new(big.Int).ModSqrt(a, p)
+		ok := f.sqrt(&fa)
+		if ok != (want != nil) {
+			t.Fatalf("sqrt(%x) reported %v", a, ok)
+		}
+		if !ok {
+			continue
+		}
+		root := f.toInt()
+		if new(big.Int).Exp(root, big.NewInt(2), p).Cmp(a) != 0 {
+			t.Fatalf("sqrt(%x) = %x isn't a root", a, root)
+		}
+	}
+}
+
+func TestFieldSetBytesReduces(t *testing.T) {
+	// Encodings in [P, 2^256) are reduced modulo P
+	for _, n := range []int64{0, 1, 0x3d1} {
+		v := new(big.Int).Add(secp256k1.P, big.NewInt(n))
+		var b [32]byte
+		v.FillBytes(b[:])
+		var f fieldVal
+		if f.setBytes(&b).toInt().Cmp(big.NewInt(n)) != 0 {
+			t.Errorf("setBytes(P + %d) = %x", n, f.toInt())
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package crypto
+
+import (
+	"crypto/subtle"
+	"errors"
+	"math/big"
+)
+
+const (
+	pubKeyCompressedEven = 0x02
+	pubKeyCompressedOdd  = 0x03
+	pubKeyUncompressed   = 0x04
+	pubKeyHybridEven     = 0x06
+	pubKeyHybridOdd      = 0x07
+
+	pubKeyCompressedLength   = 33
+	pubKeyUncompressedLength = 65
+)
+
+var (
+	// ErrInvalidPublicKeyFormat is returned when a public key has an unknown
+	// prefix or a length that doesn't match it
+	ErrInvalidPublicKeyFormat = errors.New("Invalid public key format")
+
+	// ErrHybridPublicKey is returned when parsing a hybrid (0x06/0x07) public
+	// key without allowing them
+	ErrHybridPublicKey = errors.New("Hybrid public keys are not allowed")
+
+	// ErrPublicKeyNotOnCurve is returned when a public key isn't a point on
+	// the curve
+	ErrPublicKeyNotOnCurve = errors.New("Public key is not on the curve")
+
+	// ErrInvalidTweak is returned when a tweak isn't a 32 bytes integer below
+	// the curve order
+	ErrInvalidTweak = errors.New("Tweak should be 32 bytes and less than the curve order")
+
+	// ErrTweakedKeyInvalid is returned when tweaking yields zero or the point
+	// at infinity
+	ErrTweakedKeyInvalid = errors.New("Tweaked key is invalid")
+)
+
+// PrivateKey is a secp256k1 private key, an integer in [1, N-1].
+type PrivateKey struct {
+	d scalar
+}
+
+// PublicKey is a secp256k1 public key, a point on the curve other than the
+// point at infinity.
+type PublicKey struct {
+	X, Y *big.Int
+}
+
+// PrivateKeyFromBytes returns the private key for a 32 bytes big-endian
+// integer in [1, N-1].
+func PrivateKeyFromBytes(key []byte) (*PrivateKey, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidPrivateKey
+	}
+	var buf [32]byte
+	copy(buf[:], key)
+
+	// setBytes reduces modulo N, so a changed value means key >= N
+	priv := &PrivateKey{}
+	priv.d.setBytes(&buf)
+	if priv.d.isZero() == 1 || subtle.ConstantTimeCompare(priv.Serialize(), key) != 1 {
+		return nil, ErrInvalidPrivateKey
+	}
+	return priv, nil
+}
+
+// Zero wipes the private key. The key is unusable afterwards.
+func (priv *PrivateKey) Zero() {
+	priv.d = scalar{}
+}
+
+// Serialize returns the 32 bytes big-endian encoding of the private key. The
+// result is a copy the caller should wipe once done.
+func (priv *PrivateKey) Serialize() []byte {
+	b := priv.d.bytes()
+	return b[:]
+}
+
+// PublicKey returns the public key d*G.
+func (priv *PrivateKey) PublicKey() *PublicKey {
+	var p projectivePoint
+	x, y := p.scalarBaseMult(&priv.d).toAffine()
+	return &PublicKey{X: x, Y: y}
+}
+
+// TweakAdd returns the private key d + tweak.
+func (priv *PrivateKey) TweakAdd(tweak []byte) (*PrivateKey, error) {
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	tweaked := &PrivateKey{}
+	tweaked.d.add(&priv.d, t)
+	if tweaked.d.isZero() == 1 {
+		return nil, ErrTweakedKeyInvalid
+	}
+	return tweaked, nil
+}
+
+// TweakMul returns the private key d * tweak.
+func (priv *PrivateKey) TweakMul(tweak []byte) (*PrivateKey, error) {
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	tweaked := &PrivateKey{}
+	tweaked.d.mul(&priv.d, t)
+	if tweaked.d.isZero() == 1 {
+		return nil, ErrTweakedKeyInvalid
+	}
+	return tweaked, nil
+}
+
+// Negate returns the private key N - d.
+func (priv *PrivateKey) Negate() *PrivateKey {
+	negated := &PrivateKey{}
+	negated.d.neg(&priv.d)
+	return negated
+}
+
+// Equal reports whether both private keys are the same, in constant time.
+func (priv *PrivateKey) Equal(other *PrivateKey) bool {
+	return subtle.ConstantTimeCompare(priv.Serialize(), other.Serialize()) == 1
+}
+
+// Sign produces a deterministic ECDSA signature of hash.
+func (priv *PrivateKey) Sign(hash []byte) (*Signature, error) {
+	return Sign(priv.Serialize(), hash)
+}
+
+// ParsePublicKey parses a 33 bytes compressed (0x02/0x03) or 65 bytes
+// uncompressed (0x04) public key and checks the point is on the curve.
+// Hybrid keys are rejected.
+func ParsePublicKey(key []byte) (*PublicKey, error) {
+	return parsePublicKey(key, false)
+}
+
+// ParsePublicKeyAllowHybrid is like ParsePublicKey but also accepts 65 bytes
+// hybrid (0x06/0x07) public keys whose prefix matches the parity of y.
+func ParsePublicKeyAllowHybrid(key []byte) (*PublicKey, error) {
+	return parsePublicKey(key, true)
+}
+
+func parsePublicKey(key []byte, allowHybrid bool) (*PublicKey, error) {
+	curve := secp256k1
+	if len(key) == 0 {
+		return nil, ErrInvalidPublicKeyFormat
+	}
+
+	switch key[0] {
+	case pubKeyCompressedEven, pubKeyCompressedOdd:
+		if len(key) != pubKeyCompressedLength {
+			return nil, ErrInvalidPublicKeyFormat
+		}
+		x := new(big.Int).SetBytes(key[1:])
+		y, err := curve.decompressY(x, key[0] == pubKeyCompressedOdd)
+		if err != nil {
+			return nil, ErrPublicKeyNotOnCurve
+		}
+		return &PublicKey{X: x, Y: y}, nil
+
+	case pubKeyHybridEven, pubKeyHybridOdd:
+		if !allowHybrid {
+			return nil, ErrHybridPublicKey
+		}
+		fallthrough
+
+	case pubKeyUncompressed:
+		if len(key) != pubKeyUncompressedLength {
+			return nil, ErrInvalidPublicKeyFormat
+		}
+		x := new(big.Int).SetBytes(key[1:33])
+		y := new(big.Int).SetBytes(key[33:])
+		if !curve.IsOnCurve(x, y) {
+			return nil, ErrPublicKeyNotOnCurve
+		}
+		// Hybrid keys also carry the parity of y in their prefix
+		if key[0] != pubKeyUncompressed && uint(key[0]-pubKeyHybridEven) != y.Bit(0) {
+			return nil, ErrInvalidPublicKeyFormat
+		}
+		return &PublicKey{X: x, Y: y}, nil
+	}
+
+	return nil, ErrInvalidPublicKeyFormat
+}
+
+// SerializeCompressed returns the 33 bytes compressed encoding of the key.
+func (pub *PublicKey) SerializeCompressed() []byte {
+	key := make([]byte, pubKeyCompressedLength)
+	key[0] = pubKeyCompressedEven + byte(pub.Y.Bit(0))
+	pub.X.FillBytes(key[1:])
+	return key
+}
+
+// SerializeUncompressed returns the 65 bytes uncompressed encoding of the key.
+func (pub *PublicKey) SerializeUncompressed() []byte {
+	key := make([]byte, pubKeyUncompressedLength)
+	key[0] = pubKeyUncompressed
+	pub.X.FillBytes(key[1:33])
+	pub.Y.FillBytes(key[33:])
+	return key
+}
+
+// SerializeXOnly returns the 32 bytes BIP340 x-only encoding of the key.
+func (pub *PublicKey) SerializeXOnly() []byte {
+	return intToBytes32(pub.X)
+}
+
+// Add returns the public key pub + other.
+func (pub *PublicKey) Add(other *PublicKey) (*PublicKey, error) {
+	x, y := secp256k1.Add(pub.X, pub.Y, other.X, other.Y)
+	if x == nil {
+		return nil, ErrTweakedKeyInvalid
+	}
+	return &PublicKey{X: x, Y: y}, nil
+}
+
+// TweakAdd returns the public key P + tweak*G.
+func (pub *PublicKey) TweakAdd(tweak []byte) (*PublicKey, error) {
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	var p projectivePoint
+	tx, ty := p.scalarBaseMult(t).toAffine()
+	return pub.Add(&PublicKey{X: tx, Y: ty})
+}
+
+// TweakMul returns the public key tweak*P.
+func (pub *PublicKey) TweakMul(tweak []byte) (*PublicKey, error) {
+	t, err := parseTweak(tweak)
+	if err != nil {
+		return nil, err
+	}
+	var p projectivePoint
+	p.setAffine(pub.X, pub.Y)
+	x, y := p.scalarMult(&p, t).toAffine()
+	if x == nil {
+		return nil, ErrTweakedKeyInvalid
+	}
+	return &PublicKey{X: x, Y: y}, nil
+}
+
+// Negate returns the public key -P.
+func (pub *PublicKey) Negate() *PublicKey {
+	return &PublicKey{
+		X: new(big.Int).Set(pub.X),
+		Y: new(big.Int).Sub(secp256k1.P, pub.Y),
+	}
+}
+
+// Equal reports whether both public keys are the same point.
+func (pub *PublicKey) Equal(other *PublicKey) bool {
+	return pub.X.Cmp(other.X) == 0 && pub.Y.Cmp(other.Y) == 0
+}
+
+// Verify reports whether sig is a valid ECDSA signature of hash by the key.
+func (pub *PublicKey) Verify(hash []byte, sig *Signature) bool {
+	return Verify(pub.X, pub.Y, hash, sig)
+}
+
+// parseTweak reads a 32 bytes tweak that must be below the curve order.
+func parseTweak(tweak []byte) (*scalar, error) {
+	if len(tweak) != 32 {
+		return nil, ErrInvalidTweak
+	}
+	var buf [32]byte
+	copy(buf[:], tweak)
+	t := new(scalar).setBytes(&buf)
+	reduced := t.bytes()
+	if subtle.ConstantTimeCompare(reduced[:], tweak) != 1 {
+		return nil, ErrInvalidTweak
+	}
+	return t, nil
+}
@@ -0,0 +1,244 @@
+package crypto
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+const (
+	generatorCompressed   = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	generatorUncompressed = "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	compressed := mustDecodeHex(t, generatorCompressed)
+	uncompressed := mustDecodeHex(t, generatorUncompressed)
+	// G.y is even
+	hybrid := append([]byte{pubKeyHybridEven}, uncompressed[1:]...)
+
+	for _, key := range [][]byte{compressed, uncompressed} {
+		for _, parse := range []func([]byte) (*PublicKey, error){ParsePublicKey, ParsePublicKeyAllowHybrid} {
+			pub, err := parse(key)
+			if err != nil {
+				t.Fatalf("%x: %v", key, err)
+			}
+			if pub.X.Cmp(secp256k1.Gx) != 0 || pub.Y.Cmp(secp256k1.Gy) != 0 {
+				t.Fatalf("%x: parsed another point", key)
+			}
+			if !bytes.Equal(pub.SerializeCompressed(), compressed) || !bytes.Equal(pub.SerializeUncompressed(), uncompressed) {
+				t.Fatalf("%x: serialization differs", key)
+			}
+		}
+	}
+
+	// An odd y keeps the 0x03 prefix
+	negated := &PublicKey{X: secp256k1.Gx, Y: new(big.Int).Sub(secp256k1.P, secp256k1.Gy)}
+	pub, err := ParsePublicKey(negated.SerializeCompressed())
+	if err != nil || !pub.Equal(negated) || pub.SerializeCompressed()[0] != pubKeyCompressedOdd {
+		t.Fatalf("odd key: %v", err)
+	}
+
+	pub, err = ParsePublicKeyAllowHybrid(hybrid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.X.Cmp(secp256k1.Gx) != 0 || pub.Y.Cmp(secp256k1.Gy) != 0 {
+		t.Fatal("hybrid key parsed another point")
+	}
+	if _, err := ParsePublicKey(hybrid); err != ErrHybridPublicKey {
+		t.Errorf("strict hybrid: got %v, want %v", err, ErrHybridPublicKey)
+	}
+	wrongParity := append([]byte{pubKeyHybridOdd}, uncompressed[1:]...)
+	if _, err := ParsePublicKeyAllowHybrid(wrongParity); err != ErrInvalidPublicKeyFormat {
+		t.Errorf("hybrid with the wrong parity: got %v, want %v", err, ErrInvalidPublicKeyFormat)
+	}
+}
+
+func TestParsePublicKeyInvalid(t *testing.T) {
+	compressed := mustDecodeHex(t, generatorCompressed)
+	uncompressed := mustDecodeHex(t, generatorUncompressed)
+
+	offCurve := append([]byte{}, uncompressed...)
+	offCurve[64]++
+
+	// Find a small x on the curve, so that x + P still fits in 32 bytes
+	x := big.NewInt(1)
+	for {
+		if _, err := secp256k1.decompressY(x, false); err == nil {
+			break
+		}
+		x.Add(x, big.NewInt(1))
+	}
+	y, _ := secp256k1.decompressY(x, false)
+	overflowed := new(big.Int).Add(x, secp256k1.P)
+	compressedOverflow := make([]byte, 33)
+	compressedOverflow[0] = pubKeyCompressedEven
+	overflowed.FillBytes(compressedOverflow[1:])
+	uncompressedOverflow := append([]byte{pubKeyUncompressed}, compressedOverflow[1:]...)
+	uncompressedOverflow = append(uncompressedOverflow, make([]byte, 32)...)
+	y.FillBytes(uncompressedOverflow[33:])
+
+	// An x that isn't the x coordinate of a point
+	noPoint := big.NewInt(1)
+	for {
+		if _, err := secp256k1.decompressY(noPoint, false); err != nil {
+			break
+		}
+		noPoint.Add(noPoint, big.NewInt(1))
+	}
+	compressedNoPoint := make([]byte, 33)
+	compressedNoPoint[0] = pubKeyCompressedEven
+	noPoint.FillBytes(compressedNoPoint[1:])
+
+	tests := []struct {
+		name string
+		key  []byte
+		err  error
+	}{
+		{"empty", nil, ErrInvalidPublicKeyFormat},
+		{"unknown prefix", append([]byte{0x05}, compressed[1:]...), ErrInvalidPublicKeyFormat},
+		{"short compressed", compressed[:32], ErrInvalidPublicKeyFormat},
+		{"compressed prefix with 65 bytes", append([]byte{pubKeyCompressedEven}, uncompressed[1:]...), ErrInvalidPublicKeyFormat},
+		{"uncompressed prefix with 33 bytes", append([]byte{pubKeyUncompressed}, compressed[1:]...), ErrInvalidPublicKeyFormat},
+		{"off the curve", offCurve, ErrPublicKeyNotOnCurve},
+		{"x without a point", compressedNoPoint, ErrPublicKeyNotOnCurve},
+		{"compressed x above P", compressedOverflow, ErrPublicKeyNotOnCurve},
+		{"uncompressed x above P", uncompressedOverflow, ErrPublicKeyNotOnCurve},
+	}
+	for _, test := range tests {
+		for _, parse := range []func([]byte) (*PublicKey, error){ParsePublicKey, ParsePublicKeyAllowHybrid} {
+			if _, err := parse(test.key); err != test.err {
+				t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+			}
+		}
+	}
+
+	// The same point with x reduced is valid
+	valid := append([]byte{pubKeyCompressedEven}, make([]byte, 32)...)
+	x.FillBytes(valid[1:])
+	if _, err := ParsePublicKey(valid); err != nil {
+		t.Errorf("reduced x: %v", err)
+	}
+}
+
+func TestPrivateKeyFromBytes(t *testing.T) {
+	nMinusOne := new(big.Int).Sub(secp256k1.N, big.NewInt(1))
+	priv, err := PrivateKeyFromBytes(intToBytes32(nMinusOne))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (N-1)G = -G
+	if pub := priv.PublicKey(); pub.X.Cmp(secp256k1.Gx) != 0 || pub.Y.Cmp(secp256k1.Gy) == 0 {
+		t.Fatal("(N-1)G should be -G")
+	}
+
+	for _, key := range [][]byte{nil, make([]byte, 31), make([]byte, 32), secp256k1.N.Bytes(), bytes.Repeat([]byte{0xff}, 32)} {
+		if _, err := PrivateKeyFromBytes(key); err != ErrInvalidPrivateKey {
+			t.Errorf("%x: got %v, want %v", key, err, ErrInvalidPrivateKey)
+		}
+	}
+}
+
+func TestTweaks(t *testing.T) {
+	priv, err := PrivateKeyFromBytes(bytes.Repeat([]byte{0x03}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.PublicKey()
+	tweak := bytes.Repeat([]byte{0x05}, 32)
+
+	added, err := priv.TweakAdd(tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubAdded, err := pub.TweakAdd(tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !added.PublicKey().Equal(pubAdded) {
+		t.Error("private and public TweakAdd differ")
+	}
+	want := new(big.Int).Add(new(big.Int).SetBytes(priv.Serialize()), new(big.Int).SetBytes(tweak))
+	if !bytes.Equal(added.Serialize(), intToBytes32(want.Mod(want, secp256k1.N))) {
+		t.Error("TweakAdd isn't d + tweak mod N")
+	}
+
+	multiplied, err := priv.TweakMul(tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubMultiplied, err := pub.TweakMul(tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !multiplied.PublicKey().Equal(pubMultiplied) {
+		t.Error("private and public TweakMul differ")
+	}
+	want.Mul(new(big.Int).SetBytes(priv.Serialize()), new(big.Int).SetBytes(tweak))
+	if !bytes.Equal(multiplied.Serialize(), intToBytes32(want.Mod(want, secp256k1.N))) {
+		t.Error("TweakMul isn't d * tweak mod N")
+	}
+
+	negated := priv.Negate()
+	if !negated.PublicKey().Equal(pub.Negate()) {
+		t.Error("private and public Negate differ")
+	}
+	if !negated.Negate().Equal(priv) || negated.Equal(priv) {
+		t.Error("Negate should be an involution without fixed points")
+	}
+	if !priv.Equal(priv) || priv.Equal(added) || !pub.Equal(pub) || pub.Equal(pubAdded) {
+		t.Error("Equal")
+	}
+
+	// d + (N - d) = 0 and P + (N - d)G is the point at infinity
+	if _, err := priv.TweakAdd(negated.Serialize()); err != ErrTweakedKeyInvalid {
+		t.Errorf("private TweakAdd to zero: got %v, want %v", err, ErrTweakedKeyInvalid)
+	}
+	if _, err := pub.TweakAdd(negated.Serialize()); err != ErrTweakedKeyInvalid {
+		t.Errorf("public TweakAdd to infinity: got %v, want %v", err, ErrTweakedKeyInvalid)
+	}
+
+	zero := make([]byte, 32)
+	if _, err := priv.TweakMul(zero); err != ErrTweakedKeyInvalid {
+		t.Errorf("private TweakMul by zero: got %v, want %v", err, ErrTweakedKeyInvalid)
+	}
+	if _, err := pub.TweakMul(zero); err != ErrTweakedKeyInvalid {
+		t.Errorf("public TweakMul by zero: got %v, want %v", err, ErrTweakedKeyInvalid)
+	}
+	// Adding zero is allowed and changes nothing
+	if same, err := priv.TweakAdd(zero); err != nil || !same.Equal(priv) {
+		t.Errorf("TweakAdd of zero: %v", err)
+	}
+
+	for _, invalid := range [][]byte{secp256k1.N.Bytes(), bytes.Repeat([]byte{0xff}, 32), tweak[:31], append(tweak, 0)} {
+		if _, err := priv.TweakAdd(invalid); err != ErrInvalidTweak {
+			t.Errorf("private TweakAdd %x: got %v, want %v", invalid, err, ErrInvalidTweak)
+		}
+		if _, err := priv.TweakMul(invalid); err != ErrInvalidTweak {
+			t.Errorf("private TweakMul %x: got %v, want %v", invalid, err, ErrInvalidTweak)
+		}
+		if _, err := pub.TweakAdd(invalid); err != ErrInvalidTweak {
+			t.Errorf("public TweakAdd %x: got %v, want %v", invalid, err, ErrInvalidTweak)
+		}
+		if _, err := pub.TweakMul(invalid); err != ErrInvalidTweak {
+			t.Errorf("public TweakMul %x: got %v, want %v", invalid, err, ErrInvalidTweak)
+		}
+	}
+}
+
+func TestPrivateKeyZero(t *testing.T) {
+	key := bytes.Repeat([]byte{0x03}, 32)
+	priv, err := PrivateKeyFromBytes(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialized := priv.Serialize()
+	priv.Zero()
+	if !bytes.Equal(priv.Serialize(), make([]byte, 32)) {
+		t.Error("Zero should wipe the key")
+	}
+	if !bytes.Equal(serialized, key) || !bytes.Equal(key, bytes.Repeat([]byte{0x03}, 32)) {
+		t.Error("Zero shouldn't touch earlier serializations or the input")
+	}
+}
@@ -0,0 +1,199 @@
+package crypto
+
+import "math/big"
+
+// projectivePoint is a point in homogeneous projective coordinates where
+// (X:Y:Z) represents the affine point (X/Z, Y/Z) and (0:1:0) is the point at
+// infinity. The complete formulas from Renes, Costello and Batina, "Complete
+// addition formulas for prime order elliptic curves" (2015) handle every
+// input, including doubling and infinity, without branching.
+type projectivePoint struct {
+	x, y, z fieldVal
+}
+
+// setInfinity sets p to the point at infinity.
+func (p *projectivePoint) setInfinity() *projectivePoint {
+	p.x = fieldVal{}
+	p.y = fieldOne
+	p.z = fieldVal{}
+	return p
+}
+
+// setAffine sets p to the affine point (x, y), treating nil or (0, 0) as
+// the point at infinity.
+func (p *projectivePoint) setAffine(x, y *big.Int) *projectivePoint {
+	if x == nil || y == nil || (x.Sign() == 0 && y.Sign() == 0) {
+		return p.setInfinity()
+	}
+	p.x.setInt(x)
+	p.y.setInt(y)
+	p.z = fieldOne
+	return p
+}
+
+// toAffine returns the affine coordinates of p, or nil, nil for the point at
+// infinity.
+func (p *projectivePoint) toAffine() (*big.Int, *big.Int) {
+	var x, y fieldVal
+	ok := p.affine(&x, &y)
+	if ok == 0 {
+		return nil, nil
+	}
+	return x.toInt(), y.toInt()
+}
+
+// affine stores the affine coordinates of p in x and y and returns 0 if p is
+// the point at infinity.
+func (p *projectivePoint) affine(x, y *fieldVal) uint64 {
+	var zInv fieldVal
+	zInv.inverse(&p.z)
+	x.mul(&p.x, &zInv)
+	y.mul(&p.y, &zInv)
+	return 1 ^ p.z.isZero()
+}
+
+// cmov sets p to a when flag is 1.
+func (p *projectivePoint) cmov(a *projectivePoint, flag uint64) {
+	p.x.cmov(&a.x, flag)
+	p.y.cmov(&a.y, flag)
+	p.z.cmov(&a.z, flag)
+}
+
+// add sets p = a + b (algorithm 7 for a = 0).
+func (p *projectivePoint) add(a, b *projectivePoint) *projectivePoint {
+	var t0, t1, t2, t3, t4, x3, y3, z3 fieldVal
+	t0.mul(&a.x, &b.x)
+	t1.mul(&a.y, &b.y)
+	t2.mul(&a.z, &b.z)
+	t3.add(&a.x, &a.y)
+	t4.add(&b.x, &b.y)
+	t3.mul(&t3, &t4)
+	t4.add(&t0, &t1)
+	t3.sub(&t3, &t4)
+	t4.add(&a.y, &a.z)
+	x3.add(&b.y, &b.z)
+	t4.mul(&t4, &x3)
+	x3.add(&t1, &t2)
+	t4.sub(&t4, &x3)
+	x3.add(&a.x, &a.z)
+	y3.add(&b.x, &b.z)
+	x3.mul(&x3, &y3)
+	y3.add(&t0, &t2)
+	y3.sub(&x3, &y3)
+	x3.add(&t0, &t0)
+	t0.add(&x3, &t0)
+	t2.mul(&fieldB3, &t2)
+	z3.add(&t1, &t2)
+	t1.sub(&t1, &t2)
+	y3.mul(&fieldB3, &y3)
+	x3.mul(&t4, &y3)
+	t2.mul(&t3, &t1)
+	x3.sub(&t2, &x3)
+	y3.mul(&y3, &t0)
+	t1.mul(&t1, &z3)
+	y3.add(&t1, &y3)
+	t0.mul(&t0, &t3)
+	z3.mul(&z3, &t4)
+	z3.add(&z3, &t0)
+
+	p.x, p.y, p.z = x3, y3, z3
+	return p
+}
+
+// addAffine sets p = a + (x, y) for an affine point (x, y) (algorithm 8 for
+// a = 0). It's complete as long as (x, y) isn't the point at infinity.
+func (p *projectivePoint) addAffine(a *projectivePoint, x, y *fieldVal) *projectivePoint {
+	var t0, t1, t2, t3, t4, x3, y3, z3 fieldVal
+	t0.mul(&a.x, x)
+	t1.mul(&a.y, y)
+	t3.add(x, y)
+	t4.add(&a.x, &a.y)
+	t3.mul(&t3, &t4)
+	t4.add(&t0, &t1)
+	t3.sub(&t3, &t4)
+	t4.mul(y, &a.z)
+	t4.add(&t4, &a.y)
+	y3.mul(x, &a.z)
+	y3.add(&y3, &a.x)
+	x3.add(&t0, &t0)
+	t0.add(&x3, &t0)
+	t2.mul(&fieldB3, &a.z)
+	z3.add(&t1, &t2)
+	t1.sub(&t1, &t2)
+	y3.mul(&fieldB3, &y3)
+	x3.mul(&t4, &y3)
+	t2.mul(&t3, &t1)
+	x3.sub(&t2, &x3)
+	y3.mul(&y3, &t0)
+	t1.mul(&t1, &z3)
+	y3.add(&t1, &y3)
+	t0.mul(&t0, &t3)
+	z3.mul(&z3, &t4)
+	z3.add(&z3, &t0)
+
+	p.x, p.y, p.z = x3, y3, z3
+	return p
+}
+
+// double sets p = 2a (algorithm 9 for a = 0).
+func (p *projectivePoint) double(a *projectivePoint) *projectivePoint {
+	var t0, t1, t2, x3, y3, z3 fieldVal
+	t0.square(&a.y)
+	z3.add(&t0, &t0)
+	z3.add(&z3, &z3)
+	z3.add(&z3, &z3)
+	t1.mul(&a.y, &a.z)
+	t2.square(&a.z)
+	t2.mul(&fieldB3, &t2)
+	x3.mul(&t2, &z3)
+	y3.add(&t0, &t2)
+	z3.mul(&t1, &z3)
+	t1.add(&t2, &t2)
+	t2.add(&t1, &t2)
+	t0.sub(&t0, &t2)
+	y3.mul(&t0, &y3)
+	y3.add(&x3, &y3)
+	t1.mul(&a.x, &a.y)
+	x3.mul(&t0, &t1)
+	x3.add(&x3, &x3)
+
+	p.x, p.y, p.z = x3, y3, z3
+	return p
+}
+
+// scalarMult sets p = k * a using a fixed 4 bits window. Every window does
+// the same doublings, table scan and addition, so the running time doesn't
+// depend on k.
+func (p *projectivePoint) scalarMult(a *projectivePoint, k *scalar) *projectivePoint {
+	var table [16]projectivePoint
+	table[0].setInfinity()
+	table[1] = *a
+	for i := 2; i < 16; i++ {
+		table[i].add(&table[i-1], a)
+	}
+
+	var r, entry projectivePoint
+	r.setInfinity()
+	for i := 63; i >= 0; i-- {
+		r.double(&r)
+		r.double(&r)
+		r.double(&r)
+		r.double(&r)
+
+		window := (k[i/16] >> (4 * (uint(i) % 16))) & 0x0F
+		entry.setInfinity()
+		for j := uint64(1); j < 16; j++ {
+			entry.cmov(&table[j], constantTimeEq(j, window))
+		}
+		r.add(&r, &entry)
+	}
+
+	*p = r
+	return p
+}
+
+// constantTimeEq returns 1 if a == b and 0 otherwise.
+func constantTimeEq(a, b uint64) uint64 {
+	v := a ^ b
+	return 1 ^ ((v | -v) >> 63)
+}
@@ -0,0 +1,259 @@
+package crypto
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// bigAdd adds two affine points with math/big, nil standing for the point
+// at infinity. It is the reference the projective formulas are checked
+// against.
+func bigAdd(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	p := secp256k1.P
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+
+	var lambda *big.Int
+	if x1.Cmp(x2) == 0 {
+		if new(big.Int).Add(y1, y2).Cmp(p) == 0 || y1.Sign() == 0 {
+			return nil, nil
+		}
+		// (3 * x1^2) / (2 * y1)
+		lambda = new(big.Int).Mul(x1, x1)
+		lambda.Mul(lambda, big.NewInt(3))
+		lambda.Mul(lambda, new(big.Int).ModInverse(new(big.Int).Lsh(y1, 1), p))
+	} else {
+		// (y2 - y1) / (x2 - x1)
+		lambda = new(big.Int).Sub(y2, y1)
+		lambda.Mul(lambda, new(big.Int).ModInverse(new(big.Int).Sub(x2, x1), p))
+	}
+	lambda.Mod(lambda, p)
+
+	x3 := new(big.Int).Mul(lambda, lambda)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	x3.Mod(x3, p)
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, lambda)
+	y3.Sub(y3, y1)
+	y3.Mod(y3, p)
+	return x3, y3
+}
+
+// bigScalarMult is the math/big Jacobian double-and-add k * (x, y) the
+// fixed-width backend replaced, kept as a reference and as the benchmark
+// baseline.
+func bigScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	p := secp256k1.P
+	var jx, jy, jz *big.Int
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			if jx != nil {
+				jx, jy, jz = bigDoubleJacobian(jx, jy, jz)
+			}
+			if b>>uint(bit)&1 == 0 {
+				continue
+			}
+			if jx == nil {
+				jx, jy, jz = x, y, big.NewInt(1)
+			} else {
+				jx, jy, jz = bigAddJacobian(jx, jy, jz, x, y)
+			}
+		}
+	}
+	if jx == nil || jz.Sign() == 0 {
+		return nil, nil
+	}
+
+	zInv := new(big.Int).ModInverse(jz, p)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+	rx := new(big.Int).Mul(jx, zInv2)
+	rx.Mod(rx, p)
+	zInv2.Mul(zInv2, zInv)
+	ry := new(big.Int).Mul(jy, zInv2)
+	ry.Mod(ry, p)
+	return rx, ry
+}
+
+// bigAddJacobian adds the affine point (x2, y2) to (x1, y1, z1) with the
+// madd-2007-bl formulas. The points must be distinct.
+func bigAddJacobian(x1, y1, z1, x2, y2 *big.Int) (*big.Int, *big.Int, *big.Int) {
+	p := secp256k1.P
+	z1z1 := new(big.Int).Mul(z1, z1)
+	z1z1.Mod(z1z1, p)
+	u2 := new(big.Int).Mul(x2, z1z1)
+	s2 := new(big.Int).Mul(y2, z1)
+	s2.Mul(s2, z1z1)
+
+	h := new(big.Int).Sub(u2, x1)
+	h.Mod(h, p)
+	hh := new(big.Int).Mul(h, h)
+	i := new(big.Int).Lsh(hh, 2)
+	j := new(big.Int).Mul(h, i)
+	r := new(big.Int).Sub(s2, y1)
+	r.Lsh(r, 1)
+	v := new(big.Int).Mul(x1, i)
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, j)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	x3.Mod(x3, p)
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	y3.Sub(y3, new(big.Int).Lsh(new(big.Int).Mul(y1, j), 1))
+	y3.Mod(y3, p)
+	z3 := new(big.Int).Add(z1, h)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, z1z1)
+	z3.Sub(z3, hh)
+	z3.Mod(z3, p)
+	return x3, y3, z3
+}
+
+// bigDoubleJacobian doubles (x, y, z) with the dbl-2009-l formulas.
+func bigDoubleJacobian(x, y, z *big.Int) (*big.Int, *big.Int, *big.Int) {
+	p := secp256k1.P
+	a := new(big.Int).Mul(x, x)
+	b := new(big.Int).Mul(y, y)
+	c := new(big.Int).Mul(b, b)
+	d := new(big.Int).Add(x, b)
+	d.Mul(d, d)
+	d.Sub(d, a)
+	d.Sub(d, c)
+	d.Lsh(d, 1)
+	e := new(big.Int).Mul(a, big.NewInt(3))
+	f := new(big.Int).Mul(e, e)
+
+	x3 := new(big.Int).Sub(f, new(big.Int).Lsh(d, 1))
+	x3.Mod(x3, p)
+	y3 := new(big.Int).Sub(d, x3)
+	y3.Mul(y3, e)
+	y3.Sub(y3, new(big.Int).Lsh(c, 3))
+	y3.Mod(y3, p)
+	z3 := new(big.Int).Mul(y, z)
+	z3.Lsh(z3, 1)
+	z3.Mod(z3, p)
+	return x3, y3, z3
+}
+
+func equalPoints(x1, y1, x2, y2 *big.Int) bool {
+	if x1 == nil || x2 == nil {
+		return x1 == nil && x2 == nil
+	}
+	return x1.Cmp(x2) == 0 && y1.Cmp(y2) == 0
+}
+
+func TestPointArithmetic(t *testing.T) {
+	curve := secp256k1
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 200; i++ {
+		x1, y1 := curve.ScalarBaseMult(randInt(r, curve.N).Bytes())
+		x2, y2 := curve.ScalarBaseMult(randInt(r, curve.N).Bytes())
+		if x1 != nil && !curve.IsOnCurve(x1, y1) {
+			t.Fatalf("ScalarBaseMult returned (%x, %x) off the curve", x1, y1)
+		}
+
+		x, y := curve.Add(x1, y1, x2, y2)
+		wantX, wantY := bigAdd(x1, y1, x2, y2)
+		if !equalPoints(x, y, wantX, wantY) {
+			t.Fatalf("Add mismatch for (%x, %x) + (%x, %x)", x1, y1, x2, y2)
+		}
+		x, y = curve.Double(x1, y1)
+		wantX, wantY = bigAdd(x1, y1, x1, y1)
+		if !equalPoints(x, y, wantX, wantY) {
+			t.Fatalf("Double mismatch for (%x, %x)", x1, y1)
+		}
+	}
+}
+
+func TestPointEdgeCases(t *testing.T) {
+	curve := secp256k1
+	negGy := new(big.Int).Sub(curve.P, curve.Gy)
+	if x, _ := curve.Add(curve.Gx, curve.Gy, curve.Gx, negGy); x != nil {
+		t.Error("G + -G should be the point at infinity")
+	}
+	if x, y := curve.Add(nil, nil, curve.Gx, curve.Gy); !equalPoints(x, y, curve.Gx, curve.Gy) {
+		t.Error("infinity + G should be G")
+	}
+	x2, y2 := curve.Double(curve.Gx, curve.Gy)
+	if x, y := curve.Add(curve.Gx, curve.Gy, curve.Gx, curve.Gy); !equalPoints(x, y, x2, y2) {
+		t.Error("G + G should be 2G")
+	}
+	if x, _ := curve.ScalarBaseMult(nil); x != nil {
+		t.Error("0 * G should be the point at infinity")
+	}
+	if x, _ := curve.ScalarBaseMult(curve.N.Bytes()); x != nil {
+		t.Error("N * G should be the point at infinity")
+	}
+	nMinus1 := new(big.Int).Sub(curve.N, big.NewInt(1))
+	if x, y := curve.ScalarBaseMult(nMinus1.Bytes()); !equalPoints(x, y, curve.Gx, negGy) {
+		t.Error("(N - 1) * G should be -G")
+	}
+}
+
+func TestScalarMult(t *testing.T) {
+	curve := secp256k1
+	r := rand.New(rand.NewSource(5))
+	px, py := curve.ScalarBaseMult(randInt(r, curve.N).Bytes())
+	for i := 0; i < 50; i++ {
+		k := randInt(r, curve.N).Bytes()
+		if i < 16 {
+			k = []byte{byte(i)}
+		}
+
+		x, y := curve.ScalarBaseMult(k)
+		wantX, wantY := bigScalarMult(curve.Gx, curve.Gy, k)
+		if !equalPoints(x, y, wantX, wantY) {
+			t.Fatalf("ScalarBaseMult(%x) mismatch", k)
+		}
+		x, y = curve.ScalarMult(px, py, k)
+		wantX, wantY = bigScalarMult(px, py, k)
+		if !equalPoints(x, y, wantX, wantY) {
+			t.Fatalf("ScalarMult(%x) mismatch", k)
+		}
+	}
+}
+
+// benchScalar returns a full width scalar for the benchmarks
+func benchScalar() []byte {
+	return new(big.Int).Sub(secp256k1.N, big.NewInt(12345)).Bytes()
+}
+
+func BenchmarkScalarBaseMult(b *testing.B) {
+	k := benchScalar()
+	secp256k1.ScalarBaseMult(k)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		secp256k1.ScalarBaseMult(k)
+	}
+}
+
+func BenchmarkScalarBaseMultBig(b *testing.B) {
+	k := benchScalar()
+	for i := 0; i < b.N; i++ {
+		bigScalarMult(secp256k1.Gx, secp256k1.Gy, k)
+	}
+}
+
+func BenchmarkScalarMult(b *testing.B) {
+	k := benchScalar()
+	x, y := secp256k1.ScalarBaseMult([]byte{7})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		secp256k1.ScalarMult(x, y, k)
+	}
+}
+
+func BenchmarkScalarMultBig(b *testing.B) {
+	k := benchScalar()
+	x, y := secp256k1.ScalarBaseMult([]byte{7})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bigScalarMult(x, y, k)
+	}
+}
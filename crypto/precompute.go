@@ -0,0 +1,58 @@
+package crypto
+
+import "sync"
+
+// affinePoint is a point in affine coordinates, used for precomputed tables.
+type affinePoint struct {
+	x, y fieldVal
+}
+
+// baseTable holds j * 16^i * G for every 4 bits window i and digit j in
+// [1, 15], so k*G needs one mixed addition per window and no doublings.
+// The 60KB table is built on first use.
+var (
+	baseTable     [64][15]affinePoint
+	baseTableOnce sync.Once
+)
+
+func buildBaseTable() {
+	var base projectivePoint
+	base.setAffine(secp256k1.Gx, secp256k1.Gy)
+
+	for i := 0; i < 64; i++ {
+		var p projectivePoint
+		p = base
+		for j := 0; j < 15; j++ {
+			p.affine(&baseTable[i][j].x, &baseTable[i][j].y)
+			p.add(&p, &base)
+		}
+		// p is now 16 * base, the base of the next window
+		base = p
+	}
+}
+
+// scalarBaseMult sets p = k * G using the precomputed generator table. Like
+// scalarMult, every window does the same table scan and addition.
+func (p *projectivePoint) scalarBaseMult(k *scalar) *projectivePoint {
+	baseTableOnce.Do(buildBaseTable)
+
+	var r, sum projectivePoint
+	var entry affinePoint
+	r.setInfinity()
+	for i := 0; i < 64; i++ {
+		window := (k[i/16] >> (4 * (uint(i) % 16))) & 0x0F
+
+		// A zero digit still scans the table and adds, then drops the sum
+		entry = baseTable[i][0]
+		for j := uint64(2); j <= 15; j++ {
+			flag := constantTimeEq(j, window)
+			entry.x.cmov(&baseTable[i][j-1].x, flag)
+			entry.y.cmov(&baseTable[i][j-1].y, flag)
+		}
+		sum.addAffine(&r, &entry.x, &entry.y)
+		r.cmov(&sum, 1^constantTimeEq(0, window))
+	}
+
+	*p = r
+	return p
+}
@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	// ErrInvalidRecoverableSignature is returned when a recoverable signature
+	// has the wrong length or an unknown recovery id
+	ErrInvalidRecoverableSignature = errors.New("Recoverable signatures should be 65 bytes with a recovery id in [0, 3]")
+
+	// ErrPublicKeyNotRecovered is returned when no public key matches the signature
+	ErrPublicKeyNotRecovered = errors.New("Can't recover public key from signature")
+)
+
+// SignRecoverable signs a hash like Sign and returns the 65 bytes
+// R || S || V encoding, V being the recovery id in [0, 3].
+func SignRecoverable(privateKey []byte, hash []byte) ([]byte, error) {
+	sig, recoveryID, err := sign(privateKey, hash)
+	if err != nil {
+		return nil, err
+	}
+	return append(sig.SerializeCompact(), recoveryID), nil
+}
+
+// RecoverPublicKey returns the public key that produced the 65 bytes
+// R || S || V signature of hash, as described in SEC 1 section 4.1.6.
+func RecoverPublicKey(hash []byte, sig []byte) (*big.Int, *big.Int, error) {
+	curve := secp256k1
+	if len(sig) != 65 || sig[64] > 3 {
+		return nil, nil, ErrInvalidRecoverableSignature
+	}
+	parsed, err := ParseCompactSignature(sig[:64])
+	if err != nil {
+		return nil, nil, err
+	}
+	recoveryID := sig[64]
+
+	// R.x = r + j*N, which must still be a field element
+	rx := new(big.Int).Set(parsed.R)
+	if recoveryID&0x02 != 0 {
+		rx.Add(rx, curve.N)
+		if rx.Cmp(curve.P) >= 0 {
+			return nil, nil, ErrPublicKeyNotRecovered
+		}
+	}
+	ry, err := curve.decompressY(rx, recoveryID&0x01 == 1)
+	if err != nil {
+		return nil, nil, ErrPublicKeyNotRecovered
+	}
+
+	// Q = r⁻¹(sR - eG)
+	e := new(big.Int).Mod(hashToInt(hash), curve.N)
+	ex, ey := curve.ScalarBaseMult(e.Bytes())
+	if ex != nil {
+		ey = new(big.Int).Sub(curve.P, ey)
+	}
+	sx, sy := curve.ScalarMult(rx, ry, parsed.S.Bytes())
+	px, py := addPoints(sx, sy, ex, ey)
+	if px == nil {
+		return nil, nil, ErrPublicKeyNotRecovered
+	}
+
+	rInv := new(big.Int).ModInverse(parsed.R, curve.N)
+	qx, qy := curve.ScalarMult(px, py, rInv.Bytes())
+	if qx == nil {
+		return nil, nil, ErrPublicKeyNotRecovered
+	}
+	return qx, qy, nil
+}
@@ -0,0 +1,129 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func TestSignRecoverable(t *testing.T) {
+	seen := make(map[byte]bool)
+	for i := 0; i < 32 || len(seen) < 2; i++ {
+		privateKey := sha256.Sum256([]byte(fmt.Sprintf("private key %d", i)))
+		hash := sha256.Sum256([]byte(fmt.Sprintf("message %d", i)))
+		sig, err := SignRecoverable(privateKey[:], hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sig) != 65 || sig[64] > 1 {
+			t.Fatalf("key %d: signature %x", i, sig)
+		}
+		seen[sig[64]] = true
+
+		// The recoverable signature is the same as the plain one
+		plain, _ := Sign(privateKey[:], hash[:])
+		if !bytes.Equal(plain.SerializeCompact(), sig[:64]) {
+			t.Fatalf("key %d: recoverable signature differs from Sign", i)
+		}
+
+		x, y, err := RecoverPublicKey(hash[:], sig)
+		if err != nil {
+			t.Fatalf("key %d: %v", i, err)
+		}
+		wantX, wantY := secp256k1.ScalarBaseMult(privateKey[:])
+		if x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0 {
+			t.Fatalf("key %d: recovered another key", i)
+		}
+
+		sig[64] ^= 1
+		if x, _, err := RecoverPublicKey(hash[:], sig); err == nil && x.Cmp(wantX) == 0 {
+			t.Fatalf("key %d: the other parity recovered the same key", i)
+		}
+	}
+}
+
+// TestRecoverOverflowedR covers recovery ids 2 and 3, where R.x = r + N.
+// Signing practically never yields them, but any recovered key is the one
+// the signature verifies with.
+func TestRecoverOverflowedR(t *testing.T) {
+	rx := new(big.Int).Add(secp256k1.N, big.NewInt(1))
+	for {
+		if _, err := secp256k1.decompressY(rx, false); err == nil {
+			break
+		}
+		rx.Add(rx, big.NewInt(1))
+	}
+	r := new(big.Int).Sub(rx, secp256k1.N)
+	sig := &Signature{R: r, S: big.NewInt(12345)}
+	hash := sha256.Sum256([]byte("message"))
+
+	var keys []*big.Int
+	for _, recoveryID := range []byte{2, 3} {
+		x, y, err := RecoverPublicKey(hash[:], append(sig.SerializeCompact(), recoveryID))
+		if err != nil {
+			t.Fatalf("id %d: %v", recoveryID, err)
+		}
+		if !Verify(x, y, hash[:], sig) {
+			t.Errorf("id %d: signature doesn't verify with the recovered key", recoveryID)
+		}
+		keys = append(keys, x)
+	}
+	if keys[0].Cmp(keys[1]) == 0 {
+		t.Error("both parities recovered the same key")
+	}
+}
+
+func TestRecoverPublicKeyInvalid(t *testing.T) {
+	privateKey := sha256.Sum256([]byte("private key"))
+	hash := sha256.Sum256([]byte("message"))
+	sig, err := SignRecoverable(privateKey[:], hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	withID := func(id byte) []byte {
+		return append(append([]byte{}, sig[:64]...), id)
+	}
+	withRS := func(r, s *big.Int) []byte {
+		b := make([]byte, 65)
+		r.FillBytes(b[:32])
+		s.FillBytes(b[32:64])
+		return b
+	}
+	one := big.NewInt(1)
+
+	tests := []struct {
+		name string
+		sig  []byte
+		err  error
+	}{
+		{"short", sig[:64], ErrInvalidRecoverableSignature},
+		{"long", append(withID(0), 0), ErrInvalidRecoverableSignature},
+		{"id 4", withID(4), ErrInvalidRecoverableSignature},
+		{"id 27", withID(27), ErrInvalidRecoverableSignature},
+		{"zero r", withRS(new(big.Int), one), ErrInvalidSignature},
+		{"zero s", withRS(one, new(big.Int)), ErrInvalidSignature},
+		{"r equal to N", withRS(secp256k1.N, one), ErrInvalidSignature},
+		{"s equal to N", withRS(one, secp256k1.N), ErrInvalidSignature},
+		// r + N isn't a field element
+		{"overflowed r too large", append(withRS(new(big.Int).Sub(secp256k1.P, secp256k1.N), one)[:64], 2), ErrPublicKeyNotRecovered},
+	}
+	for _, test := range tests {
+		if _, _, err := RecoverPublicKey(hash[:], test.sig); err != test.err {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	// An r that isn't the x coordinate of a point
+	r := big.NewInt(1)
+	for {
+		if _, err := secp256k1.decompressY(r, false); err != nil {
+			break
+		}
+		r.Add(r, one)
+	}
+	if _, _, err := RecoverPublicKey(hash[:], withRS(r, one)); err != ErrPublicKeyNotRecovered {
+		t.Errorf("r off the curve: got %v, want %v", err, ErrPublicKeyNotRecovered)
+	}
+}
@@ -0,0 +1,201 @@
+package crypto
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// scalar is an integer modulo the group order N stored as four 64 bits
+// little-endian limbs. Like fieldVal, all operations keep values fully
+// reduced and run in constant time.
+type scalar [4]uint64
+
+// scalarN is the group order.
+var scalarN = scalar{0xBFD25E8CD0364141, 0xBAAEDCE6AF48A03B, 0xFFFFFFFFFFFFFFFE, 0xFFFFFFFFFFFFFFFF}
+
+// scalarC is 2^256 - N, a 129 bits value used to fold the high half of
+// products.
+var scalarC = [3]uint64{0x402DA1732FC9BEBF, 0x4551231950B75FC4, 1}
+
+var scalarNMinus2 = scalar{0xBFD25E8CD036413F, 0xBAAEDCE6AF48A03B, 0xFFFFFFFFFFFFFFFE, 0xFFFFFFFFFFFFFFFF}
+
+// setBytes sets s to the big-endian value b reduced modulo N.
+func (s *scalar) setBytes(b *[32]byte) *scalar {
+	for i := 0; i < 4; i++ {
+		s[i] = uint64(b[31-8*i]) | uint64(b[30-8*i])<<8 | uint64(b[29-8*i])<<16 |
+			uint64(b[28-8*i])<<24 | uint64(b[27-8*i])<<32 | uint64(b[26-8*i])<<40 |
+			uint64(b[25-8*i])<<48 | uint64(b[24-8*i])<<56
+	}
+	s.reduce(0)
+	return s
+}
+
+// setByteSlice sets s to the big-endian value b reduced modulo N. Inputs
+// longer than 32 bytes are reduced with math/big and aren't constant time.
+func (s *scalar) setByteSlice(b []byte) *scalar {
+	var buf [32]byte
+	if len(b) > 32 {
+		new(big.Int).Mod(new(big.Int).SetBytes(b), secp256k1.N).FillBytes(buf[:])
+	} else {
+		copy(buf[32-len(b):], b)
+	}
+	return s.setBytes(&buf)
+}
+
+// bytes returns the 32 bytes big-endian encoding of s.
+func (s *scalar) bytes() [32]byte {
+	var b [32]byte
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			b[31-8*i-j] = byte(s[i] >> (8 * uint(j)))
+		}
+	}
+	return b
+}
+
+// toInt returns s as a big.Int.
+func (s *scalar) toInt() *big.Int {
+	b := s.bytes()
+	return new(big.Int).SetBytes(b[:])
+}
+
+// reduce subtracts N once if s (with an extra carry bit) is not below N.
+func (s *scalar) reduce(carry uint64) {
+	var t scalar
+	var borrow uint64
+	t[0], borrow = bits.Sub64(s[0], scalarN[0], 0)
+	t[1], borrow = bits.Sub64(s[1], scalarN[1], borrow)
+	t[2], borrow = bits.Sub64(s[2], scalarN[2], borrow)
+	t[3], borrow = bits.Sub64(s[3], scalarN[3], borrow)
+	s.cmov(&t, carry|(borrow^1))
+}
+
+// cmov sets s to a when flag is 1 and leaves it unchanged when flag is 0.
+func (s *scalar) cmov(a *scalar, flag uint64) {
+	mask := -flag
+	s[0] ^= (s[0] ^ a[0]) & mask
+	s[1] ^= (s[1] ^ a[1]) & mask
+	s[2] ^= (s[2] ^ a[2]) & mask
+	s[3] ^= (s[3] ^ a[3]) & mask
+}
+
+// add sets s = a + b.
+func (s *scalar) add(a, b *scalar) *scalar {
+	var carry uint64
+	s[0], carry = bits.Add64(a[0], b[0], 0)
+	s[1], carry = bits.Add64(a[1], b[1], carry)
+	s[2], carry = bits.Add64(a[2], b[2], carry)
+	s[3], carry = bits.Add64(a[3], b[3], carry)
+	s.reduce(carry)
+	return s
+}
+
+// neg sets s = -a.
+func (s *scalar) neg(a *scalar) *scalar {
+	var borrow uint64
+	var t scalar
+	t[0], borrow = bits.Sub64(scalarN[0], a[0], 0)
+	t[1], borrow = bits.Sub64(scalarN[1], a[1], borrow)
+	t[2], borrow = bits.Sub64(scalarN[2], a[2], borrow)
+	t[3], _ = bits.Sub64(scalarN[3], a[3], borrow)
+
+	// N - 0 must map back to 0
+	var zero scalar
+	t.cmov(&zero, a.isZero())
+	*s = t
+	return s
+}
+
+// condNeg negates s when flag is 1.
+func (s *scalar) condNeg(flag uint64) *scalar {
+	var t scalar
+	t.neg(s)
+	s.cmov(&t, flag)
+	return s
+}
+
+// mul sets s = a * b.
+func (s *scalar) mul(a, b *scalar) *scalar {
+	var t [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(a[i], b[j])
+			var c uint64
+			lo, c = bits.Add64(lo, t[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			t[i+j] = lo
+			carry = hi
+		}
+		t[i+4] = carry
+	}
+
+	// Each fold replaces hi * 2^256 with hi * C, shrinking the value from
+	// 512 bits to 386, 260, 257 and finally 256 bits
+	for i := 0; i < 4; i++ {
+		t = scalarFold(&t)
+	}
+
+	copy(s[:], t[:4])
+	s.reduce(0)
+	return s
+}
+
+// scalarFold returns lo + hi * C for t = lo + hi * 2^256.
+func scalarFold(t *[8]uint64) [8]uint64 {
+	var r [8]uint64
+	copy(r[:4], t[:4])
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 3; j++ {
+			hi, lo := bits.Mul64(t[4+i], scalarC[j])
+			var c uint64
+			lo, c = bits.Add64(lo, r[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			r[i+j] = lo
+			carry = hi
+		}
+		for k := i + 3; k < 8; k++ {
+			r[k], carry = bits.Add64(r[k], carry, 0)
+		}
+	}
+	return r
+}
+
+// inverse sets s = a⁻¹ using Fermat's little theorem; the inverse of zero
+// is zero.
+func (s *scalar) inverse(a *scalar) *scalar {
+	base := *a
+	r := scalar{1, 0, 0, 0}
+	for i := 255; i >= 0; i-- {
+		r.mul(&r, &r)
+		if (scalarNMinus2[i/64]>>(uint(i)%64))&1 == 1 {
+			r.mul(&r, &base)
+		}
+	}
+	*s = r
+	return s
+}
+
+// isZero returns 1 if s is zero and 0 otherwise.
+func (s *scalar) isZero() uint64 {
+	v := s[0] | s[1] | s[2] | s[3]
+	return 1 ^ ((v | -v) >> 63)
+}
+
+// isHigh returns 1 if s is greater than N/2 and 0 otherwise.
+func (s *scalar) isHigh() uint64 {
+	// N/2 - s borrows exactly when s > N/2
+	var borrow uint64
+	_, borrow = bits.Sub64(scalarHalfN[0], s[0], 0)
+	_, borrow = bits.Sub64(scalarHalfN[1], s[1], borrow)
+	_, borrow = bits.Sub64(scalarHalfN[2], s[2], borrow)
+	_, borrow = bits.Sub64(scalarHalfN[3], s[3], borrow)
+	return borrow
+}
+
+var scalarHalfN = scalar{0xDFE92F46681B20A0, 0x5D576E7357A4501D, 0xFFFFFFFFFFFFFFFF, 0x7FFFFFFFFFFFFFFF}
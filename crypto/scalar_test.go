@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestScalarArithmetic(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	n := secp256k1.N
+	halfN := new(big.Int).Rsh(n, 1)
+	for i := 0; i < 10000; i++ {
+		a, b := randInt(r, n), randInt(r, n)
+		var sa, sb, s scalar
+		sa.setByteSlice(a.Bytes())
+		sb.setByteSlice(b.Bytes())
+
+		check := func(op string, got *scalar, want *big.Int) {
+			t.Helper()
+			want.Mod(want, n)
+			if got.toInt().Cmp(want) != 0 {
+				t.Fatalf("%s(%x, %x) = %x, want %x", op, a, b, got.toInt(), want)
+			}
+		}
+		check("add", s.add(&sa, &sb), new(big.Int).Add(a, b))
+		check("mul", s.mul(&sa, &sb), new(big.Int).Mul(a, b))
+		check("neg", s.neg(&sa), new(big.Int).Neg(a))
+		s = sa
+		check("condNeg(0)", s.condNeg(0), new(big.Int).Set(a))
+		s = sa
+		check("condNeg(1)", s.condNeg(1), new(big.Int).Neg(a))
+		if a.Sign() != 0 && i%20 == 0 {
+			check("inverse", s.inverse(&sa), new(big.Int).ModInverse(a, n))
+		}
+
+		if (sa.isZero() == 1) != (a.Sign() == 0) {
+			t.Fatalf("isZero(%x) = %d", a, sa.isZero())
+		}
+		if (sa.isHigh() == 1) != (a.Cmp(halfN) > 0) {
+			t.Fatalf("isHigh(%x) = %d", a, sa.isHigh())
+		}
+	}
+}
+
+func TestScalarSetByteSliceReduces(t *testing.T) {
+	n := secp256k1.N
+	for _, v := range []*big.Int{
+		new(big.Int).Set(n),
+		new(big.Int).Add(n, big.NewInt(7)),
+		new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)),
+		new(big.Int).Lsh(big.NewInt(1), 300),
+	} {
+		var s scalar
+		want := new(big.Int).Mod(v, n)
+		if s.setByteSlice(v.Bytes()).toInt().Cmp(want) != 0 {
+			t.Errorf("setByteSlice(%x) = %x, want %x", v, s.toInt(), want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+var (
+	// ErrInvalidAuxRand is returned when the auxiliary randomness isn't 32 bytes
+	ErrInvalidAuxRand = errors.New("Auxiliary randomness should be exactly 32 bytes")
+
+	// ErrSchnorrSignatureFailed is returned when a freshly produced signature
+	// doesn't verify
+	ErrSchnorrSignatureFailed = errors.New("Schnorr signature failed verification")
+)
+
+// TaggedHash computes the BIP340 tagged hash
+// SHA256(SHA256(tag) || SHA256(tag) || msgs...).
+func TaggedHash(tag string, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	hasher := sha256.New()
+	hasher.Write(tagHash[:])
+	hasher.Write(tagHash[:])
+	for _, msg := range msgs {
+		hasher.Write(msg)
+	}
+	return hasher.Sum(nil)
+}
+
+// XOnlyPublicKey returns the 32 bytes x-only public key of a private key.
+func XOnlyPublicKey(privateKey []byte) ([]byte, error) {
+	d := new(big.Int).SetBytes(privateKey)
+	if d.Sign() == 0 || d.Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	x, _ := secp256k1.ScalarBaseMult(privateKey)
+	return intToBytes32(x), nil
+}
+
+// SchnorrSign produces a BIP340 signature of msg. auxRand should be 32
+// bytes of fresh randomness; nil is treated as 32 zero bytes.
+func SchnorrSign(privateKey []byte, msg []byte, auxRand []byte) ([]byte, error) {
+	curve := secp256k1
+	if auxRand == nil {
+		auxRand = make([]byte, 32)
+	}
+	if len(auxRand) != 32 {
+		return nil, ErrInvalidAuxRand
+	}
+
+	d := new(big.Int).SetBytes(privateKey)
+	if d.Sign() == 0 || d.Cmp(curve.N) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	var dScalar scalar
+	dScalar.setByteSlice(privateKey)
+	px, py := curve.ScalarBaseMult(privateKey)
+	dScalar.condNeg(uint64(py.Bit(0)))
+	pxBytes := intToBytes32(px)
+
+	// t = bytes(d) xor hash_BIP0340/aux(a)
+	t := dScalar.bytes()
+	aux := TaggedHash("BIP0340/aux", auxRand)
+	for i := range t {
+		t[i] ^= aux[i]
+	}
+
+	rand := TaggedHash("BIP0340/nonce", t[:], pxBytes, msg)
+	var k scalar
+	k.setByteSlice(rand)
+	if k.isZero() == 1 {
+		return nil, ErrSchnorrSignatureFailed
+	}
+	kBytes := k.bytes()
+	rx, ry := curve.ScalarBaseMult(kBytes[:])
+	k.condNeg(uint64(ry.Bit(0)))
+	rxBytes := intToBytes32(rx)
+
+	var e scalar
+	e.setByteSlice(schnorrChallenge(rxBytes, pxBytes, msg).Bytes())
+
+	// s = (k + ed) mod N
+	var sum scalar
+	sum.mul(&e, &dScalar)
+	sum.add(&sum, &k)
+	s := sum.bytes()
+
+	sig := append(rxBytes, s[:]...)
+	if !SchnorrVerify(pxBytes, msg, sig) {
+		return nil, ErrSchnorrSignatureFailed
+	}
+	return sig, nil
+}
+
+// SchnorrVerify reports whether sig is a valid BIP340 signature of msg by
+// the 32 bytes x-only public key.
+func SchnorrVerify(publicKey []byte, msg []byte, sig []byte) bool {
+	curve := secp256k1
+	if len(publicKey) != 32 || len(sig) != 64 {
+		return false
+	}
+
+	px, py, err := LiftX(publicKey)
+	if err != nil {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	if r.Cmp(curve.P) >= 0 {
+		return false
+	}
+	s := new(big.Int).SetBytes(sig[32:])
+	if s.Cmp(curve.N) >= 0 {
+		return false
+	}
+
+	// R = sG - eP
+	e := schnorrChallenge(sig[:32], publicKey, msg)
+	if e.Sign() != 0 {
+		e.Sub(curve.N, e)
+	}
+	sx, sy := curve.ScalarBaseMult(s.Bytes())
+	ex, ey := curve.ScalarMult(px, py, e.Bytes())
+	rx, ry := addPoints(sx, sy, ex, ey)
+	if rx == nil || ry.Bit(0) == 1 {
+		return false
+	}
+	return rx.Cmp(r) == 0
+}
+
+// LiftX returns the point with the given 32 bytes x coordinate and an even y.
+func LiftX(x []byte) (*big.Int, *big.Int, error) {
+	px := new(big.Int).SetBytes(x)
+	py, err := secp256k1.decompressY(px, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return px, py, nil
+}
+
+// schnorrChallenge computes int(hash_BIP0340/challenge(r || P || m)) mod N.
+func schnorrChallenge(r, publicKey, msg []byte) *big.Int {
+	e := new(big.Int).SetBytes(TaggedHash("BIP0340/challenge", r, publicKey, msg))
+	return e.Mod(e, secp256k1.N)
+}
+
+func intToBytes32(n *big.Int) []byte {
+	b := make([]byte, 32)
+	n.FillBytes(b)
+	return b
+}
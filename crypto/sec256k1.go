@@ -8,14 +8,18 @@
 package crypto
 
 
-// See http://www.hyperelliptic.org/EFD/g1p/auto-shortw.html
-// and http://stackoverflow.com/a/8392111/174463
-// for details on how this Koblitz curve math works.
+// The big.Int methods below satisfy elliptic.Curve and delegate to the
+// fixed-width field (field.go), scalar (scalar.go) and point (point.go)
+// arithmetic, which runs in constant time.
 
 import "crypto/elliptic"
+import "errors"
 import "fmt"
 import "math/big"
 
+// ErrPointNotOnCurve is returned when an x coordinate has no matching y
+var ErrPointNotOnCurve = errors.New("Point is not on the curve")
+
 // A Koblitz Curve with a=0.
 type KoblitzCurve struct {
 	P       *big.Int // the order of the underlying field
@@ -49,183 +53,85 @@ func init() {
 	}
 }
 
+// IsOnCurve reports whether (x, y) is a point on the curve with both
+// coordinates in [0, P-1].
 func (curve *KoblitzCurve) IsOnCurve(x, y *big.Int) bool {
-	// y² = x³ + b
-	y2 := new(big.Int).Mul(y, y)
-	y2.Mod(y2, curve.P)
-
-	x3 := new(big.Int).Mul(x, x)
-	x3.Mul(x3, x)
-
-	x3.Add(x3, curve.B)
-	x3.Mod(x3, curve.P)
+	if x == nil || y == nil || x.Sign() < 0 || x.Cmp(curve.P) >= 0 ||
+		y.Sign() < 0 || y.Cmp(curve.P) >= 0 {
+		return false
+	}
 
-	return x3.Cmp(y2) == 0
+	// y² = x³ + b
+	var fx, fy, lhs, rhs fieldVal
+	fx.setInt(x)
+	fy.setInt(y)
+	lhs.square(&fy)
+	rhs.square(&fx)
+	rhs.mul(&rhs, &fx)
+	rhs.add(&rhs, &fieldVal{7})
+	return lhs.equal(&rhs) == 1
 }
 
-// affineFromJacobian reverses the Jacobian transform.
-func (curve *KoblitzCurve) affineFromJacobian(x, y, z *big.Int) (xOut, yOut *big.Int) {
-	zinv := new(big.Int).ModInverse(z, curve.P)
-	zinvsq := new(big.Int).Mul(zinv, zinv)
+// decompressY returns the y coordinate of the point with the given x and
+// parity, as described at https://crypto.stackexchange.com/a/8916
+func (curve *KoblitzCurve) decompressY(x *big.Int, odd bool) (*big.Int, error) {
+	if x.Sign() < 0 || x.Cmp(curve.P) >= 0 {
+		return nil, ErrPointNotOnCurve
+	}
 
-	xOut = new(big.Int).Mul(x, zinvsq)
-	xOut.Mod(xOut, curve.P)
+	// y² = x³ + b
+	var fx, fy, ySquared fieldVal
+	fx.setInt(x)
+	ySquared.square(&fx)
+	ySquared.mul(&ySquared, &fx)
+	ySquared.add(&ySquared, &fieldVal{7})
+	if !fy.sqrt(&ySquared) {
+		return nil, ErrPointNotOnCurve
+	}
 
-	zinvsq.Mul(zinvsq, zinv)
-	yOut = new(big.Int).Mul(y, zinvsq)
-	yOut.Mod(yOut, curve.P)
-	return
+	var negY fieldVal
+	negY.neg(&fy)
+	wantOdd := uint64(0)
+	if odd {
+		wantOdd = 1
+	}
+	fy.cmov(&negY, fy.isOdd()^wantOdd)
+	return fy.toInt(), nil
 }
 
+// Add returns the sum of (x1, y1) and (x2, y2). nil, nil stands for the point
+// at infinity both as input and output.
 func (curve *KoblitzCurve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
-	z := new(big.Int).SetInt64(1)
-	return curve.affineFromJacobian(curve.addJacobian(x1, y1, z, x2, y2, z))
-}
-
-// addJacobian takes two points in Jacobian coordinates, (x1, y1, z1) and
-// (x2, y2, z2) and returns their sum, also in Jacobian form.
-func (curve *KoblitzCurve) addJacobian(x1, y1, z1, x2, y2, z2 *big.Int) (*big.Int, *big.Int, *big.Int) {
-	// See http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#addition-add-2007-bl
-	z1z1 := new(big.Int).Mul(z1, z1)
-	z1z1.Mod(z1z1, curve.P)
-	z2z2 := new(big.Int).Mul(z2, z2)
-	z2z2.Mod(z2z2, curve.P)
-
-	u1 := new(big.Int).Mul(x1, z2z2)
-	u1.Mod(u1, curve.P)
-	u2 := new(big.Int).Mul(x2, z1z1)
-	u2.Mod(u2, curve.P)
-	h := new(big.Int).Sub(u2, u1)
-	if h.Sign() == -1 {
-		h.Add(h, curve.P)
-	}
-	i := new(big.Int).Lsh(h, 1)
-	i.Mul(i, i)
-	j := new(big.Int).Mul(h, i)
-
-	s1 := new(big.Int).Mul(y1, z2)
-	s1.Mul(s1, z2z2)
-	s1.Mod(s1, curve.P)
-	s2 := new(big.Int).Mul(y2, z1)
-	s2.Mul(s2, z1z1)
-	s2.Mod(s2, curve.P)
-	r := new(big.Int).Sub(s2, s1)
-	if r.Sign() == -1 {
-		r.Add(r, curve.P)
-	}
-	r.Lsh(r, 1)
-	v := new(big.Int).Mul(u1, i)
-
-	x3 := new(big.Int).Set(r)
-	x3.Mul(x3, x3)
-	x3.Sub(x3, j)
-	x3.Sub(x3, v)
-	x3.Sub(x3, v)
-	x3.Mod(x3, curve.P)
-
-	y3 := new(big.Int).Set(r)
-	v.Sub(v, x3)
-	y3.Mul(y3, v)
-	s1.Mul(s1, j)
-	s1.Lsh(s1, 1)
-	y3.Sub(y3, s1)
-	y3.Mod(y3, curve.P)
-
-	z3 := new(big.Int).Add(z1, z2)
-	z3.Mul(z3, z3)
-	z3.Sub(z3, z1z1)
-	if z3.Sign() == -1 {
-		z3.Add(z3, curve.P)
-	}
-	z3.Sub(z3, z2z2)
-	if z3.Sign() == -1 {
-		z3.Add(z3, curve.P)
-	}
-	z3.Mul(z3, h)
-	z3.Mod(z3, curve.P)
-
-	return x3, y3, z3
+	var p1, p2 projectivePoint
+	p1.setAffine(x1, y1)
+	p2.setAffine(x2, y2)
+	return p1.add(&p1, &p2).toAffine()
 }
 
+// Double returns 2*(x1, y1).
 func (curve *KoblitzCurve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
-	z1 := new(big.Int).SetInt64(1)
-	return curve.affineFromJacobian(curve.doubleJacobian(x1, y1, z1))
-}
-
-// doubleJacobian takes a point in Jacobian coordinates, (x, y, z), and
-// returns its double, also in Jacobian form.
-func (curve *KoblitzCurve) doubleJacobian(x, y, z *big.Int) (*big.Int, *big.Int, *big.Int) {
-	// See http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html#doubling-dbl-2009-l
-
-	a := new(big.Int).Mul(x, x) //X1²
-	b := new(big.Int).Mul(y, y) //Y1²
-	c := new(big.Int).Mul(b, b) //B²
-
-	d := new(big.Int).Add(x, b) //X1+B
-	d.Mul(d, d)                 //(X1+B)²
-	d.Sub(d, a)                 //(X1+B)²-A
-	d.Sub(d, c)                 //(X1+B)²-A-C
-	d.Mul(d, big.NewInt(2))     //2*((X1+B)²-A-C)
-
-	e := new(big.Int).Mul(big.NewInt(3), a) //3*A
-	f := new(big.Int).Mul(e, e)             //E²
-
-	x3 := new(big.Int).Mul(big.NewInt(2), d) //2*D
-	x3.Sub(f, x3)                            //F-2*D
-	x3.Mod(x3, curve.P)
-
-	y3 := new(big.Int).Sub(d, x3)                  //D-X3
-	y3.Mul(e, y3)                                  //E*(D-X3)
-	y3.Sub(y3, new(big.Int).Mul(big.NewInt(8), c)) //E*(D-X3)-8*C
-	y3.Mod(y3, curve.P)
-
-	z3 := new(big.Int).Mul(y, z) //Y1*Z1
-	z3.Mul(big.NewInt(2), z3)    //3*Y1*Z1
-	z3.Mod(z3, curve.P)
-
-	return x3, y3, z3
+	var p projectivePoint
+	p.setAffine(x1, y1)
+	return p.double(&p).toAffine()
 }
 
+// ScalarMult returns k*(Bx, By) where k is a big-endian integer. The point at
+// infinity, e.g. for a zero k, is returned as nil, nil.
 func (curve *KoblitzCurve) ScalarMult(Bx, By *big.Int, k []byte) (*big.Int, *big.Int) {
-	// We have a slight problem in that the identity of the group (the
-	// point at infinity) cannot be represented in (x, y) form on a finite
-	// machine. Thus the standard add/double algorithm has to be tweaked
-	// slightly: our initial state is not the identity, but x, and we
-	// ignore the first true bit in |k|.  If we don't find any true bits in
-	// |k|, then we return nil, nil, because we cannot return the identity
-	// element.
-
-	Bz := new(big.Int).SetInt64(1)
-	x := Bx
-	y := By
-	z := Bz
-
-	seenFirstTrue := false
-	for _, byte := range k {
-		for bitNum := 0; bitNum < 8; bitNum++ {
-			if seenFirstTrue {
-				x, y, z = curve.doubleJacobian(x, y, z)
-			}
-			if byte&0x80 == 0x80 {
-				if !seenFirstTrue {
-					seenFirstTrue = true
-				} else {
-					x, y, z = curve.addJacobian(Bx, By, Bz, x, y, z)
-				}
-			}
-			byte <<= 1
-		}
-	}
-
-	if !seenFirstTrue {
-		return nil, nil
-	}
-
-	return curve.affineFromJacobian(x, y, z)
+	var p projectivePoint
+	var s scalar
+	p.setAffine(Bx, By)
+	s.setByteSlice(k)
+	return p.scalarMult(&p, &s).toAffine()
 }
 
+// ScalarBaseMult returns k*G where G is the base point of the curve, using
+// a lazily built table of multiples of G.
 func (curve *KoblitzCurve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
-	return curve.ScalarMult(curve.Gx, curve.Gy, k)
+	var p projectivePoint
+	var s scalar
+	s.setByteSlice(k)
+	return p.scalarBaseMult(&s).toAffine()
 }
 
 func (curve *KoblitzCurve) Params() *elliptic.CurveParams {
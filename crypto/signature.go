@@ -0,0 +1,308 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+var (
+	// ErrInvalidPrivateKey is returned when signing with a key outside [1, N-1]
+	ErrInvalidPrivateKey = errors.New("Invalid private key")
+
+	// ErrInvalidSignature is returned when a signature can't be parsed
+	ErrInvalidSignature = errors.New("Invalid signature")
+
+	// ErrInvalidSignatureLength is returned when a compact signature isn't 64 bytes
+	ErrInvalidSignatureLength = errors.New("Compact signatures should be exactly 64 bytes")
+)
+
+// Signature is an ECDSA signature over secp256k1.
+type Signature struct {
+	R *big.Int
+	S *big.Int
+}
+
+// Sign produces a deterministic (RFC 6979) ECDSA signature of hash with the
+// 32 bytes private key. The returned S is always in the lower half of the
+// curve order as required by BIP 62.
+func Sign(privateKey []byte, hash []byte) (*Signature, error) {
+	sig, _, err := sign(privateKey, hash)
+	return sig, err
+}
+
+// sign returns the signature together with its recovery id: bit 0 is the
+// parity of R.y and bit 1 is set when R.x overflowed the curve order.
+func sign(privateKey []byte, hash []byte) (*Signature, byte, error) {
+	curve := secp256k1
+	d := new(big.Int).SetBytes(privateKey)
+	if d.Sign() == 0 || d.Cmp(curve.N) >= 0 {
+		return nil, 0, ErrInvalidPrivateKey
+	}
+
+	var dScalar, e scalar
+	dScalar.setByteSlice(privateKey)
+	e.setByteSlice(hashToInt(hash).Bytes())
+
+	nonces := newRFC6979(privateKey, hash)
+	for {
+		k := nonces.next()
+
+		kBytes := k.bytes()
+		x, y := curve.ScalarBaseMult(kBytes[:])
+		var r scalar
+		r.setByteSlice(x.Bytes())
+		if r.isZero() == 1 {
+			continue
+		}
+		recoveryID := byte(y.Bit(0))
+		if x.Cmp(curve.N) >= 0 {
+			recoveryID |= 0x02
+		}
+
+		// s = k⁻¹(e + rd) mod N
+		var s, kInv scalar
+		s.mul(&r, &dScalar)
+		s.add(&s, &e)
+		s.mul(&s, kInv.inverse(k))
+		if s.isZero() == 1 {
+			continue
+		}
+
+		// Negating S negates R, which flips the parity of R.y
+		high := s.isHigh()
+		s.condNeg(high)
+		recoveryID ^= byte(high)
+		return &Signature{R: r.toInt(), S: s.toInt()}, recoveryID, nil
+	}
+}
+
+// Verify reports whether sig is a valid signature of hash by the public key
+// (x, y). Both low and high S values are accepted.
+func Verify(x, y *big.Int, hash []byte, sig *Signature) bool {
+	curve := secp256k1
+	if sig == nil || sig.R == nil || sig.S == nil || x == nil || y == nil {
+		return false
+	}
+	if sig.R.Sign() <= 0 || sig.R.Cmp(curve.N) >= 0 ||
+		sig.S.Sign() <= 0 || sig.S.Cmp(curve.N) >= 0 {
+		return false
+	}
+	if !curve.IsOnCurve(x, y) {
+		return false
+	}
+
+	// u1 = e/s, u2 = r/s
+	e := hashToInt(hash)
+	w := new(big.Int).ModInverse(sig.S, curve.N)
+	u1 := e.Mul(e, w)
+	u1.Mod(u1, curve.N)
+	u2 := w.Mul(sig.R, w)
+	u2.Mod(u2, curve.N)
+
+	x1, y1 := curve.ScalarBaseMult(u1.Bytes())
+	x2, y2 := curve.ScalarMult(x, y, u2.Bytes())
+	px, _ := addPoints(x1, y1, x2, y2)
+	if px == nil {
+		return false
+	}
+
+	px.Mod(px, curve.N)
+	return px.Cmp(sig.R) == 0
+}
+
+// IsLowS reports whether S is in the lower half of the curve order.
+func (sig *Signature) IsLowS() bool {
+	return sig.S.Cmp(halfOrder) <= 0
+}
+
+// Serialize returns the strict DER encoding of the signature.
+func (sig *Signature) Serialize() []byte {
+	r := canonicalizeInt(sig.R)
+	s := canonicalizeInt(sig.S)
+
+	buffer := new(bytes.Buffer)
+	buffer.WriteByte(0x30)
+	buffer.WriteByte(byte(4 + len(r) + len(s)))
+	buffer.WriteByte(0x02)
+	buffer.WriteByte(byte(len(r)))
+	buffer.Write(r)
+	buffer.WriteByte(0x02)
+	buffer.WriteByte(byte(len(s)))
+	buffer.Write(s)
+	return buffer.Bytes()
+}
+
+// SerializeCompact returns the 64 bytes R || S encoding of the signature.
+func (sig *Signature) SerializeCompact() []byte {
+	b := make([]byte, 64)
+	sig.R.FillBytes(b[:32])
+	sig.S.FillBytes(b[32:])
+	return b
+}
+
+// ParseDERSignature parses a strict DER (BIP 66) encoded signature.
+func ParseDERSignature(data []byte) (*Signature, error) {
+	// 0x30 <len> 0x02 <lenR> <R> 0x02 <lenS> <S>
+	if len(data) < 8 || len(data) > 72 {
+		return nil, ErrInvalidSignature
+	}
+	if data[0] != 0x30 || int(data[1]) != len(data)-2 {
+		return nil, ErrInvalidSignature
+	}
+
+	rest := data[2:]
+	r, rest, err := parseDERInt(rest)
+	if err != nil {
+		return nil, err
+	}
+	s, rest, err := parseDERInt(rest)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrInvalidSignature
+	}
+
+	return newSignature(r, s)
+}
+
+// ParseCompactSignature parses a 64 bytes R || S encoded signature.
+func ParseCompactSignature(data []byte) (*Signature, error) {
+	if len(data) != 64 {
+		return nil, ErrInvalidSignatureLength
+	}
+	r := new(big.Int).SetBytes(data[:32])
+	s := new(big.Int).SetBytes(data[32:])
+	return newSignature(r, s)
+}
+
+func newSignature(r, s *big.Int) (*Signature, error) {
+	if r.Sign() == 0 || r.Cmp(secp256k1.N) >= 0 ||
+		s.Sign() == 0 || s.Cmp(secp256k1.N) >= 0 {
+		return nil, ErrInvalidSignature
+	}
+	return &Signature{R: r, S: s}, nil
+}
+
+func parseDERInt(data []byte) (*big.Int, []byte, error) {
+	if len(data) < 2 || data[0] != 0x02 {
+		return nil, nil, ErrInvalidSignature
+	}
+	length := int(data[1])
+	if length == 0 || length > 33 || len(data) < 2+length {
+		return nil, nil, ErrInvalidSignature
+	}
+	value := data[2 : 2+length]
+
+	// Negative numbers and unnecessary leading zeros are not allowed
+	if value[0]&0x80 != 0 {
+		return nil, nil, ErrInvalidSignature
+	}
+	if length > 1 && value[0] == 0x00 && value[1]&0x80 == 0 {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	return new(big.Int).SetBytes(value), data[2+length:], nil
+}
+
+// addPoints adds two affine points where nil stands for the point at
+// infinity, falling back to doubling when both points are equal.
+func addPoints(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	switch {
+	case x1 == nil:
+		return x2, y2
+	case x2 == nil:
+		return x1, y1
+	case x1.Cmp(x2) == 0 && y1.Cmp(y2) == 0:
+		return secp256k1.Double(x1, y1)
+	case x1.Cmp(x2) == 0:
+		// P + (-P) is the point at infinity
+		return nil, nil
+	default:
+		return secp256k1.Add(x1, y1, x2, y2)
+	}
+}
+
+// canonicalizeInt returns the minimal big-endian two's complement encoding
+// of a positive integer.
+func canonicalizeInt(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) == 0 {
+		b = []byte{0x00}
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0x00}, b...)
+	}
+	return b
+}
+
+// hashToInt converts a hash to an integer as described in SEC 1, using the
+// leftmost 256 bits only.
+func hashToInt(hash []byte) *big.Int {
+	if len(hash) > 32 {
+		hash = hash[:32]
+	}
+	return new(big.Int).SetBytes(hash)
+}
+
+var halfOrder = new(big.Int)
+
+func init() {
+	halfOrder.Rsh(secp256k1.N, 1)
+}
+
+// rfc6979 is the HMAC-SHA256 deterministic nonce generator described in
+// section 3.2 of RFC 6979.
+type rfc6979 struct {
+	k, v []byte
+	n    int
+}
+
+func newRFC6979(privateKey []byte, hash []byte) *rfc6979 {
+	x := make([]byte, 32)
+	new(big.Int).SetBytes(privateKey).FillBytes(x)
+
+	// bits2octets(h1)
+	h1 := make([]byte, 32)
+	new(big.Int).Mod(hashToInt(hash), secp256k1.N).FillBytes(h1)
+
+	g := &rfc6979{
+		k: make([]byte, 32),
+		v: bytes.Repeat([]byte{0x01}, 32),
+	}
+	g.k = g.mac(g.k, g.v, []byte{0x00}, x, h1)
+	g.v = g.mac(g.k, g.v)
+	g.k = g.mac(g.k, g.v, []byte{0x01}, x, h1)
+	g.v = g.mac(g.k, g.v)
+	return g
+}
+
+func (g *rfc6979) mac(key []byte, data ...[]byte) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// next returns the next candidate nonce in [1, N-1].
+func (g *rfc6979) next() *scalar {
+	for {
+		if g.n > 0 {
+			g.k = g.mac(g.k, g.v, []byte{0x00})
+			g.v = g.mac(g.k, g.v)
+		}
+		g.n++
+
+		g.v = g.mac(g.k, g.v)
+		k := new(big.Int).SetBytes(g.v)
+		if k.Sign() > 0 && k.Cmp(secp256k1.N) < 0 {
+			var candidate [32]byte
+			copy(candidate[:], g.v)
+			return new(scalar).setBytes(&candidate)
+		}
+	}
+}
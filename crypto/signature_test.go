@@ -0,0 +1,214 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+var (
+	benchPrivateKey = sha256.Sum256([]byte("private key"))
+	benchHash       = sha256.Sum256([]byte("message"))
+)
+
+func TestSignVerify(t *testing.T) {
+	sig, err := Sign(benchPrivateKey[:], benchHash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sig.IsLowS() {
+		t.Error("signature should have a low S")
+	}
+	x, y := secp256k1.ScalarBaseMult(benchPrivateKey[:])
+	if !Verify(x, y, benchHash[:], sig) {
+		t.Error("signature should verify")
+	}
+	otherHash := sha256.Sum256([]byte("other message"))
+	if Verify(x, y, otherHash[:], sig) {
+		t.Error("signature of another hash shouldn't verify")
+	}
+}
+
+// rfc6979Vectors are deterministic ECDSA signatures of sha256(message), as
+// published in the bitcoinjs ecdsa fixtures.
+var rfc6979Vectors = []struct {
+	privateKey string
+	message    string
+	r, s       string
+}{
+	{"0000000000000000000000000000000000000000000000000000000000000001",
+		"Everything should be made as simple as possible, but not simpler.",
+		"33a69cd2065432a30f3d1ce4eb0d59b8ab58c74f27c41a7fdb5696ad4e6108c9",
+		"6f807982866f785d3f6418d24163ddae117b7db4d5fdf0071de069fa54342262"},
+	{"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+		"Equations are more important to me, because politics is for the present, but an equation is something for eternity.",
+		"54c4a33c6423d689378f160a7ff8b61330444abb58fb470f96ea16d99d4a2fed",
+		"07082304410efa6b2943111b6a4e0aaa7b7db55a07e9861d1fb3cb1f421044a5"},
+	{"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+		"Not only is the Universe stranger than we think, it is stranger than we can think.",
+		"ff466a9f1b7b273e2f4c3ffe032eb2e814121ed18ef84665d0f515360dab3dd0",
+		"6fc95f5132e5ecfdc8e5e6e616cc77151455d46ed48f5589b7db7771a332b283"},
+	{"0000000000000000000000000000000000000000000000000000000000000001",
+		"How wonderful that we have met with a paradox. Now we have some hope of making progress.",
+		"c0dafec8251f1d5010289d210232220b03202cba34ec11fec58b3e93a85b91d3",
+		"75afdc06b7d6322a590955bf264e7aaa155847f614d80078a90292fe205064d3"},
+	{"69ec59eaa1f4f2e36b639716b7c30ca86d9a5375c7b38d8918bd9c0ebc80ba64",
+		"Computer science is no more about computers than astronomy is about telescopes.",
+		"7186363571d65e084e7f02b0b77c3ec44fb1b257dee26274c38c928986fea45d",
+		"0de0b38e06807e46bda1f1e293f4f6323e854c86d58abdd00c46c16441085df6"},
+	{"00000000000000000000000000007246174ab1e92e9149c6e446fe194d072637",
+		"...if you aren't, at any given time, scandalized by code you wrote five or even three years ago, you're not learning anywhere near enough",
+		"fbfe5076a15860ba8ed00e75e9bd22e05d230f02a936b653eb55b61c99dda487",
+		"0e68880ebb0050fe4312b1b1eb0899e1b82da89baa5b895f612619edf34cbd37"},
+	{"000000000000000000000000000000000000000000056916d0f9b31dc9b637f3",
+		"The question of whether computers can think is like the question of whether submarines can swim.",
+		"cde1302d83f8dd835d89aef803c74a119f561fbaef3eb9129e45f30de86abbf9",
+		"06ce643f5049ee1f27890467b77a6a8e11ec4661cc38cd8badf90115fbd03cef"},
+}
+
+func TestSignRFC6979Vectors(t *testing.T) {
+	for i, vector := range rfc6979Vectors {
+		privateKey := mustDecodeHex(t, vector.privateKey)
+		hash := sha256.Sum256([]byte(vector.message))
+		sig, err := Sign(privateKey, hash[:])
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		want := append(mustDecodeHex(t, vector.r), mustDecodeHex(t, vector.s)...)
+		if !bytes.Equal(sig.SerializeCompact(), want) {
+			t.Errorf("vector %d: got %x, want %x", i, sig.SerializeCompact(), want)
+		}
+		if !sig.IsLowS() {
+			t.Errorf("vector %d: signature has a high S", i)
+		}
+		x, y := secp256k1.ScalarBaseMult(privateKey)
+		if !Verify(x, y, hash[:], sig) {
+			t.Errorf("vector %d: signature doesn't verify", i)
+		}
+	}
+}
+
+func TestSignInvalidPrivateKey(t *testing.T) {
+	for _, privateKey := range [][]byte{make([]byte, 32), secp256k1.N.Bytes()} {
+		if _, err := Sign(privateKey, benchHash[:]); err != ErrInvalidPrivateKey {
+			t.Errorf("%x: got %v, want %v", privateKey, err, ErrInvalidPrivateKey)
+		}
+	}
+}
+
+func TestVerifyHighS(t *testing.T) {
+	sig, err := Sign(benchPrivateKey[:], benchHash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	highS := &Signature{R: sig.R, S: new(big.Int).Sub(secp256k1.N, sig.S)}
+	if highS.IsLowS() {
+		t.Fatal("N - S should be a high S")
+	}
+	x, y := secp256k1.ScalarBaseMult(benchPrivateKey[:])
+	if !Verify(x, y, benchHash[:], highS) {
+		t.Error("high S signatures should verify")
+	}
+	if Verify(x, y, benchHash[:], &Signature{R: sig.R, S: secp256k1.N}) {
+		t.Error("S equal to N shouldn't verify")
+	}
+}
+
+func TestSignatureEncoding(t *testing.T) {
+	for i, vector := range rfc6979Vectors {
+		privateKey := mustDecodeHex(t, vector.privateKey)
+		hash := sha256.Sum256([]byte(vector.message))
+		sig, err := Sign(privateKey, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		der := sig.Serialize()
+		parsed, err := ParseDERSignature(der)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if parsed.R.Cmp(sig.R) != 0 || parsed.S.Cmp(sig.S) != 0 {
+			t.Errorf("vector %d: DER round trip gives %x", i, parsed.Serialize())
+		}
+		parsed, err = ParseCompactSignature(sig.SerializeCompact())
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if parsed.R.Cmp(sig.R) != 0 || parsed.S.Cmp(sig.S) != 0 {
+			t.Errorf("vector %d: compact round trip gives %x", i, parsed.SerializeCompact())
+		}
+	}
+
+	// R with its high bit set needs a leading zero, S with leading zero
+	// bytes is minimal
+	sig := &Signature{R: new(big.Int).SetBytes(mustDecodeHex(t, "ff466a9f1b7b273e2f4c3ffe032eb2e814121ed18ef84665d0f515360dab3dd0")), S: big.NewInt(1)}
+	want := mustDecodeHex(t, "3026022100ff466a9f1b7b273e2f4c3ffe032eb2e814121ed18ef84665d0f515360dab3dd0020101")
+	if !bytes.Equal(sig.Serialize(), want) {
+		t.Errorf("got %x, want %x", sig.Serialize(), want)
+	}
+}
+
+func TestParseDERSignatureStrict(t *testing.T) {
+	tests := []struct {
+		name string
+		der  string
+	}{
+		{"empty", ""},
+		{"too short", "30060201010201"},
+		{"wrong sequence tag", "3106020101020101"},
+		{"wrong total length", "3007020101020101"},
+		{"wrong integer tag", "3006030101020101"},
+		{"zero length R", "30050200020101"},
+		{"R overruns", "3006020201020101"},
+		{"negative R", "3006020181020101"},
+		{"negative S", "3006020101020181"},
+		{"padded R", "300702020001020101"},
+		{"padded S", "300702010102020001"},
+		{"zero R", "3006020100020101"},
+		{"zero S", "3006020101020100"},
+		{"trailing bytes", "300802010102010102"},
+		{"S equal to N", "3026020101022100fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"},
+	}
+	for _, test := range tests {
+		if _, err := ParseDERSignature(mustDecodeHex(t, test.der)); err != ErrInvalidSignature {
+			t.Errorf("%s: got %v, want %v", test.name, err, ErrInvalidSignature)
+		}
+	}
+
+	if _, err := ParseDERSignature(mustDecodeHex(t, "3006020101020101")); err != nil {
+		t.Errorf("minimal signature: %v", err)
+	}
+	if _, err := ParseDERSignature(mustDecodeHex(t, "30070202008002017f")); err != nil {
+		t.Errorf("padded R with its high bit set: %v", err)
+	}
+	if _, err := ParseCompactSignature(make([]byte, 63)); err != ErrInvalidSignatureLength {
+		t.Errorf("short compact signature: got %v, want %v", err, ErrInvalidSignatureLength)
+	}
+	if _, err := ParseCompactSignature(make([]byte, 64)); err != ErrInvalidSignature {
+		t.Errorf("zero compact signature: got %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func BenchmarkSign(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := Sign(benchPrivateKey[:], benchHash[:]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	sig, err := Sign(benchPrivateKey[:], benchHash[:])
+	if err != nil {
+		b.Fatal(err)
+	}
+	x, y := secp256k1.ScalarBaseMult(benchPrivateKey[:])
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !Verify(x, y, benchHash[:], sig) {
+			b.Fatal("signature doesn't verify")
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package crypto
+
+import "errors"
+
+// ErrInvalidMerkleRoot is returned when a taproot merkle root is neither
+// empty nor 32 bytes
+var ErrInvalidMerkleRoot = errors.New("Merkle root should be empty or 32 bytes")
+
+// TapTweakHash computes the BIP341 tweak hash_TapTweak(internalKey ||
+// merkleRoot) for a 32 bytes x-only internal key. An empty merkleRoot
+// commits to key path spending only.
+func TapTweakHash(internalKey []byte, merkleRoot []byte) ([]byte, error) {
+	if len(merkleRoot) != 0 && len(merkleRoot) != 32 {
+		return nil, ErrInvalidMerkleRoot
+	}
+	return TaggedHash("TapTweak", internalKey, merkleRoot), nil
+}
+
+// TapTweak returns the BIP341 output key Q = P + tG, where P is the key with
+// an even y coordinate and t = hash_TapTweak(x(P) || merkleRoot).
+func (pub *PublicKey) TapTweak(merkleRoot []byte) (*PublicKey, error) {
+	internal := pub
+	if pub.Y.Bit(0) == 1 {
+		internal = pub.Negate()
+	}
+	tweak, err := TapTweakHash(internal.SerializeXOnly(), merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	return internal.TweakAdd(tweak)
+}
+
+// TapTweak returns the private key of the BIP341 output key, used to sign
+// key path spends. d is negated first when dG has an odd y coordinate.
+func (priv *PrivateKey) TapTweak(merkleRoot []byte) (*PrivateKey, error) {
+	pub := priv.PublicKey()
+	tweak, err := TapTweakHash(pub.SerializeXOnly(), merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	internal := &PrivateKey{d: priv.d}
+	internal.d.condNeg(uint64(pub.Y.Bit(0)))
+	defer internal.Zero()
+	return internal.TweakAdd(tweak)
+}
+
+// TweakTaprootPublicKey returns the 32 bytes x-only output key for a 32
+// bytes x-only internal key and an optional script tree merkle root, along
+// with the parity of the output key's y coordinate.
+func TweakTaprootPublicKey(internalKey []byte, merkleRoot []byte) ([]byte, byte, error) {
+	if len(internalKey) != 32 {
+		return nil, 0, ErrInvalidPublicKeyFormat
+	}
+	x, y, err := LiftX(internalKey)
+	if err != nil {
+		return nil, 0, ErrPublicKeyNotOnCurve
+	}
+	output, err := (&PublicKey{X: x, Y: y}).TapTweak(merkleRoot)
+	if err != nil {
+		return nil, 0, err
+	}
+	return output.SerializeXOnly(), byte(output.Y.Bit(0)), nil
+}
+
+// TweakTaprootPrivateKey returns the 32 bytes private key of the output key
+// for an internal private key and an optional script tree merkle root.
+func TweakTaprootPrivateKey(privateKey []byte, merkleRoot []byte) ([]byte, error) {
+	priv, err := PrivateKeyFromBytes(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	defer priv.Zero()
+	tweaked, err := priv.TapTweak(merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer tweaked.Zero()
+	return tweaked.Serialize(), nil
+}
@@ -0,0 +1,198 @@
+package keystore
+
+import (
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+// Purpose is the first level of a bip43 derivation path, selecting the
+// address format of an account
+type Purpose uint32
+
+const (
+	PurposeBIP44 Purpose = 44 // legacy P2PKH
+	PurposeBIP49 Purpose = 49 // nested SegWit P2SH-P2WPKH
+	PurposeBIP84 Purpose = 84 // native SegWit P2WPKH
+	PurposeBIP86 Purpose = 86 // taproot P2TR key path
+
+	// ExternalChain derives receiving addresses
+	ExternalChain = uint32(0)
+
+	// InternalChain derives change addresses
+	InternalChain = uint32(1)
+)
+
+var (
+	// ErrUnknownPurpose is returned when creating an account for a purpose
+	// other than 44, 49, 84 or 86
+	ErrUnknownPurpose = errors.New("Unknown account purpose")
+
+	// ErrNotMasterKey is returned when deriving an account from a key that
+	// isn't a private master key
+	ErrNotMasterKey = errors.New("Accounts should be derived from a private master key")
+
+	// ErrHardenedIndex is returned when an account or address index is not
+	// below FirstHardenedChild
+	ErrHardenedIndex = errors.New("Index should be below the first hardened child")
+)
+
+// Account is a bip44 style account m/purpose'/coin_type'/account'. It keeps
+// the account key and both chain keys, so deriving an address only takes a
+// single child derivation.
+type Account struct {
+	Purpose Purpose
+	Network *utils.Network
+	Index   uint32
+
+	key      *Key
+	external *Key
+	internal *Key
+}
+
+// NewAccount derives account index of the given purpose from a private
+// master key, using the coin type of the master key's network.
+func NewAccount(master *Key, purpose Purpose, index uint32) (*Account, error) {
+	if !master.IsPrivate || master.Depth != 0 {
+		return nil, ErrNotMasterKey
+	}
+	if _, err := purpose.scriptType(); err != nil {
+		return nil, err
+	}
+	if index >= FirstHardenedChild {
+		return nil, ErrHardenedIndex
+	}
+
+	net := master.network()
+	key := master
+	for _, i := range []uint32{uint32(purpose), net.CoinType, index} {
+		child, err := key.NewChildKey(FirstHardenedChild + i)
+		if key != master {
+			key.Zero()
+		}
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+	defer key.Zero()
+	return newAccount(key, purpose, index)
+}
+
+// newAccount builds an account on a copy of an account level key
+func newAccount(accountKey *Key, purpose Purpose, index uint32) (*Account, error) {
+	// Tag the account with its SLIP-132 version so exported keys keep the
+	// script type. Networks without SLIP-132 versions keep plain xprv/xpub
+	scriptType, err := purpose.scriptType()
+	if err != nil {
+		return nil, err
+	}
+	key, err := accountKey.WithScriptType(scriptType)
+	if err != nil {
+		key, err = accountKey.WithScriptType(utils.ScriptP2PKH)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	external, err := key.NewChildKey(ExternalChain)
+	if err != nil {
+		return nil, err
+	}
+	internal, err := key.NewChildKey(InternalChain)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		Purpose:  purpose,
+		Network:  key.network(),
+		Index:    index,
+		key:      key,
+		external: external,
+		internal: internal,
+	}, nil
+}
+
+// Key returns the account level extended key
+func (account *Account) Key() *Key {
+	return account.key
+}
+
+// ExtendedPublicKey returns the base58 account public key, a ypub or zpub
+// for bip49 and bip84 accounts where the network supports it
+func (account *Account) ExtendedPublicKey() string {
+	return account.key.PublicKey().B58Serialize()
+}
+
+// ExternalKey returns the key of receiving address i
+func (account *Account) ExternalKey(i uint32) (*Key, error) {
+	return deriveAddressKey(account.external, i)
+}
+
+// InternalKey returns the key of change address i
+func (account *Account) InternalKey(i uint32) (*Key, error) {
+	return deriveAddressKey(account.internal, i)
+}
+
+// ExternalAddress returns receiving address i in the account's format
+func (account *Account) ExternalAddress(i uint32) (string, error) {
+	key, err := account.ExternalKey(i)
+	if err != nil {
+		return "", err
+	}
+	defer key.Zero()
+	return account.address(key)
+}
+
+// InternalAddress returns change address i in the account's format
+func (account *Account) InternalAddress(i uint32) (string, error) {
+	key, err := account.InternalKey(i)
+	if err != nil {
+		return "", err
+	}
+	defer key.Zero()
+	return account.address(key)
+}
+
+// Zero wipes the account and chain keys. The account is unusable afterwards
+func (account *Account) Zero() {
+	account.key.Zero()
+	account.external.Zero()
+	account.internal.Zero()
+}
+
+func (account *Account) address(key *Key) (string, error) {
+	switch account.Purpose {
+	case PurposeBIP44:
+		return key.P2PKHAddress()
+	case PurposeBIP49:
+		return key.P2SHP2WPKHAddress()
+	case PurposeBIP84:
+		return key.P2WPKHAddress()
+	case PurposeBIP86:
+		return key.P2TRAddress(nil)
+	}
+	return "", ErrUnknownPurpose
+}
+
+func deriveAddressKey(chain *Key, i uint32) (*Key, error) {
+	if i >= FirstHardenedChild {
+		return nil, ErrHardenedIndex
+	}
+	return chain.NewChildKey(i)
+}
+
+// scriptType returns the SLIP-132 script type of the purpose. Taproot has
+// no SLIP-132 version and uses plain xprv/xpub
+func (purpose Purpose) scriptType() (utils.ScriptType, error) {
+	switch purpose {
+	case PurposeBIP44, PurposeBIP86:
+		return utils.ScriptP2PKH, nil
+	case PurposeBIP49:
+		return utils.ScriptP2SHP2WPKH, nil
+	case PurposeBIP84:
+		return utils.ScriptP2WPKH, nil
+	}
+	return utils.ScriptP2PKH, ErrUnknownPurpose
+}
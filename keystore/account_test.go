@@ -0,0 +1,87 @@
+package keystore
+
+import (
+	"testing"
+
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestAccountAddresses(t *testing.T) {
+	master, err := FromMnemonic(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		purpose  Purpose
+		path     string
+		external string
+	}{
+		{PurposeBIP44, "m/44'/0'/0'", "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"},
+		{PurposeBIP49, "m/49'/0'/0'", "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"},
+		{PurposeBIP84, "m/84'/0'/0'", "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+	}
+	for _, test := range tests {
+		account, err := NewAccount(master, test.purpose, 0)
+		if err != nil {
+			t.Fatalf("%d: %v", test.purpose, err)
+		}
+		address, err := account.ExternalAddress(0)
+		if err != nil {
+			t.Fatalf("%d: %v", test.purpose, err)
+		}
+		if address != test.external {
+			t.Errorf("%d: external address %s, want %s", test.purpose, address, test.external)
+		}
+
+		changeKey, err := master.DeriveChildKey(test.path + "/1/0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := account.address(changeKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		change, err := account.InternalAddress(0)
+		if err != nil {
+			t.Fatalf("%d: %v", test.purpose, err)
+		}
+		if change != want {
+			t.Errorf("%d: internal address %s, want %s", test.purpose, change, want)
+		}
+	}
+
+	account, err := NewAccount(master, PurposeBIP84, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const zpub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	if xpub := account.ExtendedPublicKey(); xpub != zpub {
+		t.Errorf("BIP84 account xpub %s, want %s", xpub, zpub)
+	}
+	if change, _ := account.InternalAddress(0); change != "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el" {
+		t.Errorf("BIP84 internal address %s", change)
+	}
+	if _, err := account.ExternalAddress(FirstHardenedChild); err != ErrHardenedIndex {
+		t.Errorf("hardened index: got %v, want %v", err, ErrHardenedIndex)
+	}
+	if _, err := NewAccount(master, Purpose(45), 0); err != ErrUnknownPurpose {
+		t.Errorf("unknown purpose: got %v, want %v", err, ErrUnknownPurpose)
+	}
+}
+
+func TestAccountTestNet(t *testing.T) {
+	master, err := NewMasterKeyForNetwork(NewSeed(testMnemonic, ""), utils.TestNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := NewAccount(master, PurposeBIP49, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// BIP49 test vector
+	if address, _ := account.ExternalAddress(0); address != "2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2" {
+		t.Errorf("BIP49 testnet address %s", address)
+	}
+}
@@ -0,0 +1,99 @@
+package keystore
+
+// DefaultGapLimit is the bip44 number of consecutive unused addresses after
+// which a chain is considered exhausted
+const DefaultGapLimit = uint32(20)
+
+// AddressChecker reports whether an address has been used on chain. It's
+// backed by a node, an indexer or, in tests, an AddressSet.
+type AddressChecker interface {
+	IsUsed(address string) (bool, error)
+}
+
+// AddressSet is an in-memory AddressChecker holding the used addresses
+type AddressSet map[string]bool
+
+// IsUsed reports whether address is in the set
+func (set AddressSet) IsUsed(address string) (bool, error) {
+	return set[address], nil
+}
+
+// UsedAddress is an address found used during discovery
+type UsedAddress struct {
+	Chain   uint32 // ExternalChain or InternalChain
+	Index   uint32
+	Address string
+}
+
+// AccountDiscovery is the result of scanning an account
+type AccountDiscovery struct {
+	Account      *Account
+	Used         []UsedAddress
+	NextExternal uint32 // first receiving index after the last used one
+	NextInternal uint32 // first change index after the last used one
+}
+
+// DiscoverAccount scans both chains of an account until gapLimit
+// consecutive addresses are unused. A zero gapLimit means DefaultGapLimit
+func DiscoverAccount(account *Account, checker AddressChecker, gapLimit uint32) (*AccountDiscovery, error) {
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+
+	result := &AccountDiscovery{Account: account}
+	var err error
+	result.NextExternal, err = result.scanChain(ExternalChain, account.ExternalAddress, checker, gapLimit)
+	if err != nil {
+		return nil, err
+	}
+	result.NextInternal, err = result.scanChain(InternalChain, account.InternalAddress, checker, gapLimit)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DiscoverAccounts walks accounts 0, 1, ... of a purpose as described in
+// bip44 and returns the used ones, stopping at the first account whose
+// external chain has no used address
+func DiscoverAccounts(master *Key, purpose Purpose, checker AddressChecker, gapLimit uint32) ([]*AccountDiscovery, error) {
+	var accounts []*AccountDiscovery
+	for index := uint32(0); index < FirstHardenedChild; index++ {
+		account, err := NewAccount(master, purpose, index)
+		if err != nil {
+			return nil, err
+		}
+		result, err := DiscoverAccount(account, checker, gapLimit)
+		if err != nil {
+			account.Zero()
+			return nil, err
+		}
+		if result.NextExternal == 0 {
+			account.Zero()
+			break
+		}
+		accounts = append(accounts, result)
+	}
+	return accounts, nil
+}
+
+// scanChain records the used addresses of a chain and returns the index
+// following the last used one
+func (result *AccountDiscovery) scanChain(chain uint32, address func(uint32) (string, error), checker AddressChecker, gapLimit uint32) (uint32, error) {
+	next := uint32(0)
+	for i := uint32(0); i < FirstHardenedChild && i-next < gapLimit; i++ {
+		addr, err := address(i)
+		if err != nil {
+			return 0, err
+		}
+		used, err := checker.IsUsed(addr)
+		if err != nil {
+			return 0, err
+		}
+		if used {
+			result.Used = append(result.Used, UsedAddress{Chain: chain, Index: i, Address: addr})
+			next = i + 1
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,141 @@
+package keystore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiscoverAccountGapLimit(t *testing.T) {
+	master, err := FromMnemonic(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := NewAccount(master, PurposeBIP84, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const gapLimit = 5
+
+	tests := []struct {
+		name         string
+		external     []uint32
+		internal     []uint32
+		nextExternal uint32
+		nextInternal uint32
+		used         int
+	}{
+		{"empty", nil, nil, 0, 0, 0},
+		{"last index inside the gap", []uint32{gapLimit - 1}, nil, gapLimit, 0, 1},
+		{"first index past the gap", []uint32{gapLimit}, nil, 0, 0, 0},
+		{"gap restarts after a used address", []uint32{0, gapLimit}, nil, gapLimit + 1, 0, 2},
+		{"gap exceeded after a used address", []uint32{0, gapLimit + 1}, nil, 1, 0, 1},
+		{"change chain", []uint32{0}, []uint32{2, 6}, 1, 7, 3},
+		{"change chain past the gap", nil, []uint32{gapLimit}, 0, 0, 0},
+	}
+	for _, test := range tests {
+		set := AddressSet{}
+		for _, i := range test.external {
+			address, err := account.ExternalAddress(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set[address] = true
+		}
+		for _, i := range test.internal {
+			address, err := account.InternalAddress(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set[address] = true
+		}
+
+		result, err := DiscoverAccount(account, set, gapLimit)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if result.NextExternal != test.nextExternal || result.NextInternal != test.nextInternal {
+			t.Errorf("%s: next external %d, internal %d, want %d, %d", test.name,
+				result.NextExternal, result.NextInternal, test.nextExternal, test.nextInternal)
+		}
+		if len(result.Used) != test.used {
+			t.Errorf("%s: %d used addresses, want %d", test.name, len(result.Used), test.used)
+		}
+		for _, used := range result.Used {
+			if !set[used.Address] {
+				t.Errorf("%s: reported unused address %s", test.name, used.Address)
+			}
+			if used.Chain != ExternalChain && used.Chain != InternalChain {
+				t.Errorf("%s: unknown chain %d", test.name, used.Chain)
+			}
+		}
+	}
+}
+
+func TestDiscoverAccounts(t *testing.T) {
+	master, err := FromMnemonic(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := AddressSet{}
+	add := func(account uint32, chain uint32, i uint32) {
+		a, err := NewAccount(master, PurposeBIP84, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		address, err := a.ExternalAddress(i)
+		if chain == InternalChain {
+			address, err = a.InternalAddress(i)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		set[address] = true
+	}
+	add(0, ExternalChain, 0)
+	add(0, ExternalChain, 3)
+	add(0, ExternalChain, 22)
+	add(0, ExternalChain, 43) // more than DefaultGapLimit after 22
+	add(0, InternalChain, 1)
+	add(1, ExternalChain, 19)
+	add(3, ExternalChain, 0) // after the unused account 2
+
+	accounts, err := DiscoverAccounts(master, PurposeBIP84, set, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("%d accounts, want 2", len(accounts))
+	}
+	if accounts[0].NextExternal != 23 || accounts[0].NextInternal != 2 || len(accounts[0].Used) != 4 {
+		t.Errorf("account 0: next %d/%d, %d used", accounts[0].NextExternal, accounts[0].NextInternal, len(accounts[0].Used))
+	}
+	if accounts[1].NextExternal != 20 || accounts[1].NextInternal != 0 {
+		t.Errorf("account 1: next %d/%d", accounts[1].NextExternal, accounts[1].NextInternal)
+	}
+
+	accounts, err = DiscoverAccounts(master, PurposeBIP84, set, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].NextExternal != 4 {
+		t.Errorf("gap limit 3: %d accounts", len(accounts))
+	}
+}
+
+type failingChecker struct{}
+
+var errCheckerFailed = errors.New("checker failed")
+
+func (failingChecker) IsUsed(address string) (bool, error) {
+	return false, errCheckerFailed
+}
+
+func TestDiscoverAccountCheckerError(t *testing.T) {
+	master, err := FromMnemonic(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DiscoverAccounts(master, PurposeBIP84, failingChecker{}, 0); err != errCheckerFailed {
+		t.Errorf("got %v, want %v", err, errCheckerFailed)
+	}
+}
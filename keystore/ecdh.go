@@ -0,0 +1,47 @@
+package keystore
+
+import (
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+var (
+	// ErrECDHWithPublicKey is returned when trying to derive a shared secret
+	// without a private key
+	ErrECDHWithPublicKey = errors.New("Can't derive a shared secret from a public key")
+)
+
+// SharedPoint returns the compressed point key * peer, where peer is the
+// other party's compressed public key
+func SharedPoint(key *Key, peer []byte) ([]byte, error) {
+	if !key.IsPrivate {
+		return nil, ErrECDHWithPublicKey
+	}
+
+	err := utils.ValidatePrivateKey(key.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = utils.ValidatePublicKey(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	point := utils.MultiplyPublicKey(peer, key.Key)
+	if point == nil {
+		return nil, utils.ErrInvalidPrivateKey
+	}
+	return point, nil
+}
+
+// ECDH derives a shared secret between key and the peer's compressed public
+// key as the SHA-256 of the compressed shared point, like libsecp256k1
+func ECDH(key *Key, peer []byte) ([]byte, error) {
+	point, err := SharedPoint(key, peer)
+	if err != nil {
+		return nil, err
+	}
+	return utils.HashSha256(point)
+}
@@ -0,0 +1,97 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+func TestECDHSymmetric(t *testing.T) {
+	alice := testKey(t, "m/0")
+	bob := testKey(t, "m/1")
+
+	aliceSecret, err := ECDH(alice, bob.PublicKey().Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobSecret, err := ECDH(bob, alice.PublicKey().Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(aliceSecret, bobSecret) {
+		t.Errorf("shared secrets differ: %x and %x", aliceSecret, bobSecret)
+	}
+
+	carol := testKey(t, "m/2")
+	carolSecret, err := ECDH(alice, carol.PublicKey().Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(aliceSecret, carolSecret) {
+		t.Error("secrets with different peers should differ")
+	}
+}
+
+func TestECDHKnownAnswer(t *testing.T) {
+	tests := []struct {
+		privateKey string
+		peer       string
+		secret     string
+	}{
+		// 1 * 2G = 2G
+		{"0000000000000000000000000000000000000000000000000000000000000001",
+			"02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+			"b1c9938f01121e159887ac2c8d393a22e4476ff8212de13fe1939de2a236f0a7"},
+		// sha256("alice") with the public key of sha256("bob")
+		{"2bd806c97f0e00af1a1fc3328fa763a9269723c8db8fac4f93af71db186d6e90",
+			"024edfcf9dfe6c0b5c83d1ab3f78d1b39a46ebac6798e08e19761f5ed89ec83c10",
+			"4e06de2520d1fe909bcf244b0a0de57c92bc6e21e28c2cdb108d980ad7d709b6"},
+	}
+	for _, test := range tests {
+		privateKey, _ := hex.DecodeString(test.privateKey)
+		peer, _ := hex.DecodeString(test.peer)
+		secret, err := ECDH(&Key{Key: privateKey, IsPrivate: true}, peer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(secret) != test.secret {
+			t.Errorf("%s: got %x, want %s", test.privateKey, secret, test.secret)
+		}
+	}
+}
+
+func TestECDHInvalidKeys(t *testing.T) {
+	alice := testKey(t, "m/0")
+	bob := testKey(t, "m/1")
+
+	if _, err := ECDH(alice.PublicKey(), bob.PublicKey().Key); err != ErrECDHWithPublicKey {
+		t.Errorf("public key: got %v, want %v", err, ErrECDHWithPublicKey)
+	}
+
+	uncompressed := append([]byte{0x04}, bob.PublicKey().Key[1:]...)
+	if _, err := ECDH(alice, uncompressed); err == nil {
+		t.Error("a peer key with a bad prefix should be rejected")
+	}
+
+	zeroed := testKey(t, "m/0")
+	zeroed.Zero()
+	if secret, err := ECDH(zeroed, bob.PublicKey().Key); err != utils.ErrInvalidPrivateKey {
+		t.Errorf("zeroed key: got %x, %v, want %v", secret, err, utils.ErrInvalidPrivateKey)
+	}
+}
+
+// testKey derives path from a fixed test master key
+func testKey(t *testing.T, path string) *Key {
+	t.Helper()
+	master, err := FromMnemonic("panel swim canvas organ claw luxury swarm quarter control december abandon able", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := master.DeriveChildKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -5,26 +5,31 @@ package keystore
 import (
 	"encoding/hex"
 	"errors"
+	"strings"
 	"crypto/hmac"
 	"crypto/sha512"
 	"bytes"
+	"github.com/icodeface/go-blockchain-kit/crypto"
 	"github.com/icodeface/go-blockchain-kit/utils"
-	"strings"
-	"strconv"
 )
 
 const (
 	// FirstHardenedChild is the index of the firxt "harded" child key as per the
 	// bip32 spec
 	FirstHardenedChild = uint32(0x80000000)
+
+	// WIFPrefix is the Bitcoin mainnet WIF prefix, see utils.Network for
+	// other networks
 	WIFPrefix = 0x80
 )
 
 var (
-	// PrivateWalletVersion is the version flag for serialized private keys
+	// PrivateWalletVersion is the Bitcoin mainnet version flag for serialized
+	// private keys, see utils.Network for other networks
 	PrivateWalletVersion, _ = hex.DecodeString("0488ADE4")
 
-	// PublicWalletVersion is the version flag for serialized private keys
+	// PublicWalletVersion is the Bitcoin mainnet version flag for serialized
+	// public keys, see utils.Network for other networks
 	PublicWalletVersion, _ = hex.DecodeString("0488B21E")
 
 	// ErrSerializedKeyWrongSize is returned when trying to deserialize a key that
@@ -34,22 +39,39 @@ var (
 	// ErrHardnedChildPublicKey is returned when trying to create a harded child
 	// of the public key
 	ErrHardnedChildPublicKey = errors.New("Can't create hardened child for public key")
+
+	// ErrSignWithPublicKey is returned when trying to sign with a public key
+	ErrSignWithPublicKey = errors.New("Can't sign with public key")
+
+	// ErrNotPrivateKey is returned when private key material is requested
+	// from a public key
+	ErrNotPrivateKey = errors.New("Key is not a private key")
 )
 
 
-// Key represents a bip32 extended key
+// Key represents a bip32 extended key. Every Key returned by this package
+// owns its byte slices, so wiping one with Zero never affects its parent,
+// its children or the buffers it was created from.
 type Key struct {
-	Key         []byte // 33 bytes
-	Version     []byte // 4 bytes
-	ChildNumber []byte // 4 bytes
-	FingerPrint []byte // 4 bytes
-	ChainCode   []byte // 32 bytes
-	Depth       byte   // 1 bytes
-	IsPrivate   bool   // unserialized
+	Key         []byte         // 33 bytes
+	Version     []byte         // 4 bytes
+	ChildNumber []byte         // 4 bytes
+	FingerPrint []byte         // 4 bytes
+	ChainCode   []byte         // 32 bytes
+	Depth       byte           // 1 bytes
+	IsPrivate   bool           // unserialized
+	Network     *utils.Network // unserialized, nil means utils.MainNet
 }
 
-// NewMasterKey creates a new master extended key from a seed
+// NewMasterKey creates a new Bitcoin mainnet master extended key from a seed.
+// The seed isn't retained and may be wiped with ZeroSeed once this returns
 func NewMasterKey(seed []byte) (*Key, error) {
+	return NewMasterKeyForNetwork(seed, utils.MainNet)
+}
+
+// NewMasterKeyForNetwork creates a new master extended key from a seed, with
+// the version bytes and address parameters of net
+func NewMasterKeyForNetwork(seed []byte, net *utils.Network) (*Key, error) {
 	// Generate key and chaincode
 	hmac512 := hmac.New(sha512.New, []byte("Bitcoin seed"))
 	_, err := hmac512.Write(seed)
@@ -57,26 +79,29 @@ func NewMasterKey(seed []byte) (*Key, error) {
 		return nil, err
 	}
 	intermediary := hmac512.Sum(nil)
+	defer utils.Zero(intermediary)
 
 	// Split it into our key and chain code
-	keyBytes := intermediary[:32]
-	chainCode := intermediary[32:]
+	keyBytes := copyBytes(intermediary[:32])
+	chainCode := copyBytes(intermediary[32:])
 
 	// Validate key
 	err = utils.ValidatePrivateKey(keyBytes)
 	if err != nil {
+		utils.Zero(keyBytes)
 		return nil, err
 	}
 
 	// Create the key struct
 	key := &Key{
-		Version:     PrivateWalletVersion,
+		Version:     copyBytes(net.PrivateKeyVersion),
 		ChainCode:   chainCode,
 		Key:         keyBytes,
 		Depth:       0x0,
 		ChildNumber: []byte{0x00, 0x00, 0x00, 0x00},
 		FingerPrint: []byte{0x00, 0x00, 0x00, 0x00},
 		IsPrivate:   true,
+		Network:     net,
 	}
 
 	return key, nil
@@ -93,18 +118,20 @@ func (key *Key) NewChildKey(childIdx uint32) (*Key, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer utils.Zero(intermediary)
 
 	// Create child Key with data common to all both scenarios
 	childKey := &Key{
+		Version:     copyBytes(key.Version),
 		ChildNumber: utils.Uint32Bytes(childIdx),
-		ChainCode:   intermediary[32:],
+		ChainCode:   copyBytes(intermediary[32:]),
 		Depth:       key.Depth + 1,
 		IsPrivate:   key.IsPrivate,
+		Network:     key.Network,
 	}
 
 	// Bip32 CKDpriv
 	if key.IsPrivate {
-		childKey.Version = PrivateWalletVersion
 		fingerprint, err := utils.Hash160(utils.PublicKeyForPrivateKey(key.Key))
 		if err != nil {
 			return nil, err
@@ -127,53 +154,34 @@ func (key *Key) NewChildKey(childIdx uint32) (*Key, error) {
 			return nil, err
 		}
 
-		childKey.Version = PublicWalletVersion
 		fingerprint, err := utils.Hash160(key.Key)
 		if err != nil {
 			return nil, err
 		}
 		childKey.FingerPrint = fingerprint[:4]
 		childKey.Key = utils.AddPublicKeys(keyBytes, key.Key)
+		if childKey.Key == nil {
+			return nil, utils.ErrInvalidPublicKey
+		}
 	}
 
 	return childKey, nil
 }
 
 
+// DeriveChildKey derives the key at a path like "m/44'/0'/0'/0" from a master
+// key, or a relative path like "0/1" or "/1" from any key. See
+// ParseDerivationPath and ParseRelativeDerivationPath
 func (key *Key) DeriveChildKey(derivation string) (*Key, error) {
-	child := key
-	for _, n := range strings.Split(derivation, "/") {
-		if strings.Compare(n, " ") == 0 {
-			continue
-		}
-		if strings.Compare(n, "m") == 0 {
-			continue
-		}
-		if len(n) == 0 {
-			continue
-		}
-		var i uint32
-		if strings.HasSuffix(n, "'") {
-			num, err := strconv.Atoi(string(n[0:len(n)-1]))
-			if err != nil {
-				return nil, err
-			}
-			i = FirstHardenedChild + uint32(num)
-		} else {
-			num, err := strconv.Atoi(n)
-			if err != nil {
-				return nil, err
-			}
-			i = uint32(num)
-		}
-
-		new_child, err := child.NewChildKey(i)
-		child = new_child
-		if err != nil {
-			return nil, err
-		}
+	parse := ParseRelativeDerivationPath
+	if strings.HasPrefix(derivation, "m") {
+		parse = ParseDerivationPath
+	}
+	path, err := parse(derivation)
+	if err != nil {
+		return nil, err
 	}
-	return child, nil
+	return key.DerivePath(path)
 }
 
 func (key *Key) getIntermediary(childIdx uint32) ([]byte, error) {
@@ -182,17 +190,21 @@ func (key *Key) getIntermediary(childIdx uint32) ([]byte, error) {
 	// NonHardened children are based on the public key
 	childIndexBytes := utils.Uint32Bytes(childIdx)
 
-	var data []byte
+	// Sized for a private key so the appends don't reallocate and the
+	// deferred Zero wipes the buffer that was hashed
+	data := make([]byte, 0, 37)
 	if childIdx >= FirstHardenedChild {
-		data = append([]byte{0x0}, key.Key...)
+		data = append(data, 0x0)
+		data = append(data, key.Key...)
 	} else {
 		if key.IsPrivate {
-			data = utils.PublicKeyForPrivateKey(key.Key)
+			data = append(data, utils.PublicKeyForPrivateKey(key.Key)...)
 		} else {
-			data = key.Key
+			data = append(data, key.Key...)
 		}
 	}
 	data = append(data, childIndexBytes...)
+	defer utils.Zero(data)
 
 	hmac512 := hmac.New(sha512.New, key.ChainCode)
 	_, err := hmac512.Write(data)
@@ -206,23 +218,210 @@ func (key *Key) getIntermediary(childIdx uint32) ([]byte, error) {
 // The 'Neuter' function from the bip32 spec
 func (key *Key) PublicKey() *Key {
 	keyBytes := key.Key
+	version := key.Version
 
 	if key.IsPrivate {
 		keyBytes = utils.PublicKeyForPrivateKey(keyBytes)
+		version = key.network().PublicKeyVersion
+
+		// Keep the SLIP-132 script type, so a zprv neuters to a zpub
+		if scriptType, err := key.ScriptType(); err == nil {
+			if v, err := key.network().ExtendedKeyVersion(scriptType, false); err == nil {
+				version = v
+			}
+		}
 	}
 
 	return &Key{
-		Version:     PublicWalletVersion,
-		Key:         keyBytes,
+		Version:     copyBytes(version),
+		Key:         copyBytes(keyBytes),
 		Depth:       key.Depth,
-		ChildNumber: key.ChildNumber,
-		FingerPrint: key.FingerPrint,
-		ChainCode:   key.ChainCode,
+		ChildNumber: copyBytes(key.ChildNumber),
+		FingerPrint: copyBytes(key.FingerPrint),
+		ChainCode:   copyBytes(key.ChainCode),
 		IsPrivate:   false,
+		Network:     key.Network,
+	}
+}
+
+// Fingerprint returns the first 4 bytes of the hash160 of the key's public
+// key, the value its children store as FingerPrint
+func (key *Key) Fingerprint() ([]byte, error) {
+	hash160, err := utils.Hash160(key.PublicKey().Key)
+	if err != nil {
+		return nil, err
+	}
+	return hash160[:4], nil
+}
+
+// ScriptType returns the script type signalled by the key's SLIP-132 version
+// bytes, ScriptP2PKH for plain xprv/xpub keys
+func (key *Key) ScriptType() (utils.ScriptType, error) {
+	_, scriptType, _, err := utils.ParseExtendedKeyVersion(key.Version)
+	return scriptType, err
+}
+
+// WithScriptType returns a copy of the key carrying the SLIP-132 version
+// bytes of scriptType, e.g. to turn an xpub into the zpub of the same key
+func (key *Key) WithScriptType(scriptType utils.ScriptType) (*Key, error) {
+	version, err := key.network().ExtendedKeyVersion(scriptType, key.IsPrivate)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		Version:     copyBytes(version),
+		Key:         copyBytes(key.Key),
+		Depth:       key.Depth,
+		ChildNumber: copyBytes(key.ChildNumber),
+		FingerPrint: copyBytes(key.FingerPrint),
+		ChainCode:   copyBytes(key.ChainCode),
+		IsPrivate:   key.IsPrivate,
+		Network:     key.Network,
+	}, nil
+}
+
+// ECPrivKey returns the key as a typed private key
+func (key *Key) ECPrivKey() (*crypto.PrivateKey, error) {
+	if !key.IsPrivate {
+		return nil, ErrNotPrivateKey
+	}
+	return crypto.PrivateKeyFromBytes(key.Key)
+}
+
+// ECPubKey returns the key's typed public key
+func (key *Key) ECPubKey() (*crypto.PublicKey, error) {
+	return crypto.ParsePublicKey(key.PublicKey().Key)
+}
+
+// Sign signs a 32 bytes hash with the private key using deterministic ECDSA
+func (key *Key) Sign(hash []byte) (*crypto.Signature, error) {
+	if !key.IsPrivate {
+		return nil, ErrSignWithPublicKey
+	}
+	return crypto.Sign(key.Key, hash)
+}
+
+// SignRecoverable signs a 32 bytes hash and returns a 65 bytes R || S || V
+// signature the public key can be recovered from
+func (key *Key) SignRecoverable(hash []byte) ([]byte, error) {
+	if !key.IsPrivate {
+		return nil, ErrSignWithPublicKey
+	}
+	return crypto.SignRecoverable(key.Key, hash)
+}
+
+// Verify checks an ECDSA signature of hash against the key's public key
+func (key *Key) Verify(hash []byte, sig *crypto.Signature) bool {
+	return utils.VerifySignature(key.PublicKey().Key, hash, sig)
+}
+
+// WIF encodes the private key in the wallet import format of the key's network
+func (key *Key) WIF() (string, error) {
+	if !key.IsPrivate {
+		return "", ErrNotPrivateKey
+	}
+	return utils.WIFEncode(key.network().WIFPrefix, key.Key, true)
+}
+
+// P2PKHAddress returns the legacy base58check address of the key's public key
+func (key *Key) P2PKHAddress() (string, error) {
+	return utils.PublicKeyToP2PKHAddress(key.PublicKey().Key, key.network().P2PKHPrefix)
+}
+
+// P2WPKHAddress returns the native SegWit address of the key's public key
+func (key *Key) P2WPKHAddress() (string, error) {
+	net := key.network()
+	if !net.HasSegWit() {
+		return "", utils.ErrSegWitNotSupported
+	}
+	return utils.PublicKeyToP2WPKHAddress(key.PublicKey().Key, net.Bech32HRP)
+}
+
+// P2WPKHRedeemScript returns the redeem script that nests the key's P2WPKH
+// output in P2SH
+func (key *Key) P2WPKHRedeemScript() ([]byte, error) {
+	return utils.P2WPKHRedeemScript(key.PublicKey().Key)
+}
+
+// P2SHP2WPKHAddress returns the nested SegWit address of the key, a
+// 3-address on Bitcoin mainnet
+func (key *Key) P2SHP2WPKHAddress() (string, error) {
+	net := key.network()
+	if !net.HasSegWit() {
+		return "", utils.ErrSegWitNotSupported
+	}
+	return utils.PublicKeyToP2SHP2WPKHAddress(key.PublicKey().Key, net.P2SHPrefix)
+}
+
+// P2TRAddress returns the taproot address of the key's x-only public key.
+// merkleRoot commits to a script tree and may be nil for key path only
+// outputs as in BIP86
+func (key *Key) P2TRAddress(merkleRoot []byte) (string, error) {
+	net := key.network()
+	if !net.HasSegWit() {
+		return "", utils.ErrSegWitNotSupported
+	}
+	return utils.PublicKeyToP2TRAddress(key.XOnlyPublicKey(), merkleRoot, net.Bech32HRP)
+}
+
+// TaprootOutputKey returns the 32 bytes x-only BIP341 output key and the
+// parity of its y coordinate
+func (key *Key) TaprootOutputKey(merkleRoot []byte) ([]byte, byte, error) {
+	return crypto.TweakTaprootPublicKey(key.XOnlyPublicKey(), merkleRoot)
+}
+
+// TaprootPrivateKey returns the tweaked private key that signs key path
+// spends of the key's taproot output. The caller should wipe it once done
+func (key *Key) TaprootPrivateKey(merkleRoot []byte) ([]byte, error) {
+	if !key.IsPrivate {
+		return nil, ErrNotPrivateKey
+	}
+	return crypto.TweakTaprootPrivateKey(key.Key, merkleRoot)
+}
+
+// SignTaprootKeyPath produces the BIP340 Schnorr signature of a BIP341
+// signature hash with the tweaked key of the key's taproot output. Script
+// path spends sign with SignSchnorr instead
+func (key *Key) SignTaprootKeyPath(sigHash []byte, merkleRoot []byte, auxRand []byte) ([]byte, error) {
+	if !key.IsPrivate {
+		return nil, ErrSignWithPublicKey
+	}
+	tweaked, err := crypto.TweakTaprootPrivateKey(key.Key, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.Zero(tweaked)
+	return crypto.SchnorrSign(tweaked, sigHash, auxRand)
+}
+
+// XOnlyPublicKey returns the 32 bytes BIP340 x-only public key
+func (key *Key) XOnlyPublicKey() []byte {
+	return key.PublicKey().Key[1:]
+}
+
+// SignSchnorr produces a BIP340 Schnorr signature of msg. auxRand should be
+// 32 bytes of fresh randomness
+func (key *Key) SignSchnorr(msg []byte, auxRand []byte) ([]byte, error) {
+	if !key.IsPrivate {
+		return nil, ErrSignWithPublicKey
 	}
+	return crypto.SchnorrSign(key.Key, msg, auxRand)
+}
+
+// VerifySchnorr checks a BIP340 Schnorr signature of msg against the key's
+// x-only public key
+func (key *Key) VerifySchnorr(msg []byte, sig []byte) bool {
+	return crypto.SchnorrVerify(key.XOnlyPublicKey(), msg, sig)
+}
+
+// Zero wipes the key material and chain code. The key is unusable afterwards
+func (key *Key) Zero() {
+	utils.Zero(key.Key)
+	utils.Zero(key.ChainCode)
 }
 
-// Serialize a Key to a 78 byte byte slice
+// Serialize a Key to a 78 byte byte slice. The result holds the private key
+// of private keys and should be wiped by the caller
 func (key *Key) Serialize() ([]byte, error) {
 	// Private keys should be prepended with a single null byte
 	keyBytes := key.Key
@@ -248,12 +447,14 @@ func (key *Key) Serialize() ([]byte, error) {
 	return serializedKey, nil
 }
 
-// B58Serialize encodes the Key in the standard Bitcoin base58 encoding
+// B58Serialize encodes the Key in the standard Bitcoin base58 encoding. Use
+// ExportKeys when exporting several related keys
 func (key *Key) B58Serialize() string {
 	serializedKey, err := key.Serialize()
 	if err != nil {
 		return ""
 	}
+	defer utils.Zero(serializedKey)
 
 	return utils.Base58.EncodeToString(serializedKey)
 }
@@ -266,7 +467,7 @@ func (key *Key) String() string {
 	}
 
 	if key.IsPrivate {
-		s, _ := utils.WIFEncode(WIFPrefix, key.Key, true)
+		s, _ := key.WIF()
 		return s
 	} else {
 		return hex.EncodeToString(key.Key)
@@ -274,40 +475,119 @@ func (key *Key) String() string {
 }
 
 
-// Deserialize a byte slice into a Key
+// Deserialize a byte slice into a Key. The Key copies what it needs, so
+// data may be wiped once this returns. The network is guessed from the
+// version bytes, which signet and regtest share with testnet; use
+// DeserializeNetwork when the network is known. Unknown version bytes are
+// rejected
 func Deserialize(data []byte) (*Key, error) {
 	if len(data) != 82 {
 		return nil, ErrSerializedKeyWrongSize
 	}
+	// validate checksum
+	_, err := utils.ValidateChecksum(data)
+	if err != nil {
+		return nil, err
+	}
+
+	net, _, _, err := utils.ParseExtendedKeyVersion(data[0:4])
+	if err != nil {
+		return nil, err
+	}
+
 	var key = &Key{}
-	key.Version = data[0:4]
+	key.Version = copyBytes(data[0:4])
+	key.Network = net
 	key.Depth = data[4]
-	key.FingerPrint = data[5:9]
-	key.ChildNumber = data[9:13]
-	key.ChainCode = data[13:45]
+	key.FingerPrint = copyBytes(data[5:9])
+	key.ChildNumber = copyBytes(data[9:13])
+	key.ChainCode = copyBytes(data[13:45])
 
 	if data[45] == byte(0) {
 		key.IsPrivate = true
-		key.Key = data[46:78]
+		key.Key = copyBytes(data[46:78])
 	} else {
 		key.IsPrivate = false
-		key.Key = data[45:78]
+		key.Key = copyBytes(data[45:78])
 	}
 
-	// validate checksum
-	_, err := utils.ValidateChecksum(data)
+	return key, nil
+}
+
+// B58Deserialize deserializes a Key encoded in base58 encoding
+func B58Deserialize(data string) (*Key, error) {
+	b, err := utils.Base58.DecodeString(data)
 	if err != nil {
 		return nil, err
 	}
+	defer utils.Zero(b)
+	return Deserialize(b)
+}
 
+// DeserializeNetwork deserializes a Key of net, failing when its version
+// bytes belong to another network
+func DeserializeNetwork(data []byte, net *utils.Network) (*Key, error) {
+	key, err := Deserialize(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := net.ParseExtendedKeyVersion(key.Version); err != nil {
+		key.Zero()
+		return nil, err
+	}
+	key.Network = net
 	return key, nil
 }
 
-// B58Deserialize deserializes a Key encoded in base58 encoding
-func B58Deserialize(data string) (*Key, error) {
+// B58DeserializeNetwork deserializes a base58 encoded Key of net
+func B58DeserializeNetwork(data string, net *utils.Network) (*Key, error) {
 	b, err := utils.Base58.DecodeString(data)
 	if err != nil {
 		return nil, err
 	}
-	return Deserialize(b)
+	defer utils.Zero(b)
+	return DeserializeNetwork(b, net)
+}
+
+// network returns the key's network, defaulting to Bitcoin mainnet
+func (key *Key) network() *utils.Network {
+	if key.Network == nil {
+		return utils.MainNet
+	}
+	return key.Network
+}
+
+// ConvertExtendedKey re-encodes a base58 extended key with the SLIP-132
+// version bytes of scriptType, e.g. from xpub to ypub or zpub
+func ConvertExtendedKey(data string, scriptType utils.ScriptType) (string, error) {
+	key, err := B58Deserialize(data)
+	if err != nil {
+		return "", err
+	}
+	defer key.Zero()
+
+	converted, err := key.WithScriptType(scriptType)
+	if err != nil {
+		return "", err
+	}
+	defer converted.Zero()
+	return converted.B58Serialize(), nil
+}
+
+// copy returns a Key that doesn't share any buffer with key
+func (key *Key) copy() *Key {
+	return &Key{
+		Version:     copyBytes(key.Version),
+		Key:         copyBytes(key.Key),
+		Depth:       key.Depth,
+		ChildNumber: copyBytes(key.ChildNumber),
+		FingerPrint: copyBytes(key.FingerPrint),
+		ChainCode:   copyBytes(key.ChainCode),
+		IsPrivate:   key.IsPrivate,
+		Network:     key.Network,
+	}
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
 }
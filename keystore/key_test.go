@@ -0,0 +1,327 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+// bip86Master is the root key of the BIP86 test vectors, derived from the
+// "abandon ... about" mnemonic
+const bip86Master = "xprv9s21ZrQH143K3GJpoapnV8SFfukcVBSfeCficPSGfubmSFDxo1kuHnLisriDvSnRRuL2Qrg5ggqHKNVpxR86QEC8w35uxmGoggxtQTPvfUu"
+
+func TestBIP86Vectors(t *testing.T) {
+	master, err := B58Deserialize(bip86Master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mnemonicMaster, err := FromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mnemonicMaster.B58Serialize() != bip86Master {
+		t.Errorf("mnemonic master key %s, want %s", mnemonicMaster.B58Serialize(), bip86Master)
+	}
+
+	tests := []struct {
+		path        string
+		internalKey string
+		outputKey   string
+		address     string
+	}{
+		{
+			"m/86'/0'/0'/0/0",
+			"cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115",
+			"a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c",
+			"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr",
+		},
+		{
+			"m/86'/0'/0'/0/1",
+			"83dfe85a3151d2517290da461fe2815591ef69f2b18a2ce63f01697a8b313145",
+			"a82f29944d65b86ae6b5e5cc75e294ead6c59391a1edc5e016e3498c67fc7bbb",
+			"bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh",
+		},
+		{
+			"m/86'/0'/0'/1/0",
+			"399f1b2f4393f29a18c937859c5dd8a77350103157eb880f02e8c08214277cef",
+			"882d74e5d0572d5a816cef0041a96b6c1de832f6f9676d9605c44d5e9a97d3dc",
+			"bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7",
+		},
+	}
+	for _, test := range tests {
+		key, err := master.DeriveChildKey(test.path)
+		if err != nil {
+			t.Fatalf("%s: %v", test.path, err)
+		}
+		if internalKey := hex.EncodeToString(key.XOnlyPublicKey()); internalKey != test.internalKey {
+			t.Errorf("%s: internal key %s, want %s", test.path, internalKey, test.internalKey)
+		}
+		outputKey, _, err := key.TaprootOutputKey(nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.path, err)
+		}
+		if hex.EncodeToString(outputKey) != test.outputKey {
+			t.Errorf("%s: output key %x, want %s", test.path, outputKey, test.outputKey)
+		}
+		address, err := key.P2TRAddress(nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.path, err)
+		}
+		if address != test.address {
+			t.Errorf("%s: address %s, want %s", test.path, address, test.address)
+		}
+	}
+
+	account, err := NewAccount(master, PurposeBIP86, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const accountXPub = "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ"
+	if xpub := account.ExtendedPublicKey(); xpub != accountXPub {
+		t.Errorf("account xpub %s, want %s", xpub, accountXPub)
+	}
+	for i, want := range []string{tests[0].address, tests[1].address} {
+		address, err := account.ExternalAddress(uint32(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != want {
+			t.Errorf("external address %d: %s, want %s", i, address, want)
+		}
+	}
+	change, err := account.InternalAddress(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change != tests[2].address {
+		t.Errorf("internal address 0: %s, want %s", change, tests[2].address)
+	}
+}
+
+func TestDeserializeNetwork(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	for _, net := range utils.Networks {
+		master, err := NewMasterKeyForNetwork(seed, net)
+		if err != nil {
+			t.Fatalf("%s: %v", net.Name, err)
+		}
+		child, err := master.DeriveChildKey("m/0'/1")
+		if err != nil {
+			t.Fatalf("%s: %v", net.Name, err)
+		}
+		for _, key := range []*Key{master, child, child.PublicKey()} {
+			s := key.B58Serialize()
+			decoded, err := B58DeserializeNetwork(s, net)
+			if err != nil {
+				t.Fatalf("%s: %v", net.Name, err)
+			}
+			if decoded.Network != net {
+				t.Errorf("%s: network %s", net.Name, decoded.Network.Name)
+			}
+			if decoded.B58Serialize() != s {
+				t.Errorf("%s: re-serialized as %s, want %s", net.Name, decoded.B58Serialize(), s)
+			}
+
+			// shared versions resolve to the first network listed
+			guessed, err := B58Deserialize(s)
+			if err != nil {
+				t.Fatalf("%s: %v", net.Name, err)
+			}
+			want := net
+			if net == utils.SigNet || net == utils.RegTest {
+				want = utils.TestNet
+			}
+			if guessed.Network != want {
+				t.Errorf("%s: guessed network %s, want %s", net.Name, guessed.Network.Name, want.Name)
+			}
+		}
+	}
+
+	master, err := NewMasterKeyForNetwork(seed, utils.LitecoinMainNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := B58DeserializeNetwork(master.B58Serialize(), utils.MainNet); err != utils.ErrExtendedKeyNetworkMismatch {
+		t.Errorf("litecoin key as mainnet: got %v, want %v", err, utils.ErrExtendedKeyNetworkMismatch)
+	}
+
+	unknown := &Key{Version: []byte{0x01, 0x02, 0x03, 0x04}, FingerPrint: master.FingerPrint, ChildNumber: master.ChildNumber,
+		ChainCode: master.ChainCode, Key: master.Key, IsPrivate: true}
+	data, err := unknown.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Deserialize(data); err != utils.ErrUnknownNetwork {
+		t.Errorf("unknown version: got %v, want %v", err, utils.ErrUnknownNetwork)
+	}
+}
+
+func TestConvertExtendedKey(t *testing.T) {
+	master, err := B58Deserialize(bip86Master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := master.DeriveChildKey("m/84'/0'/0'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zprv, err := account.WithScriptType(utils.ScriptP2WPKH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// BIP84 account keys
+	const (
+		wantZprv = "zprvAdG4iTXWBoARxkkzNpNh8r6Qag3irQB8PzEMkAFeTRXxHpbF9z4QgEvBRmfvqWvGp42t42nvgGpNgYSJA9iefm1yYNZKEm7z6qUWCroSQnE"
+		wantZpub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	)
+	if s := zprv.B58Serialize(); s != wantZprv {
+		t.Errorf("zprv %s, want %s", s, wantZprv)
+	}
+	if s := zprv.PublicKey().B58Serialize(); s != wantZpub {
+		t.Errorf("zpub %s, want %s", s, wantZpub)
+	}
+	xpub, err := ConvertExtendedKey(wantZpub, utils.ScriptP2PKH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xpub != account.PublicKey().B58Serialize() {
+		t.Errorf("xpub %s, want %s", xpub, account.PublicKey().B58Serialize())
+	}
+
+	tests := []struct {
+		net      *utils.Network
+		prefixes map[utils.ScriptType][2]string
+	}{
+		{utils.MainNet, map[utils.ScriptType][2]string{
+			utils.ScriptP2PKH:      {"xprv", "xpub"},
+			utils.ScriptP2SHP2WPKH: {"yprv", "ypub"},
+			utils.ScriptP2WPKH:     {"zprv", "zpub"},
+			utils.ScriptP2SHP2WSH:  {"Yprv", "Ypub"},
+			utils.ScriptP2WSH:      {"Zprv", "Zpub"},
+		}},
+		{utils.TestNet, map[utils.ScriptType][2]string{
+			utils.ScriptP2PKH:      {"tprv", "tpub"},
+			utils.ScriptP2SHP2WPKH: {"uprv", "upub"},
+			utils.ScriptP2WPKH:     {"vprv", "vpub"},
+			utils.ScriptP2SHP2WSH:  {"Uprv", "Upub"},
+			utils.ScriptP2WSH:      {"Vprv", "Vpub"},
+		}},
+	}
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	for _, test := range tests {
+		master, err := NewMasterKeyForNetwork(seed, test.net)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := master.DeriveChildKey("m/84'/1'/0'")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range []*Key{key, key.PublicKey()} {
+			i := 1
+			if k.IsPrivate {
+				i = 0
+			}
+			original := k.B58Serialize()
+			s := original
+			for _, scriptType := range []utils.ScriptType{utils.ScriptP2SHP2WPKH, utils.ScriptP2WPKH, utils.ScriptP2SHP2WSH, utils.ScriptP2WSH, utils.ScriptP2PKH} {
+				s, err = ConvertExtendedKey(s, scriptType)
+				if err != nil {
+					t.Fatalf("%s %d: %v", test.net.Name, scriptType, err)
+				}
+				if prefix := test.prefixes[scriptType][i]; s[:4] != prefix {
+					t.Errorf("%s %d: converted to %s, want prefix %s", test.net.Name, scriptType, s, prefix)
+				}
+				decoded, err := B58Deserialize(s)
+				if err != nil {
+					t.Fatalf("%s %d: %v", test.net.Name, scriptType, err)
+				}
+				if got, err := decoded.ScriptType(); err != nil || got != scriptType {
+					t.Errorf("%s %s: script type %d, %v, want %d", test.net.Name, s, got, err, scriptType)
+				}
+				if decoded.Network != test.net || decoded.IsPrivate != k.IsPrivate {
+					t.Errorf("%s %s: network %s, private %v", test.net.Name, s, decoded.Network.Name, decoded.IsPrivate)
+				}
+			}
+			if s != original {
+				t.Errorf("%s: round trip gave %s, want %s", test.net.Name, s, original)
+			}
+		}
+	}
+
+	doge, err := NewMasterKeyForNetwork(seed, utils.DogecoinMainNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doge.WithScriptType(utils.ScriptP2WPKH); err != utils.ErrUnsupportedScriptType {
+		t.Errorf("dogecoin zpub: got %v, want %v", err, utils.ErrUnsupportedScriptType)
+	}
+}
+
+func TestKeyZero(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xprv := master.B58Serialize()
+
+	// Keys own their buffers, so wiping the inputs doesn't change them
+	ZeroSeed(seed)
+	if !bytes.Equal(seed, make([]byte, 16)) {
+		t.Error("ZeroSeed should wipe the seed")
+	}
+	if master.B58Serialize() != xprv {
+		t.Error("wiping the seed changed the master key")
+	}
+	serialized, err := master.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Deserialize(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utils.Zero(serialized)
+	if decoded.B58Serialize() != xprv || master.B58Serialize() != xprv {
+		t.Error("wiping the serialization changed a key")
+	}
+
+	child, err := master.NewChildKey(FirstHardenedChild)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpub := child.PublicKey().B58Serialize()
+	public := child.PublicKey()
+	child.Zero()
+	if !bytes.Equal(child.Key, make([]byte, len(child.Key))) || !bytes.Equal(child.ChainCode, make([]byte, len(child.ChainCode))) {
+		t.Error("Zero should wipe the key and chain code")
+	}
+	if master.B58Serialize() != xprv {
+		t.Error("wiping the child changed its parent")
+	}
+	if public.B58Serialize() != tpub {
+		t.Error("wiping the child changed its public key")
+	}
+
+	child, err = master.NewChildKey(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xprvChild := child.B58Serialize()
+	master.Zero()
+	if child.B58Serialize() != xprvChild {
+		t.Error("wiping the parent changed its child")
+	}
+	if decoded.B58Serialize() != xprv {
+		t.Error("wiping the master changed a deserialized copy")
+	}
+
+	mnemonic := []byte("abandon abandon about")
+	ZeroMnemonic(mnemonic)
+	if !bytes.Equal(mnemonic, make([]byte, len(mnemonic))) {
+		t.Error("ZeroMnemonic should wipe the mnemonic")
+	}
+}
@@ -0,0 +1,141 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/crypto"
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+var (
+	// ErrNotChildKey is returned when a key isn't a direct child of the
+	// given parent
+	ErrNotChildKey = errors.New("Key is not a child of the parent key")
+
+	// ErrHardenedChildRecovery is returned when recovering a parent key from
+	// a hardened child, which doesn't leak its parent
+	ErrHardenedChildRecovery = errors.New("Can't recover a parent key from a hardened child")
+
+	// ErrUnsafeExport is returned when an export holds a non-hardened child
+	// private key together with its parent's public key, which together
+	// reveal the parent private key
+	ErrUnsafeExport = errors.New("Exporting a non-hardened child private key with its parent public key leaks the parent private key")
+)
+
+// RecoverParentPrivateKey returns the parent private key given the parent
+// extended public key and the private key of one of its non-hardened
+// children, as k_parent = k_child - IL (mod N). This is the known bip32
+// weakness, exposed for incident response and auditing.
+func RecoverParentPrivateKey(parent *Key, child *Key) (*Key, error) {
+	if !child.IsPrivate {
+		return nil, ErrNotPrivateKey
+	}
+	parentPublic := parent.PublicKey()
+	if !isChildOf(child, parentPublic) {
+		return nil, ErrNotChildKey
+	}
+	childIdx := binary.BigEndian.Uint32(child.ChildNumber)
+	if childIdx >= FirstHardenedChild {
+		return nil, ErrHardenedChildRecovery
+	}
+
+	intermediary, err := parentPublic.getIntermediary(childIdx)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.Zero(intermediary)
+	if !bytes.Equal(intermediary[32:], child.ChainCode) {
+		return nil, ErrNotChildKey
+	}
+
+	// k_parent = k_child + (N - IL)
+	il, err := crypto.PrivateKeyFromBytes(intermediary[:32])
+	if err != nil {
+		return nil, err
+	}
+	defer il.Zero()
+	negIL := il.Negate()
+	defer negIL.Zero()
+	tweak := negIL.Serialize()
+	defer utils.Zero(tweak)
+
+	childPriv, err := child.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	defer childPriv.Zero()
+	parentPriv, err := childPriv.TweakAdd(tweak)
+	if err != nil {
+		return nil, err
+	}
+	defer parentPriv.Zero()
+
+	if !bytes.Equal(parentPriv.PublicKey().SerializeCompressed(), parentPublic.Key) {
+		return nil, ErrNotChildKey
+	}
+
+	// Give the recovered key the private version matching the parent's
+	version := parentPublic.network().PrivateKeyVersion
+	if scriptType, err := parentPublic.ScriptType(); err == nil {
+		if v, err := parentPublic.network().ExtendedKeyVersion(scriptType, true); err == nil {
+			version = v
+		}
+	}
+
+	return &Key{
+		Version:     copyBytes(version),
+		Key:         parentPriv.Serialize(),
+		Depth:       parentPublic.Depth,
+		ChildNumber: copyBytes(parentPublic.ChildNumber),
+		FingerPrint: copyBytes(parentPublic.FingerPrint),
+		ChainCode:   copyBytes(parentPublic.ChainCode),
+		IsPrivate:   true,
+		Network:     parentPublic.Network,
+	}, nil
+}
+
+// CheckExportSafety returns ErrUnsafeExport if keys holds the private key of
+// a non-hardened child together with the public key of its parent. Serialize
+// and B58Serialize work on a single key and can't check this, so callers
+// exporting or sharing several keys must call it or use ExportKeys
+func CheckExportSafety(keys ...*Key) error {
+	for _, child := range keys {
+		if !child.IsPrivate || binary.BigEndian.Uint32(child.ChildNumber) >= FirstHardenedChild {
+			continue
+		}
+		for _, parent := range keys {
+			if !parent.IsPrivate && isChildOf(child, parent) {
+				return ErrUnsafeExport
+			}
+		}
+	}
+	return nil
+}
+
+// ExportKeys base58 serializes keys for export, refusing sets that
+// CheckExportSafety reports as unsafe
+func ExportKeys(keys ...*Key) ([]string, error) {
+	if err := CheckExportSafety(keys...); err != nil {
+		return nil, err
+	}
+	exported := make([]string, len(keys))
+	for i, key := range keys {
+		exported[i] = key.B58Serialize()
+	}
+	return exported, nil
+}
+
+// isChildOf reports whether child's depth and parent fingerprint point to
+// parent
+func isChildOf(child *Key, parent *Key) bool {
+	if child.Depth != parent.Depth+1 {
+		return false
+	}
+	fingerprint, err := parent.Fingerprint()
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(child.FingerPrint, fingerprint)
+}
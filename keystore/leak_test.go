@@ -0,0 +1,87 @@
+package keystore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+func TestRecoverParentPrivateKey(t *testing.T) {
+	account, err := testKey(t, "m/84'/0'/0'").WithScriptType(utils.ScriptP2WPKH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xpub := account.PublicKey()
+
+	for _, index := range []uint32{0, 5, FirstHardenedChild - 1} {
+		child, err := account.NewChildKey(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recovered, err := RecoverParentPrivateKey(xpub, child)
+		if err != nil {
+			t.Fatalf("child %d: %v", index, err)
+		}
+		if !bytes.Equal(recovered.Key, account.Key) {
+			t.Errorf("child %d: recovered %x, want %x", index, recovered.Key, account.Key)
+		}
+		if recovered.B58Serialize() != account.B58Serialize() {
+			t.Errorf("child %d: recovered %s, want %s", index, recovered.B58Serialize(), account.B58Serialize())
+		}
+	}
+
+	for _, index := range []uint32{FirstHardenedChild, FirstHardenedChild + 5} {
+		hardened, err := account.NewChildKey(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RecoverParentPrivateKey(xpub, hardened); err != ErrHardenedChildRecovery {
+			t.Errorf("hardened child %d: got %v, want %v", index-FirstHardenedChild, err, ErrHardenedChildRecovery)
+		}
+	}
+
+	child, err := account.NewChildKey(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecoverParentPrivateKey(xpub, child.PublicKey()); err != ErrNotPrivateKey {
+		t.Errorf("public child: got %v, want %v", err, ErrNotPrivateKey)
+	}
+	other := testKey(t, "m/84'/0'/1'/5")
+	if _, err := RecoverParentPrivateKey(xpub, other); err != ErrNotChildKey {
+		t.Errorf("unrelated key: got %v, want %v", err, ErrNotChildKey)
+	}
+}
+
+func TestExportKeys(t *testing.T) {
+	account := testKey(t, "m/84'/0'/0'")
+	xpub := account.PublicKey()
+	child, err := account.NewChildKey(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hardened, err := account.NewChildKey(FirstHardenedChild)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ExportKeys(xpub, child); err != ErrUnsafeExport {
+		t.Errorf("xpub with child private key: got %v, want %v", err, ErrUnsafeExport)
+	}
+	if _, err := ExportKeys(child, xpub); err != ErrUnsafeExport {
+		t.Errorf("child private key with xpub: got %v, want %v", err, ErrUnsafeExport)
+	}
+	for _, keys := range [][]*Key{{xpub, hardened}, {xpub, child.PublicKey()}, {account, child}} {
+		exported, err := ExportKeys(keys...)
+		if err != nil {
+			t.Errorf("safe export: %v", err)
+			continue
+		}
+		for i, key := range keys {
+			if exported[i] != key.B58Serialize() {
+				t.Errorf("exported %s, want %s", exported[i], key.B58Serialize())
+			}
+		}
+	}
+}
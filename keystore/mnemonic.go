@@ -12,6 +12,7 @@ import (
 	"crypto/sha256"
 	"crypto/rand"
 	"errors"
+	"github.com/icodeface/go-blockchain-kit/utils"
 )
 
 // Some bitwise operands for working with big.Ints
@@ -24,11 +25,32 @@ var (
 
 func FromMnemonic(mnemonic, passphrase string) (*Key, error){
 	seed := NewSeed(mnemonic, passphrase)
+	defer ZeroSeed(seed)
 	return NewMasterKey(seed);
 }
 
+// FromMnemonicBytes is like FromMnemonic but takes the mnemonic and
+// passphrase as byte slices, which unlike strings can be wiped with
+// ZeroMnemonic afterwards. Neither slice is retained.
+func FromMnemonicBytes(mnemonic, passphrase []byte) (*Key, error) {
+	seed := NewSeedFromBytes(mnemonic, passphrase)
+	defer ZeroSeed(seed)
+	return NewMasterKey(seed)
+}
+
+// ZeroSeed wipes a seed or entropy buffer, e.g. from NewSeed or NewEntropy.
+func ZeroSeed(seed []byte) {
+	utils.Zero(seed)
+}
+
+// ZeroMnemonic wipes a mnemonic held in a byte slice.
+func ZeroMnemonic(mnemonic []byte) {
+	utils.Zero(mnemonic)
+}
+
 // NewEntropy will create random entropy bytes
 // so long as the requested size bitSize is an appropriate size.
+// The caller owns the result and should wipe it with ZeroSeed.
 func NewEntropy(bitSize int) ([]byte, error) {
 	err := validateEntropyBitSize(bitSize)
 	if err != nil {
@@ -169,10 +191,20 @@ func NewSeedWithErrorChecking(mnemonic string, password string) ([]byte, error)
 
 // NewSeed creates a hashed seed output given a provided string and password.
 // No checking is performed to validate that the string provided is a valid mnemonic.
+// The caller owns the seed and should wipe it with ZeroSeed; the strings
+// themselves can't be wiped, see NewSeedFromBytes.
 func NewSeed(mnemonic string, password string) []byte {
 	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+password), 2048, 64, sha512.New)
 }
 
+// NewSeedFromBytes is like NewSeed for a mnemonic and password held in byte
+// slices. Neither slice is retained.
+func NewSeedFromBytes(mnemonic []byte, password []byte) []byte {
+	salt := append([]byte("mnemonic"), password...)
+	defer utils.Zero(salt)
+	return pbkdf2.Key(mnemonic, salt, 2048, 64, sha512.New)
+}
+
 // Appends to data the first (len(data) / 32)bits of the result of sha256(data)
 // Currently only supports data up to 32 bytes
 func addChecksum(data []byte) []byte {
@@ -0,0 +1,153 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrEmptyDerivationPath is returned when parsing an empty path
+	ErrEmptyDerivationPath = errors.New("Derivation path is empty")
+
+	// ErrInvalidPathSegment is returned when a path segment is empty, isn't
+	// a decimal number or has an unknown hardening marker
+	ErrInvalidPathSegment = errors.New("Invalid derivation path segment")
+
+	// ErrPathIndexOutOfRange is returned when a path index isn't below 2^31
+	ErrPathIndexOutOfRange = errors.New("Derivation path index out of range")
+
+	// ErrMissingMaster is returned when an absolute path doesn't start with "m"
+	ErrMissingMaster = errors.New("Derivation path must start with \"m\"")
+
+	// ErrMisplacedMaster is returned when "m" appears after the first segment
+	ErrMisplacedMaster = errors.New("\"m\" is only allowed at the start of a derivation path")
+
+	// ErrAbsolutePathFromChild is returned when deriving an absolute path
+	// from a key that isn't a master key
+	ErrAbsolutePathFromChild = errors.New("Absolute derivation path from a non master key")
+)
+
+// DerivationPath is a parsed bip32 path. Absolute paths start with "m" and
+// apply to master keys, relative ones apply to any key at its current depth.
+type DerivationPath struct {
+	Absolute bool
+	Indexes  []uint32
+}
+
+// ParseDerivationPath parses absolute paths like "m/84'/0'/0'/0/1". Hardened
+// indexes are marked with ', h or H.
+func ParseDerivationPath(path string) (*DerivationPath, error) {
+	if len(path) == 0 {
+		return nil, ErrEmptyDerivationPath
+	}
+	segments := strings.Split(path, "/")
+	if segments[0] != "m" {
+		return nil, ErrMissingMaster
+	}
+	indexes, err := parsePathSegments(segments[1:])
+	if err != nil {
+		return nil, err
+	}
+	return &DerivationPath{Absolute: true, Indexes: indexes}, nil
+}
+
+// ParseRelativeDerivationPath parses paths like "0/1" relative to a key's
+// current depth. A leading "/" is allowed, so "/1" is the same as "1".
+func ParseRelativeDerivationPath(path string) (*DerivationPath, error) {
+	path = strings.TrimPrefix(path, "/")
+	if len(path) == 0 {
+		return nil, ErrEmptyDerivationPath
+	}
+	indexes, err := parsePathSegments(strings.Split(path, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return &DerivationPath{Indexes: indexes}, nil
+}
+
+// String formats the path with ' as hardening marker.
+func (path *DerivationPath) String() string {
+	segments := make([]string, 0, len(path.Indexes)+1)
+	if path.Absolute {
+		segments = append(segments, "m")
+	}
+	for _, index := range path.Indexes {
+		if index >= FirstHardenedChild {
+			segments = append(segments, strconv.FormatUint(uint64(index-FirstHardenedChild), 10)+"'")
+		} else {
+			segments = append(segments, strconv.FormatUint(uint64(index), 10))
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// DerivePath derives the key at path. Absolute paths are only allowed from
+// master keys. The result never shares buffers with key, even for an empty
+// path, so either may be wiped independently
+func (key *Key) DerivePath(path *DerivationPath) (*Key, error) {
+	if path.Absolute && key.Depth != 0 {
+		return nil, ErrAbsolutePathFromChild
+	}
+	if len(path.Indexes) == 0 {
+		return key.copy(), nil
+	}
+
+	child := key
+	for _, index := range path.Indexes {
+		next, err := child.NewChildKey(index)
+		if child != key {
+			child.Zero()
+		}
+		if err != nil {
+			return nil, err
+		}
+		child = next
+	}
+	return child, nil
+}
+
+func parsePathSegments(segments []string) ([]uint32, error) {
+	indexes := make([]uint32, 0, len(segments))
+	for i, segment := range segments {
+		if segment == "m" {
+			return nil, fmt.Errorf("%w: segment %d", ErrMisplacedMaster, i)
+		}
+		index, err := parsePathIndex(segment)
+		if err != nil {
+			return nil, fmt.Errorf("%w: segment %d %q", err, i, segment)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// parsePathIndex parses a decimal index below 2^31 with an optional
+// hardening marker.
+func parsePathIndex(segment string) (uint32, error) {
+	var hardened uint32
+	if n := len(segment); n > 0 {
+		switch segment[n-1] {
+		case '\'', 'h', 'H':
+			hardened = FirstHardenedChild
+			segment = segment[:n-1]
+		}
+	}
+
+	// Only plain digits, without sign or leading zeros, so String round trips
+	if len(segment) == 0 || (len(segment) > 1 && segment[0] == '0') {
+		return 0, ErrInvalidPathSegment
+	}
+	for i := 0; i < len(segment); i++ {
+		if segment[i] < '0' || segment[i] > '9' {
+			return 0, ErrInvalidPathSegment
+		}
+	}
+
+	index, err := strconv.ParseUint(segment, 10, 32)
+	if err != nil || uint32(index) >= FirstHardenedChild {
+		return 0, ErrPathIndexOutOfRange
+	}
+	return uint32(index) + hardened, nil
+}
@@ -0,0 +1,108 @@
+package keystore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDerivationPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		err  error
+	}{
+		{"m", "m", nil},
+		{"m/0", "m/0", nil},
+		{"m/44'/0h/0H/0/1", "m/44'/0'/0'/0/1", nil},
+		{"m/2147483647'", "m/2147483647'", nil},
+		{"", "", ErrEmptyDerivationPath},
+		{"/1", "", ErrMissingMaster},
+		{"0/1", "", ErrMissingMaster},
+		{"M/1", "", ErrMissingMaster},
+		{"m/", "", ErrInvalidPathSegment},
+		{"m//0", "", ErrInvalidPathSegment},
+		{"m/0/m", "", ErrMisplacedMaster},
+		{"m/2147483648", "", ErrPathIndexOutOfRange},
+		{"m/2147483648'", "", ErrPathIndexOutOfRange},
+		{"m/4294967296", "", ErrPathIndexOutOfRange},
+		{"m/-1", "", ErrInvalidPathSegment},
+		{"m/+1", "", ErrInvalidPathSegment},
+		{"m/01", "", ErrInvalidPathSegment},
+		{"m/1''", "", ErrInvalidPathSegment},
+		{"m/ 1", "", ErrInvalidPathSegment},
+		{"m/1x", "", ErrInvalidPathSegment},
+		{"m/h", "", ErrInvalidPathSegment},
+	}
+	for _, test := range tests {
+		path, err := ParseDerivationPath(test.path)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%q: got %v, want %v", test.path, err, test.err)
+			continue
+		}
+		if err == nil && path.String() != test.want {
+			t.Errorf("%q: parsed as %s, want %s", test.path, path, test.want)
+		}
+	}
+}
+
+func TestParseRelativeDerivationPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		err  error
+	}{
+		{"0/1", "0/1", nil},
+		{"/1", "1", nil},
+		{"0'/1h", "0'/1'", nil},
+		{"2147483647", "2147483647", nil},
+		{"", "", ErrEmptyDerivationPath},
+		{"/", "", ErrEmptyDerivationPath},
+		{"m/1", "", ErrMisplacedMaster},
+		{"0//1", "", ErrInvalidPathSegment},
+		{"1/", "", ErrInvalidPathSegment},
+		{"2147483648", "", ErrPathIndexOutOfRange},
+	}
+	for _, test := range tests {
+		path, err := ParseRelativeDerivationPath(test.path)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%q: got %v, want %v", test.path, err, test.err)
+			continue
+		}
+		if err == nil && (path.Absolute || path.String() != test.want) {
+			t.Errorf("%q: parsed as %s, want %s", test.path, path, test.want)
+		}
+	}
+}
+
+func TestDeriveChildKeyPaths(t *testing.T) {
+	account := testKey(t, "m/84'/0'/0'")
+	for _, path := range []string{"0/1", "/0/1"} {
+		relative, err := account.DeriveChildKey(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		absolute := testKey(t, "m/84'/0'/0'/0/1")
+		if relative.B58Serialize() != absolute.B58Serialize() {
+			t.Errorf("%s: derived %s, want %s", path, relative.B58Serialize(), absolute.B58Serialize())
+		}
+	}
+	if _, err := account.DeriveChildKey("m/0"); err != ErrAbsolutePathFromChild {
+		t.Errorf("absolute path from child: got %v, want %v", err, ErrAbsolutePathFromChild)
+	}
+}
+
+func TestDeriveEmptyPath(t *testing.T) {
+	master := testKey(t, "m")
+	xprv := master.B58Serialize()
+	derived, err := master.DeriveChildKey("m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derived == master || derived.B58Serialize() != xprv {
+		t.Fatal("m should derive a copy of the key")
+	}
+	derived.Zero()
+	if master.B58Serialize() != xprv {
+		t.Error("wiping the derived key wiped the master")
+	}
+}
@@ -0,0 +1,186 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+var (
+	// ErrWatchOnlyPrivateKey is returned when building a watch-only wallet
+	// from a private key
+	ErrWatchOnlyPrivateKey = errors.New("Watch-only wallets should be built from a public key")
+
+	// ErrScriptTypeMismatch is returned when a SLIP-132 key doesn't match the
+	// requested purpose
+	ErrScriptTypeMismatch = errors.New("Extended key version doesn't match the account purpose")
+
+	// ErrKeyMismatch is returned when upgrading a watch-only wallet with a
+	// private key of another account
+	ErrKeyMismatch = errors.New("Private key doesn't match the watch-only wallet")
+)
+
+// WatchOnlyWallet generates the addresses of an account from its extended
+// public key and tracks the next unused receive and change indexes.
+type WatchOnlyWallet struct {
+	NextExternal uint32
+	NextInternal uint32
+
+	account *Account
+}
+
+// WatchOnlyState is the persistable state of a watch-only wallet
+type WatchOnlyState struct {
+	ExtendedPublicKey string  `json:"xpub"`
+	Purpose           Purpose `json:"purpose"`
+	NextExternal      uint32  `json:"next_external"`
+	NextInternal      uint32  `json:"next_internal"`
+}
+
+// NewWatchOnlyWallet builds a watch-only wallet from an account level xpub
+// or SLIP-132 ypub/zpub. A SLIP-132 version must agree with purpose
+func NewWatchOnlyWallet(extendedPublicKey string, purpose Purpose) (*WatchOnlyWallet, error) {
+	key, err := B58Deserialize(extendedPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsPrivate {
+		key.Zero()
+		return nil, ErrWatchOnlyPrivateKey
+	}
+
+	scriptType, err := key.ScriptType()
+	if err != nil {
+		return nil, err
+	}
+	expected, err := purpose.scriptType()
+	if err != nil {
+		return nil, err
+	}
+	if scriptType != utils.ScriptP2PKH && scriptType != expected {
+		return nil, ErrScriptTypeMismatch
+	}
+
+	index := binary.BigEndian.Uint32(key.ChildNumber) &^ FirstHardenedChild
+	account, err := newAccount(key, purpose, index)
+	if err != nil {
+		return nil, err
+	}
+	return &WatchOnlyWallet{account: account}, nil
+}
+
+// RestoreWatchOnlyWallet rebuilds a watch-only wallet from a saved state
+func RestoreWatchOnlyWallet(state *WatchOnlyState) (*WatchOnlyWallet, error) {
+	wallet, err := NewWatchOnlyWallet(state.ExtendedPublicKey, state.Purpose)
+	if err != nil {
+		return nil, err
+	}
+	wallet.NextExternal = state.NextExternal
+	wallet.NextInternal = state.NextInternal
+	return wallet, nil
+}
+
+// State returns the wallet state to persist. It never holds private keys,
+// even once the wallet is upgraded
+func (wallet *WatchOnlyWallet) State() *WatchOnlyState {
+	return &WatchOnlyState{
+		ExtendedPublicKey: wallet.account.ExtendedPublicKey(),
+		Purpose:           wallet.account.Purpose,
+		NextExternal:      wallet.NextExternal,
+		NextInternal:      wallet.NextInternal,
+	}
+}
+
+// Account returns the wallet's account, able to sign once upgraded
+func (wallet *WatchOnlyWallet) Account() *Account {
+	return wallet.account
+}
+
+// IsWatchOnly reports whether the wallet has no private key
+func (wallet *WatchOnlyWallet) IsWatchOnly() bool {
+	return !wallet.account.key.IsPrivate
+}
+
+// ReceiveAddress returns receiving address i
+func (wallet *WatchOnlyWallet) ReceiveAddress(i uint32) (string, error) {
+	return wallet.account.ExternalAddress(i)
+}
+
+// ChangeAddress returns change address i
+func (wallet *WatchOnlyWallet) ChangeAddress(i uint32) (string, error) {
+	return wallet.account.InternalAddress(i)
+}
+
+// NextReceiveAddress returns the next unused receiving address and advances
+// NextExternal
+func (wallet *WatchOnlyWallet) NextReceiveAddress() (string, error) {
+	address, err := wallet.account.ExternalAddress(wallet.NextExternal)
+	if err != nil {
+		return "", err
+	}
+	wallet.NextExternal++
+	return address, nil
+}
+
+// NextChangeAddress returns the next unused change address and advances
+// NextInternal
+func (wallet *WatchOnlyWallet) NextChangeAddress() (string, error) {
+	address, err := wallet.account.InternalAddress(wallet.NextInternal)
+	if err != nil {
+		return "", err
+	}
+	wallet.NextInternal++
+	return address, nil
+}
+
+// Upgrade gives the wallet its private keys. key is either the private
+// master key or the account level xprv; it must derive exactly the watched
+// public key, parent fingerprint and chain code
+func (wallet *WatchOnlyWallet) Upgrade(key *Key) error {
+	if !key.IsPrivate {
+		return ErrNotPrivateKey
+	}
+
+	watched := wallet.account.key
+	accountKey := key
+	if key.Depth == 0 && watched.Depth != 0 {
+		path := &DerivationPath{Absolute: true, Indexes: []uint32{
+			FirstHardenedChild + uint32(wallet.account.Purpose),
+			FirstHardenedChild + key.network().CoinType,
+			FirstHardenedChild + wallet.account.Index,
+		}}
+		derived, err := key.DerivePath(path)
+		if err != nil {
+			return err
+		}
+		defer derived.Zero()
+		accountKey = derived
+	}
+
+	public := accountKey.PublicKey()
+	fingerprint, err := public.Fingerprint()
+	if err != nil {
+		return err
+	}
+	watchedFingerprint, err := watched.Fingerprint()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(fingerprint, watchedFingerprint) ||
+		!bytes.Equal(public.FingerPrint, watched.FingerPrint) ||
+		!bytes.Equal(public.Key, watched.Key) ||
+		!bytes.Equal(public.ChainCode, watched.ChainCode) ||
+		public.Depth != watched.Depth {
+		return ErrKeyMismatch
+	}
+
+	account, err := newAccount(accountKey, wallet.account.Purpose, wallet.account.Index)
+	if err != nil {
+		return err
+	}
+	wallet.account.Zero()
+	wallet.account = account
+	return nil
+}
@@ -0,0 +1,89 @@
+package keystore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWatchOnlyWallet(t *testing.T) {
+	const zpub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	wallet, err := NewWatchOnlyWallet(zpub, PurposeBIP84)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wallet.IsWatchOnly() {
+		t.Error("wallet from a zpub should be watch-only")
+	}
+	receive, err := wallet.NextReceiveAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receive != "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu" || wallet.NextExternal != 1 {
+		t.Errorf("receive address %s, next %d", receive, wallet.NextExternal)
+	}
+	change, err := wallet.NextChangeAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change != "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el" || wallet.NextInternal != 1 {
+		t.Errorf("change address %s, next %d", change, wallet.NextInternal)
+	}
+
+	data, err := json.Marshal(wallet.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state WatchOnlyState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreWatchOnlyWallet(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.NextExternal != 1 || restored.NextInternal != 1 || !restored.IsWatchOnly() {
+		t.Errorf("restored state %s", data)
+	}
+
+	other, err := FromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Upgrade(other); err != ErrKeyMismatch {
+		t.Errorf("upgrade with another key: got %v, want %v", err, ErrKeyMismatch)
+	}
+	master, err := FromMnemonic(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Upgrade(master); err != nil {
+		t.Fatal(err)
+	}
+	if restored.IsWatchOnly() {
+		t.Error("upgraded wallet is still watch-only")
+	}
+	if address, _ := restored.ReceiveAddress(0); address != receive {
+		t.Errorf("upgraded receive address %s, want %s", address, receive)
+	}
+	if restored.State().ExtendedPublicKey != zpub {
+		t.Errorf("upgraded state xpub %s", restored.State().ExtendedPublicKey)
+	}
+}
+
+func TestWatchOnlyWalletInvalid(t *testing.T) {
+	const zpub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	if _, err := NewWatchOnlyWallet(zpub, PurposeBIP49); err != ErrScriptTypeMismatch {
+		t.Errorf("zpub as BIP49: got %v, want %v", err, ErrScriptTypeMismatch)
+	}
+	account := testKey(t, "m/84'/0'/0'")
+	if _, err := NewWatchOnlyWallet(account.B58Serialize(), PurposeBIP84); err != ErrWatchOnlyPrivateKey {
+		t.Errorf("private key: got %v, want %v", err, ErrWatchOnlyPrivateKey)
+	}
+	wallet, err := NewWatchOnlyWallet(account.PublicKey().B58Serialize(), PurposeBIP84)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wallet.Upgrade(account); err != nil {
+		t.Errorf("upgrade with the account key: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package psbt
+
+import "bytes"
+
+// Combine merges PSBTs of the same transaction, as produced by different
+// signers, into a new PSBT. Fields set in several PSBTs are taken from the
+// first one holding them. The version is the first PSBT's.
+func Combine(packets ...*Packet) (*Packet, error) {
+	if len(packets) == 0 {
+		return nil, ErrNothingToCombine
+	}
+	first := packets[0]
+	unsigned, err := first.UnsignedTx()
+	if err != nil {
+		return nil, err
+	}
+
+	combined := &Packet{
+		Version:      first.Version,
+		TxVersion:    first.TxVersion,
+		LockTime:     first.LockTime,
+		TxModifiable: first.TxModifiable,
+	}
+	for _, in := range first.Inputs {
+		combined.Inputs = append(combined.Inputs, &Input{PreviousOutPoint: in.PreviousOutPoint, Sequence: in.Sequence})
+	}
+	for _, out := range first.Outputs {
+		combined.Outputs = append(combined.Outputs, &Output{Amount: out.Amount, Script: out.Script})
+	}
+
+	for _, packet := range packets {
+		other, err := packet.UnsignedTx()
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(other.Hash(), unsigned.Hash()) {
+			return nil, ErrDifferentTx
+		}
+
+		for _, xpub := range packet.XPubs {
+			if !combined.hasXPub(xpub.ExtendedKey) {
+				combined.XPubs = append(combined.XPubs, xpub)
+			}
+		}
+		combined.Unknowns = mergeUnknowns(combined.Unknowns, packet.Unknowns)
+		for i, in := range packet.Inputs {
+			combined.Inputs[i].merge(in)
+		}
+		for i, out := range packet.Outputs {
+			combined.Outputs[i].merge(out)
+		}
+	}
+	return combined, nil
+}
+
+func (packet *Packet) hasXPub(extendedKey []byte) bool {
+	for _, xpub := range packet.XPubs {
+		if bytes.Equal(xpub.ExtendedKey, extendedKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func (in *Input) merge(other *Input) {
+	if in.RequiredTimeLockTime == 0 {
+		in.RequiredTimeLockTime = other.RequiredTimeLockTime
+	}
+	if in.RequiredHeightLockTime == 0 {
+		in.RequiredHeightLockTime = other.RequiredHeightLockTime
+	}
+	if in.NonWitnessUtxo == nil {
+		in.NonWitnessUtxo = other.NonWitnessUtxo
+	}
+	if in.WitnessUtxo == nil {
+		in.WitnessUtxo = other.WitnessUtxo
+	}
+	if in.SigHashType == 0 && !in.HasSigHashType {
+		in.SigHashType, in.HasSigHashType = other.SigHashType, other.HasSigHashType
+	}
+	if in.RedeemScript == nil {
+		in.RedeemScript = other.RedeemScript
+	}
+	if in.WitnessScript == nil {
+		in.WitnessScript = other.WitnessScript
+	}
+	if in.FinalScriptSig == nil {
+		in.FinalScriptSig = other.FinalScriptSig
+	}
+	if in.FinalScriptWitness == nil {
+		in.FinalScriptWitness = other.FinalScriptWitness
+	}
+	if in.TaprootKeySpendSig == nil {
+		in.TaprootKeySpendSig = other.TaprootKeySpendSig
+	}
+	if in.TaprootInternalKey == nil {
+		in.TaprootInternalKey = other.TaprootInternalKey
+	}
+	if in.TaprootMerkleRoot == nil {
+		in.TaprootMerkleRoot = other.TaprootMerkleRoot
+	}
+
+	for _, sig := range other.PartialSigs {
+		if !in.hasPartialSig(sig.PubKey) {
+			in.PartialSigs = append(in.PartialSigs, sig)
+		}
+	}
+	in.Bip32Derivations = mergeDerivations(in.Bip32Derivations, other.Bip32Derivations)
+	for _, sig := range other.TaprootScriptSpendSigs {
+		if !in.hasTaprootScriptSig(sig.XOnlyPubKey, sig.LeafHash) {
+			in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs, sig)
+		}
+	}
+	for _, leaf := range other.TaprootLeafScripts {
+		if !in.hasTaprootLeafScript(leaf.ControlBlock) {
+			in.TaprootLeafScripts = append(in.TaprootLeafScripts, leaf)
+		}
+	}
+	in.TaprootBip32Derivations = mergeTaprootDerivations(in.TaprootBip32Derivations, other.TaprootBip32Derivations)
+	in.Unknowns = mergeUnknowns(in.Unknowns, other.Unknowns)
+}
+
+func (in *Input) hasTaprootLeafScript(controlBlock []byte) bool {
+	for _, leaf := range in.TaprootLeafScripts {
+		if bytes.Equal(leaf.ControlBlock, controlBlock) {
+			return true
+		}
+	}
+	return false
+}
+
+func (out *Output) merge(other *Output) {
+	if out.RedeemScript == nil {
+		out.RedeemScript = other.RedeemScript
+	}
+	if out.WitnessScript == nil {
+		out.WitnessScript = other.WitnessScript
+	}
+	if out.TaprootInternalKey == nil {
+		out.TaprootInternalKey = other.TaprootInternalKey
+	}
+	if out.TaprootTree == nil {
+		out.TaprootTree = other.TaprootTree
+	}
+	out.Bip32Derivations = mergeDerivations(out.Bip32Derivations, other.Bip32Derivations)
+	out.TaprootBip32Derivations = mergeTaprootDerivations(out.TaprootBip32Derivations, other.TaprootBip32Derivations)
+	out.Unknowns = mergeUnknowns(out.Unknowns, other.Unknowns)
+}
+
+func mergeDerivations(derivations []*Bip32Derivation, others []*Bip32Derivation) []*Bip32Derivation {
+	for _, other := range others {
+		found := false
+		for _, derivation := range derivations {
+			found = found || bytes.Equal(derivation.PubKey, other.PubKey)
+		}
+		if !found {
+			derivations = append(derivations, other)
+		}
+	}
+	return derivations
+}
+
+func mergeTaprootDerivations(derivations []*TaprootBip32Derivation, others []*TaprootBip32Derivation) []*TaprootBip32Derivation {
+	for _, other := range others {
+		found := false
+		for _, derivation := range derivations {
+			found = found || bytes.Equal(derivation.XOnlyPubKey, other.XOnlyPubKey)
+		}
+		if !found {
+			derivations = append(derivations, other)
+		}
+	}
+	return derivations
+}
+
+func mergeUnknowns(unknowns []*Unknown, others []*Unknown) []*Unknown {
+	for _, other := range others {
+		found := false
+		for _, unknown := range unknowns {
+			found = found || bytes.Equal(unknown.Key, other.Key)
+		}
+		if !found {
+			unknowns = append(unknowns, other)
+		}
+	}
+	return unknowns
+}
@@ -0,0 +1,343 @@
+package psbt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/icodeface/go-blockchain-kit/crypto"
+	"github.com/icodeface/go-blockchain-kit/tx"
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+const (
+	op1             = 0x51
+	op16            = 0x60
+	opCheckSig      = 0xac
+	opCheckMultiSig = 0xae
+)
+
+var (
+	// ErrCannotFinalize is returned when an input lacks the signatures its
+	// script needs, or has a script the finalizer doesn't know
+	ErrCannotFinalize = errors.New("Input can't be finalized")
+
+	// ErrNotFinalized is returned when extracting a PSBT with inputs that
+	// aren't finalized
+	ErrNotFinalized = errors.New("PSBT isn't fully finalized")
+)
+
+// Finalize builds the final script sig and witness of every input that
+// isn't finalized yet. The error names the first input that can't be.
+func (packet *Packet) Finalize() error {
+	for i := range packet.Inputs {
+		if err := packet.FinalizeInput(i); err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// FinalizeInput builds the final script sig and witness of input i from
+// its signatures and drops the fields only signers need. It handles
+// P2PKH, P2WPKH, P2SH-P2WPKH, bare, P2SH and P2WSH multisig, taproot key
+// path and single key tapscript leaves.
+func (packet *Packet) FinalizeInput(i int) error {
+	if i < 0 || i >= len(packet.Inputs) {
+		return tx.ErrInputIndexOutOfRange
+	}
+	in := packet.Inputs[i]
+	if in.IsFinalized() {
+		return nil
+	}
+	prevOut, err := in.prevOut()
+	if err != nil {
+		return err
+	}
+
+	var scriptSig []byte
+	var witness [][]byte
+	addrType, program := tx.ScriptAddressType(prevOut.PkScript)
+	switch addrType {
+	case utils.AddressP2TR:
+		witness, err = packet.taprootWitness(i, program)
+	case utils.AddressP2SH:
+		if in.RedeemScript == nil {
+			return ErrMissingScript
+		}
+		hash160, err := utils.Hash160(in.RedeemScript)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(hash160, program) {
+			return ErrScriptMismatch
+		}
+		scriptSig = tx.PushData(in.RedeemScript)
+		redeemType, redeemProgram := tx.ScriptAddressType(in.RedeemScript)
+		switch redeemType {
+		case utils.AddressP2WPKH:
+			witness, err = packet.keyHashWitness(i, prevOut, in.RedeemScript)
+		case utils.AddressP2WSH:
+			witness, err = packet.multiSigWitness(i, prevOut, redeemProgram)
+		default:
+			var redeem []byte
+			redeem, err = packet.multiSigScriptSig(i, prevOut, in.RedeemScript)
+			scriptSig = append(redeem, scriptSig...)
+		}
+		if err != nil {
+			return err
+		}
+	case utils.AddressP2WPKH:
+		witness, err = packet.keyHashWitness(i, prevOut, prevOut.PkScript)
+	case utils.AddressP2WSH:
+		witness, err = packet.multiSigWitness(i, prevOut, program)
+	case utils.AddressP2PKH:
+		witness, err = packet.keyHashWitness(i, prevOut, prevOut.PkScript)
+		if err == nil {
+			scriptSig = append(tx.PushData(witness[0]), tx.PushData(witness[1])...)
+			witness = nil
+		}
+	default:
+		scriptSig, err = packet.multiSigScriptSig(i, prevOut, prevOut.PkScript)
+	}
+	if err != nil {
+		return err
+	}
+
+	finalized := &Input{
+		PreviousOutPoint:       in.PreviousOutPoint,
+		Sequence:               in.Sequence,
+		RequiredTimeLockTime:   in.RequiredTimeLockTime,
+		RequiredHeightLockTime: in.RequiredHeightLockTime,
+		NonWitnessUtxo:         in.NonWitnessUtxo,
+		WitnessUtxo:            in.WitnessUtxo,
+		FinalScriptSig:         scriptSig,
+		FinalScriptWitness:     witness,
+		Unknowns:               in.Unknowns,
+	}
+	packet.Inputs[i] = finalized
+	return nil
+}
+
+// Extract returns the network transaction of a finalized PSBT.
+func (packet *Packet) Extract() (*tx.Tx, error) {
+	if !packet.IsComplete() {
+		return nil, ErrNotFinalized
+	}
+	final, err := packet.UnsignedTx()
+	if err != nil {
+		return nil, err
+	}
+	for i, in := range packet.Inputs {
+		final.Inputs[i].SignatureScript = copyBytes(in.FinalScriptSig)
+		for _, item := range in.FinalScriptWitness {
+			final.Inputs[i].Witness = append(final.Inputs[i].Witness, copyBytes(item))
+		}
+	}
+	return final, nil
+}
+
+// keyHashWitness returns the [signature, public key] pair spending the
+// P2PKH or P2WPKH script of input i, from the partial signature whose key
+// hashes to the script and that is valid for the transaction.
+func (packet *Packet) keyHashWitness(i int, prevOut *tx.TxOut, script []byte) ([][]byte, error) {
+	addrType, program := tx.ScriptAddressType(script)
+	scriptCode := script
+	if addrType == utils.AddressP2WPKH {
+		scriptCode = tx.P2WPKHScriptCode(program)
+	}
+	sigHash, err := packet.ecdsaSigHasher(i, prevOut, scriptCode, addrType == utils.AddressP2WPKH)
+	if err != nil {
+		return nil, err
+	}
+	for _, sig := range packet.Inputs[i].PartialSigs {
+		hash160, err := utils.Hash160(sig.PubKey)
+		if err != nil || !bytes.Equal(hash160, program) {
+			continue
+		}
+		if validPartialSig(sig, sigHash) {
+			return [][]byte{sig.Signature, sig.PubKey}, nil
+		}
+	}
+	return nil, ErrCannotFinalize
+}
+
+// multiSigWitness returns the witness spending a P2WSH multisig, whose
+// witness script hashes to program.
+func (packet *Packet) multiSigWitness(i int, prevOut *tx.TxOut, program []byte) ([][]byte, error) {
+	in := packet.Inputs[i]
+	if in.WitnessScript == nil {
+		return nil, ErrMissingScript
+	}
+	if hash := sha256.Sum256(in.WitnessScript); !bytes.Equal(hash[:], program) {
+		return nil, ErrScriptMismatch
+	}
+	sigHash, err := packet.ecdsaSigHasher(i, prevOut, in.WitnessScript, true)
+	if err != nil {
+		return nil, err
+	}
+	sigs, err := in.multiSigs(in.WitnessScript, sigHash)
+	if err != nil {
+		return nil, err
+	}
+	// The empty item feeds the extra value OP_CHECKMULTISIG pops
+	witness := append([][]byte{{}}, sigs...)
+	return append(witness, in.WitnessScript), nil
+}
+
+// multiSigScriptSig returns the script sig pushes of the signatures of a
+// legacy multisig script.
+func (packet *Packet) multiSigScriptSig(i int, prevOut *tx.TxOut, script []byte) ([]byte, error) {
+	sigHash, err := packet.ecdsaSigHasher(i, prevOut, script, false)
+	if err != nil {
+		return nil, err
+	}
+	sigs, err := packet.Inputs[i].multiSigs(script, sigHash)
+	if err != nil {
+		return nil, err
+	}
+	scriptSig := []byte{0x00}
+	for _, sig := range sigs {
+		scriptSig = append(scriptSig, tx.PushData(sig)...)
+	}
+	return scriptSig, nil
+}
+
+// multiSigs returns the required number of valid signatures of an m-of-n
+// OP_CHECKMULTISIG script, in the order of its public keys.
+func (in *Input) multiSigs(script []byte, sigHash sigHasher) ([][]byte, error) {
+	required, publicKeys, ok := parseMultiSig(script)
+	if !ok {
+		return nil, ErrCannotFinalize
+	}
+	var sigs [][]byte
+	for _, publicKey := range publicKeys {
+		for _, sig := range in.PartialSigs {
+			if len(sigs) == required {
+				break
+			}
+			if bytes.Equal(sig.PubKey, publicKey) && validPartialSig(sig, sigHash) {
+				sigs = append(sigs, sig.Signature)
+				break
+			}
+		}
+	}
+	if len(sigs) < required {
+		return nil, ErrCannotFinalize
+	}
+	return sigs, nil
+}
+
+// taprootWitness returns the key path witness, or else the witness of a
+// "<key> OP_CHECKSIG" leaf with a signature. Only signatures valid for the
+// output key or the leaf key are used.
+func (packet *Packet) taprootWitness(i int, outputKey []byte) ([][]byte, error) {
+	in := packet.Inputs[i]
+	unsigned, err := packet.UnsignedTx()
+	if err != nil {
+		return nil, err
+	}
+	prevOuts := make([]*tx.TxOut, len(packet.Inputs))
+	for j, other := range packet.Inputs {
+		if prevOuts[j], err = other.prevOut(); err != nil {
+			return nil, err
+		}
+	}
+	validSig := func(publicKey []byte, sig []byte, scriptPath *tx.ScriptPathSpend) bool {
+		hashType, ok := taprootSigHashType(sig)
+		if !ok {
+			return false
+		}
+		sigHash, err := unsigned.TaprootSigHash(i, prevOuts, hashType, nil, scriptPath)
+		return err == nil && crypto.SchnorrVerify(publicKey, sigHash, sig[:64])
+	}
+
+	if in.TaprootKeySpendSig != nil && validSig(outputKey, in.TaprootKeySpendSig, nil) {
+		return [][]byte{in.TaprootKeySpendSig}, nil
+	}
+	for _, leaf := range in.TaprootLeafScripts {
+		if len(leaf.Script) != 34 || leaf.Script[0] != 0x20 || leaf.Script[33] != opCheckSig {
+			continue
+		}
+		leafHash := tx.TapLeafHash(leaf.LeafVersion, leaf.Script)
+		scriptPath := &tx.ScriptPathSpend{LeafHash: leafHash, CodeSeparatorPos: tx.NoCodeSeparator}
+		for _, sig := range in.TaprootScriptSpendSigs {
+			if bytes.Equal(sig.LeafHash, leafHash) && bytes.Equal(sig.XOnlyPubKey, leaf.Script[1:33]) &&
+				validSig(sig.XOnlyPubKey, sig.Signature, scriptPath) {
+				return [][]byte{sig.Signature, leaf.Script, leaf.ControlBlock}, nil
+			}
+		}
+	}
+	return nil, ErrCannotFinalize
+}
+
+// sigHasher returns the ECDSA sighash of an input for a hash type.
+type sigHasher func(hashType tx.SigHashType) ([]byte, error)
+
+// ecdsaSigHasher returns the legacy or segwit version 0 sighash function of
+// input i for scriptCode.
+func (packet *Packet) ecdsaSigHasher(i int, prevOut *tx.TxOut, scriptCode []byte, witness bool) (sigHasher, error) {
+	unsigned, err := packet.UnsignedTx()
+	if err != nil {
+		return nil, err
+	}
+	return func(hashType tx.SigHashType) ([]byte, error) {
+		if witness {
+			return unsigned.WitnessV0SigHash(i, scriptCode, prevOut.Value, hashType)
+		}
+		return unsigned.LegacySigHash(i, scriptCode, hashType)
+	}, nil
+}
+
+// validPartialSig reports whether sig, a DER signature followed by its hash
+// type, is valid for its public key.
+func validPartialSig(sig *PartialSig, sigHash sigHasher) bool {
+	if len(sig.Signature) == 0 {
+		return false
+	}
+	der, hashType := sig.Signature[:len(sig.Signature)-1], tx.SigHashType(sig.Signature[len(sig.Signature)-1])
+	signature, err := crypto.ParseDERSignature(der)
+	if err != nil {
+		return false
+	}
+	hash, err := sigHash(hashType)
+	return err == nil && utils.VerifySignature(sig.PubKey, hash, signature)
+}
+
+// taprootSigHashType returns the hash type of a BIP341 signature, implied
+// by a 64 bytes signature and appended to a 65 bytes one.
+func taprootSigHashType(sig []byte) (tx.SigHashType, bool) {
+	switch {
+	case len(sig) == 64:
+		return tx.SigHashDefault, true
+	case len(sig) == 65 && sig[64] != byte(tx.SigHashDefault):
+		return tx.SigHashType(sig[64]), true
+	}
+	return 0, false
+}
+
+// parseMultiSig parses "OP_m <pubkey>... OP_n OP_CHECKMULTISIG".
+func parseMultiSig(script []byte) (int, [][]byte, bool) {
+	if len(script) < 3 || script[len(script)-1] != opCheckMultiSig {
+		return 0, nil, false
+	}
+	m, n := script[0], script[len(script)-2]
+	if m < op1 || m > op16 || n < m || n > op16 {
+		return 0, nil, false
+	}
+
+	var publicKeys [][]byte
+	for pos := 1; pos < len(script)-2; {
+		size := int(script[pos])
+		if size != 33 && size != 65 || pos+1+size > len(script)-2 {
+			return 0, nil, false
+		}
+		publicKeys = append(publicKeys, script[pos+1:pos+1+size])
+		pos += 1 + size
+	}
+	if len(publicKeys) != int(n-op1+1) {
+		return 0, nil, false
+	}
+	return int(m - op1 + 1), publicKeys, true
+}
@@ -0,0 +1,264 @@
+// Package psbt implements BIP174 partially signed bitcoin transactions and
+// their BIP370 version 2 encoding.
+package psbt
+
+import (
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/keystore"
+	"github.com/icodeface/go-blockchain-kit/tx"
+)
+
+var (
+	// ErrUnsupportedVersion is returned for PSBT versions other than 0 and 2
+	ErrUnsupportedVersion = errors.New("Unsupported PSBT version")
+
+	// ErrInvalidTxVersion is returned for version 2 PSBTs of transactions
+	// below version 2
+	ErrInvalidTxVersion = errors.New("PSBT version 2 requires transaction version 2")
+
+	// ErrTxNotUnsigned is returned when the unsigned transaction has
+	// signature scripts or witnesses
+	ErrTxNotUnsigned = errors.New("PSBT transaction should be unsigned")
+
+	// ErrDifferentTx is returned when combining PSBTs of different
+	// transactions
+	ErrDifferentTx = errors.New("PSBTs are for different transactions")
+
+	// ErrNothingToCombine is returned when combining no PSBT at all
+	ErrNothingToCombine = errors.New("No PSBT to combine")
+
+	// ErrLockTimeConflict is returned when some version 2 inputs require a
+	// height based lock time and others a time based one
+	ErrLockTimeConflict = errors.New("Inputs require incompatible lock times")
+)
+
+// Unknown is a key-value pair this package doesn't interpret, kept so that
+// it survives a round trip. Key includes the key type.
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// Bip32Derivation records the master key fingerprint and derivation path of
+// a public key involved in an input or output.
+type Bip32Derivation struct {
+	PubKey      []byte
+	Fingerprint []byte
+	Path        []uint32
+}
+
+// DerivationPath returns the absolute path of the derivation.
+func (derivation *Bip32Derivation) DerivationPath() *keystore.DerivationPath {
+	return &keystore.DerivationPath{Absolute: true, Indexes: derivation.Path}
+}
+
+// TaprootBip32Derivation is the taproot variant of Bip32Derivation for an
+// x-only key, along with the tapleaf hashes of the scripts it appears in.
+type TaprootBip32Derivation struct {
+	XOnlyPubKey []byte
+	LeafHashes  [][]byte
+	Fingerprint []byte
+	Path        []uint32
+}
+
+// DerivationPath returns the absolute path of the derivation.
+func (derivation *TaprootBip32Derivation) DerivationPath() *keystore.DerivationPath {
+	return &keystore.DerivationPath{Absolute: true, Indexes: derivation.Path}
+}
+
+// XPub is a global extended public key with its origin.
+type XPub struct {
+	ExtendedKey []byte // 78 bytes bip32 serialization
+	Fingerprint []byte
+	Path        []uint32
+}
+
+// PartialSig is an ECDSA signature, with its hash type byte, of an input.
+type PartialSig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// TaprootScriptSpendSig is a Schnorr signature of an input for the tapleaf
+// LeafHash.
+type TaprootScriptSpendSig struct {
+	XOnlyPubKey []byte
+	LeafHash    []byte
+	Signature   []byte
+}
+
+// TaprootLeafScript is a tapscript leaf along with the control block that
+// proves it's part of the output's script tree.
+type TaprootLeafScript struct {
+	ControlBlock []byte
+	Script       []byte
+	LeafVersion  byte
+}
+
+// Input holds what signers need to know about an input and the signatures
+// collected so far. SigHashType 0 means the default: SIGHASH_ALL for ECDSA
+// and SIGHASH_DEFAULT for taproot. HasSigHashType records an explicit 0, so
+// that it survives a round trip.
+type Input struct {
+	PreviousOutPoint       tx.OutPoint
+	Sequence               uint32
+	RequiredTimeLockTime   uint32 // version 2 only, 0 when absent
+	RequiredHeightLockTime uint32 // version 2 only, 0 when absent
+
+	NonWitnessUtxo     *tx.Tx
+	WitnessUtxo        *tx.TxOut
+	PartialSigs        []*PartialSig
+	SigHashType        tx.SigHashType
+	HasSigHashType     bool
+	RedeemScript       []byte
+	WitnessScript      []byte
+	Bip32Derivations   []*Bip32Derivation
+	FinalScriptSig     []byte
+	FinalScriptWitness [][]byte
+
+	TaprootKeySpendSig      []byte
+	TaprootScriptSpendSigs  []*TaprootScriptSpendSig
+	TaprootLeafScripts      []*TaprootLeafScript
+	TaprootBip32Derivations []*TaprootBip32Derivation
+	TaprootInternalKey      []byte
+	TaprootMerkleRoot       []byte
+
+	Unknowns []*Unknown
+}
+
+// Output holds what signers need to know about an output, such as the
+// derivation of change keys.
+type Output struct {
+	Amount int64
+	Script []byte
+
+	RedeemScript            []byte
+	WitnessScript           []byte
+	Bip32Derivations        []*Bip32Derivation
+	TaprootInternalKey      []byte
+	TaprootTree             []byte
+	TaprootBip32Derivations []*TaprootBip32Derivation
+
+	Unknowns []*Unknown
+}
+
+// Packet is a partially signed transaction. The unsigned transaction is
+// kept as its fields so that a packet serializes either as version 0, with
+// a global unsigned transaction, or as BIP370 version 2 with per input and
+// output fields. LockTime is the transaction lock time for version 0 and
+// the fallback lock time for version 2.
+type Packet struct {
+	Version      uint32
+	TxVersion    int32
+	LockTime     uint32
+	TxModifiable byte // version 2 only
+	XPubs        []*XPub
+	Inputs       []*Input
+	Outputs      []*Output
+	Unknowns     []*Unknown
+}
+
+// New returns a version 0 PSBT for an unsigned transaction. Set Version to
+// 2 to serialize it as BIP370.
+func New(unsigned *tx.Tx) (*Packet, error) {
+	packet := &Packet{TxVersion: unsigned.Version, LockTime: unsigned.LockTime}
+	for _, in := range unsigned.Inputs {
+		if len(in.SignatureScript) != 0 || len(in.Witness) != 0 {
+			return nil, ErrTxNotUnsigned
+		}
+		packet.Inputs = append(packet.Inputs, &Input{
+			PreviousOutPoint: in.PreviousOutPoint,
+			Sequence:         in.Sequence,
+		})
+	}
+	for _, out := range unsigned.Outputs {
+		packet.Outputs = append(packet.Outputs, &Output{
+			Amount: out.Value,
+			Script: copyBytes(out.PkScript),
+		})
+	}
+	return packet, nil
+}
+
+// UnsignedTx returns the transaction being signed, without any signature.
+// For version 2 the lock time is computed from the inputs' requirements
+// as described in BIP370.
+func (packet *Packet) UnsignedTx() (*tx.Tx, error) {
+	lockTime, err := packet.lockTime()
+	if err != nil {
+		return nil, err
+	}
+	unsigned := tx.NewTx(packet.TxVersion)
+	unsigned.LockTime = lockTime
+	for _, in := range packet.Inputs {
+		txIn := tx.NewTxIn(&in.PreviousOutPoint)
+		txIn.Sequence = in.Sequence
+		unsigned.AddInput(txIn)
+	}
+	for _, out := range packet.Outputs {
+		unsigned.AddOutput(tx.NewTxOut(out.Amount, copyBytes(out.Script)))
+	}
+	return unsigned, nil
+}
+
+// lockTime picks the height based lock time when every input allows it,
+// the time based one otherwise, and the fallback when no input requires
+// any.
+func (packet *Packet) lockTime() (uint32, error) {
+	if packet.Version < 2 {
+		return packet.LockTime, nil
+	}
+
+	var height, time uint32
+	heightOK, timeOK, required := true, true, false
+	for _, in := range packet.Inputs {
+		if in.RequiredHeightLockTime == 0 && in.RequiredTimeLockTime == 0 {
+			continue
+		}
+		required = true
+		if in.RequiredHeightLockTime == 0 {
+			heightOK = false
+		} else if in.RequiredHeightLockTime > height {
+			height = in.RequiredHeightLockTime
+		}
+		if in.RequiredTimeLockTime == 0 {
+			timeOK = false
+		} else if in.RequiredTimeLockTime > time {
+			time = in.RequiredTimeLockTime
+		}
+	}
+
+	switch {
+	case !required:
+		return packet.LockTime, nil
+	case heightOK:
+		return height, nil
+	case timeOK:
+		return time, nil
+	}
+	return 0, ErrLockTimeConflict
+}
+
+// IsComplete reports whether every input is finalized.
+func (packet *Packet) IsComplete() bool {
+	for _, in := range packet.Inputs {
+		if !in.IsFinalized() {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFinalized reports whether the input has its final script sig or
+// witness.
+func (in *Input) IsFinalized() bool {
+	return in.FinalScriptSig != nil || in.FinalScriptWitness != nil
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,887 @@
+package psbt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/icodeface/go-blockchain-kit/crypto"
+	"github.com/icodeface/go-blockchain-kit/keystore"
+	"github.com/icodeface/go-blockchain-kit/tx"
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+// validVectors are the valid BIP174 test vectors, in hex.
+var validVectors = []string{
+	"70736274ff0100750200000001268171371edff285e937adeea4b37b78000c0566cbb3ad64641713ca42171bf60000000000feffffff02d3dff505000000001976a914d0c59903c5bac2868760e90fd521a4665aa7652088ac00e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787b32e1300000100fda5010100000000010289a3c71eab4d20e0371bbba4cc698fa295c9463afa2e397f8533ccb62f9567e50100000017160014be18d152a9b012039daf3da7de4f53349eecb985ffffffff86f8aa43a71dff1448893a530a7237ef6b4608bbb2dd2d0171e63aec6a4890b40100000017160014fe3e9ef1a745e974d902c4355943abcb34bd5353ffffffff0200c2eb0b000000001976a91485cff1097fd9e008bb34af709c62197b38978a4888ac72fef84e2c00000017a914339725ba21efd62ac753a9bcd067d6c7a6a39d05870247304402202712be22e0270f394f568311dc7ca9a68970b8025fdd3b240229f07f8a5f3a240220018b38d7dcd314e734c9276bd6fb40f673325bc4baa144c800d2f2f02db2765c012103d2e15674941bad4a996372cb87e1856d3652606d98562fe39c5e9e7e413f210502483045022100d12b852d85dcd961d2f5f4ab660654df6eedcc794c0c33ce5cc309ffb5fce58d022067338a8e0e1725c197fb1a88af59f51e44e4255b20167c8684031c05d1f2592a01210223b72beef0965d10be0778efecd61fcac6f79a4ea169393380734464f84f2ab300000000000000",
+	"70736274ff0100a00200000002ab0949a08c5af7c49b8212f417e2f15ab3f5c33dcf153821a8139f877a5b7be40000000000feffffffab0949a08c5af7c49b8212f417e2f15ab3f5c33dcf153821a8139f877a5b7be40100000000feffffff02603bea0b000000001976a914768a40bbd740cbe81d988e71de2a4d5c71396b1d88ac8e240000000000001976a9146f4620b553fa095e721b9ee0efe9fa039cca459788ac000000000001076a47304402204759661797c01b036b25928948686218347d89864b719e1f7fcf57d1e511658702205309eabf56aa4d8891ffd111fdf1336f3a29da866d7f8486d75546ceedaf93190121035cdc61fc7ba971c0b501a646a2a83b102cb43881217ca682dc86e2d73fa882920001012000e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787010416001485d13537f2e265405a34dbafa9e3dda01fb82308000000",
+	"70736274ff0100750200000001268171371edff285e937adeea4b37b78000c0566cbb3ad64641713ca42171bf60000000000feffffff02d3dff505000000001976a914d0c59903c5bac2868760e90fd521a4665aa7652088ac00e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787b32e1300000100fda5010100000000010289a3c71eab4d20e0371bbba4cc698fa295c9463afa2e397f8533ccb62f9567e50100000017160014be18d152a9b012039daf3da7de4f53349eecb985ffffffff86f8aa43a71dff1448893a530a7237ef6b4608bbb2dd2d0171e63aec6a4890b40100000017160014fe3e9ef1a745e974d902c4355943abcb34bd5353ffffffff0200c2eb0b000000001976a91485cff1097fd9e008bb34af709c62197b38978a4888ac72fef84e2c00000017a914339725ba21efd62ac753a9bcd067d6c7a6a39d05870247304402202712be22e0270f394f568311dc7ca9a68970b8025fdd3b240229f07f8a5f3a240220018b38d7dcd314e734c9276bd6fb40f673325bc4baa144c800d2f2f02db2765c012103d2e15674941bad4a996372cb87e1856d3652606d98562fe39c5e9e7e413f210502483045022100d12b852d85dcd961d2f5f4ab660654df6eedcc794c0c33ce5cc309ffb5fce58d022067338a8e0e1725c197fb1a88af59f51e44e4255b20167c8684031c05d1f2592a01210223b72beef0965d10be0778efecd61fcac6f79a4ea169393380734464f84f2ab30000000001030401000000000000",
+	"70736274ff0100a00200000002ab0949a08c5af7c49b8212f417e2f15ab3f5c33dcf153821a8139f877a5b7be40000000000feffffffab0949a08c5af7c49b8212f417e2f15ab3f5c33dcf153821a8139f877a5b7be40100000000feffffff02603bea0b000000001976a914768a40bbd740cbe81d988e71de2a4d5c71396b1d88ac8e240000000000001976a9146f4620b553fa095e721b9ee0efe9fa039cca459788ac00000000000100df0200000001268171371edff285e937adeea4b37b78000c0566cbb3ad64641713ca42171bf6000000006a473044022070b2245123e6bf474d60c5b50c043d4c691a5d2435f09a34a7662a9dc251790a022001329ca9dacf280bdf30740ec0390422422c81cb45839457aeb76fc12edd95b3012102657d118d3357b8e0f4c2cd46db7b39f6d9c38d9a70abcb9b2de5dc8dbfe4ce31feffffff02d3dff505000000001976a914d0c59903c5bac2868760e90fd521a4665aa7652088ac00e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787b32e13000001012000e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787010416001485d13537f2e265405a34dbafa9e3dda01fb8230800220202ead596687ca806043edc3de116cdf29d5e9257c196cd055cf698c8d02bf24e9910b4a6ba670000008000000080020000800022020394f62be9df19952c5587768aeb7698061ad2c4a25c894f47d8c162b4d7213d0510b4a6ba6700000080010000800200008000",
+	"70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac0000000000010120955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a010104220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681010547522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba67000000800000008004000080220603de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd10b4a6ba670000008000000080050000800000",
+	"70736274ff01003f0200000001ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0000000000ffffffff010000000000000000036a010000000000000a0f0102030405060708090f0102030405060708090a0b0c0d0e0f0000",
+	"70736274ff01003f0200000001ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0000000000ffffffff010000000000000000036a010000000000002206030d097466b7f59162ac4d90bf65f2a31a8bad82fcd22e98138dcf279401939bd104ffffffff0a0f0102030405060708090f0102030405060708090a0b0c0d0e0f0000",
+	"70736274ff01002001000000000100000000000000000d6a0b68656c6c6f20776f726c64000000000000",
+}
+
+// validTaprootVectors are the valid taproot PSBTs of Bitcoin Core, in base64.
+var validTaprootVectors = []string{
+	"cHNidP8BAHUCAAAAASaBcTce3/KF6Tet7qSze3gADAVmy7OtZGQXE8pCFxv2AAAAAAD+////AtPf9QUAAAAAGXapFNDFmQPFusKGh2DpD9UhpGZap2UgiKwA4fUFAAAAABepFDVF5uM7gyxHBQ8k0+65PJwDlIvHh7MuEwAAAQD9pQEBAAAAAAECiaPHHqtNIOA3G7ukzGmPopXJRjr6Ljl/hTPMti+VZ+UBAAAAFxYAFL4Y0VKpsBIDna89p95PUzSe7LmF/////4b4qkOnHf8USIk6UwpyN+9rRgi7st0tAXHmOuxqSJC0AQAAABcWABT+Pp7xp0XpdNkCxDVZQ6vLNL1TU/////8CAMLrCwAAAAAZdqkUhc/xCX/Z4Ai7NK9wnGIZeziXikiIrHL++E4sAAAAF6kUM5cluiHv1irHU6m80GfWx6ajnQWHAkcwRAIgJxK+IuAnDzlPVoMR3HyppolwuAJf3TskAinwf4pfOiQCIAGLONfc0xTnNMkna9b7QPZzMlvEuqFEyADS8vAtsnZcASED0uFWdJQbrUqZY3LLh+GFbTZSYG2YVi/jnF6efkE/IQUCSDBFAiEA0SuFLYXc2WHS9fSrZgZU327tzHlMDDPOXMMJ/7X85Y0CIGczio4OFyXBl/saiK9Z9R5E5CVbIBZ8hoQDHAXR8lkqASECI7cr7vCWXRC+B3jv7NYfysb3mk6haTkzgHNEZPhPKrMAAAAAIQ12pWrO2RXSUT3NhMLDeLLoqlzWMrW3HKLyrFsOOmSb2wIBAiENnBLP3ATHRYTXh6w9I3chMsGFJLx6so3sQhm4/FtCX3ABAQAAAA==",
+	"cHNidP8BAFICAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////AUjmBSoBAAAAFgAUdo4e60z0IIZgM/gKzv8PlyB0SWkAAAAAAAEBKwDyBSoBAAAAIlEgWiws9bUs8x+DrS6Npj/wMYPs2PYJx1EK6KSOA5EKB1chFv40kGTJjW4qhT+jybEr2LMEoZwZXGDvp+4jkwRtP6IyGQB3Ky2nVgAAgAEAAIAAAACAAQAAAAAAAAABFyD+NJBkyY1uKoU/o8mxK9izBKGcGVxg76fuI5MEbT+iMgAiAgNrdyptt02HU8mKgnlY3mx4qzMSEJ830+AwRIQkLs5z2Bh3Ky2nVAAAgAEAAIAAAACAAAAAAAAAAAAA",
+	"cHNidP8BAFICAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////AUjmBSoBAAAAFgAUdo4e60z0IIZgM/gKzv8PlyB0SWkAAAAAAAEBKwDyBSoBAAAAIlEgWiws9bUs8x+DrS6Npj/wMYPs2PYJx1EK6KSOA5EKB1cBE0C7U+yRe62dkGrxuocYHEi4as5aritTYFpyXKdGJWMUdvxvW67a9PLuD0d/NvWPOXDVuCc7fkl7l68uPxJcl680IRb+NJBkyY1uKoU/o8mxK9izBKGcGVxg76fuI5MEbT+iMhkAdystp1YAAIABAACAAAAAgAEAAAAAAAAAARcg/jSQZMmNbiqFP6PJsSvYswShnBlcYO+n7iOTBG0/ojIAIgIDa3cqbbdNh1PJioJ5WN5seKszEhCfN9PgMESEJC7Oc9gYdystp1QAAIABAACAAAAAgAAAAAAAAAAAAA==",
+	"cHNidP8BAF4CAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////AUjmBSoBAAAAIlEgg2mORYxmZOFZXXXaJZfeHiLul9eY5wbEwKS1qYI810MAAAAAAAEBKwDyBSoBAAAAIlEgWiws9bUs8x+DrS6Npj/wMYPs2PYJx1EK6KSOA5EKB1chFv40kGTJjW4qhT+jybEr2LMEoZwZXGDvp+4jkwRtP6IyGQB3Ky2nVgAAgAEAAIAAAACAAQAAAAAAAAABFyD+NJBkyY1uKoU/o8mxK9izBKGcGVxg76fuI5MEbT+iMgABBSARJNp67JLM0GyVRWJkf0N7E4uVchqEvivyJ2u92rPmcSEHESTaeuySzNBslUViZH9DexOLlXIahL4r8idrvdqz5nEZAHcrLadWAACAAQAAgAAAAIAAAAAABQAAAAA=",
+	"cHNidP8BAF4CAAAAAZvUh2UjC/mnLmYgAflyVW5U8Mb5f+tWvLVgDYF/aZUmAQAAAAD/////AUjmBSoBAAAAIlEgg2mORYxmZOFZXXXaJZfeHiLul9eY5wbEwKS1qYI810MAAAAAAAEBKwDyBSoBAAAAIlEgwiR++/2SrEf29AuNQtFpF1oZ+p+hDkol1/NetN2FtpJiFcFQkpt0waBJVLeLS2A16XpeB4paDyjsltVHv+6azoA6wG99YgWelJehpKJnVp2YdtpgEBr/OONSm5uTnOf5GulwEV8uSQr3zEXE94UR82BXzlxaXFYyWin7RN/CA/NW4fgjICyxOsaCSN6AaqajZZzzwD62gh0JyBFKToaP696GW7bSrMBCFcFQkpt0waBJVLeLS2A16XpeB4paDyjsltVHv+6azoA6wJfG5v6l/3FP9XJEmZkIEOQG6YqhD1v35fZ4S8HQqabOIyBDILC/FvARtT6nvmFZJKp/J+XSmtIOoRVdhIZ2w7rRsqzAYhXBUJKbdMGgSVS3i0tgNel6XgeKWg8o7JbVR7/ums6AOsDNlw4V9T/AyC+VD9Vg/6kZt2FyvgFzaKiZE68HT0ALCRFfLkkK98xFxPeFEfNgV85cWlxWMlop+0TfwgPzVuH4IyD6D3o87zsdDAps59JuF62gsuXJLRnvrUi0GFnLikUcqazAIRYssTrGgkjegGqmo2Wc88A+toIdCcgRSk6Gj+vehlu20jkBzZcOFfU/wMgvlQ/VYP+pGbdhcr4Bc2iomROvB09ACwl3Ky2nVgAAgAEAAIACAACAAAAAAAAAAAAhFkMgsL8W8BG1Pqe+YVkkqn8n5dKa0g6hFV2EhnbDutGyOQERXy5JCvfMRcT3hRHzYFfOXFpcVjJaKftE38ID81bh+HcrLadWAACAAQAAgAEAAIAAAAAAAAAAACEWUJKbdMGgSVS3i0tgNel6XgeKWg8o7JbVR7/ums6AOsAFAHxGHl0hFvoPejzvOx0MCmzn0m4XraCy5cktGe+tSLQYWcuKRRypOQFvfWIFnpSXoaSiZ1admHbaYBAa/zjjUpubk5zn+RrpcHcrLadWAACAAQAAgAMAAIAAAAAAAAAAAAEXIFCSm3TBoElUt4tLYDXpel4HiloPKOyW1Ue/7prOgDrAARgg8DYuL3Wm9CClvePrIh2WrmcgzyX4GJDJWx13WstRXmUAAQUgESTaeuySzNBslUViZH9DexOLlXIahL4r8idrvdqz5nEhBxEk2nrskszQbJVFYmR/Q3sTi5VyGoS+K/Ina73as+ZxGQB3Ky2nVgAAgAEAAIAAAACAAAAAAAUAAAAA",
+	"cHNidP8BAF4CAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////AUjmBSoBAAAAIlEgCoy9yG3hzhwPnK6yLW33ztNoP+Qj4F0eQCqHk0HW9vUAAAAAAAEBKwDyBSoBAAAAIlEgWiws9bUs8x+DrS6Npj/wMYPs2PYJx1EK6KSOA5EKB1chFv40kGTJjW4qhT+jybEr2LMEoZwZXGDvp+4jkwRtP6IyGQB3Ky2nVgAAgAEAAIAAAACAAQAAAAAAAAABFyD+NJBkyY1uKoU/o8mxK9izBKGcGVxg76fuI5MEbT+iMgABBSBQkpt0waBJVLeLS2A16XpeB4paDyjsltVHv+6azoA6wAEGbwLAIiBzblcpAP4SUliaIUPI88efcaBBLSNTr3VelwHHgmlKAqwCwCIgYxxfO1gyuPvev7GXBM7rMjwh9A96JPQ9aO8MwmsSWWmsAcAiIET6pJoDON5IjI3//s37bzKfOAvVZu8gyN9tgT6rHEJzrCEHRPqkmgM43kiMjf/+zftvMp84C9Vm7yDI322BPqscQnM5AfBreYuSoQ7ZqdC7/Trxc6U7FhfaOkFZygCCFs2Fay4Odystp1YAAIABAACAAQAAgAAAAAADAAAAIQdQkpt0waBJVLeLS2A16XpeB4paDyjsltVHv+6azoA6wAUAfEYeXSEHYxxfO1gyuPvev7GXBM7rMjwh9A96JPQ9aO8MwmsSWWk5ARis5AmIl4Xg6nDO67jhyokqenjq7eDy4pbPQ1lhqPTKdystp1YAAIABAACAAgAAgAAAAAADAAAAIQdzblcpAP4SUliaIUPI88efcaBBLSNTr3VelwHHgmlKAjkBKaW0kVCQFi11mv0/4Pk/ozJgVtC0CIy5M8rngmy42Cx3Ky2nVgAAgAEAAIADAACAAAAAAAMAAAAA",
+	"cHNidP8BAF4CAAAAAZvUh2UjC/mnLmYgAflyVW5U8Mb5f+tWvLVgDYF/aZUmAQAAAAD/////AUjmBSoBAAAAIlEgg2mORYxmZOFZXXXaJZfeHiLul9eY5wbEwKS1qYI810MAAAAAAAEBKwDyBSoBAAAAIlEgwiR++/2SrEf29AuNQtFpF1oZ+p+hDkol1/NetN2FtpJBFCyxOsaCSN6AaqajZZzzwD62gh0JyBFKToaP696GW7bSzZcOFfU/wMgvlQ/VYP+pGbdhcr4Bc2iomROvB09ACwlAv4GNl1fW/+tTi6BX+0wfxOD17xhudlvrVkeR4Cr1/T1eJVHU404z2G8na4LJnHmu0/A5Wgge/NLMLGXdfmk9eUEUQyCwvxbwEbU+p75hWSSqfyfl0prSDqEVXYSGdsO60bIRXy5JCvfMRcT3hRHzYFfOXFpcVjJaKftE38ID81bh+EDh8atvq/omsjbyGDNxncHUKKt2jYD5H5mI2KvvR7+4Y7sfKlKfdowV8AzjTsKDzcB+iPhCi+KPbvZAQ8MpEYEaQRT6D3o87zsdDAps59JuF62gsuXJLRnvrUi0GFnLikUcqW99YgWelJehpKJnVp2YdtpgEBr/OONSm5uTnOf5GulwQOwfA3kgZGHIM0IoVCMyZwirAx8NpKJT7kWq+luMkgNNi2BUkPjNE+APmJmJuX4hX6o28S3uNpPS2szzeBwXV/ZiFcFQkpt0waBJVLeLS2A16XpeB4paDyjsltVHv+6azoA6wG99YgWelJehpKJnVp2YdtpgEBr/OONSm5uTnOf5GulwEV8uSQr3zEXE94UR82BXzlxaXFYyWin7RN/CA/NW4fgjICyxOsaCSN6AaqajZZzzwD62gh0JyBFKToaP696GW7bSrMBCFcFQkpt0waBJVLeLS2A16XpeB4paDyjsltVHv+6azoA6wJfG5v6l/3FP9XJEmZkIEOQG6YqhD1v35fZ4S8HQqabOIyBDILC/FvARtT6nvmFZJKp/J+XSmtIOoRVdhIZ2w7rRsqzAYhXBUJKbdMGgSVS3i0tgNel6XgeKWg8o7JbVR7/ums6AOsDNlw4V9T/AyC+VD9Vg/6kZt2FyvgFzaKiZE68HT0ALCRFfLkkK98xFxPeFEfNgV85cWlxWMlop+0TfwgPzVuH4IyD6D3o87zsdDAps59JuF62gsuXJLRnvrUi0GFnLikUcqazAIRYssTrGgkjegGqmo2Wc88A+toIdCcgRSk6Gj+vehlu20jkBzZcOFfU/wMgvlQ/VYP+pGbdhcr4Bc2iomROvB09ACwl3Ky2nVgAAgAEAAIACAACAAAAAAAAAAAAhFkMgsL8W8BG1Pqe+YVkkqn8n5dKa0g6hFV2EhnbDutGyOQERXy5JCvfMRcT3hRHzYFfOXFpcVjJaKftE38ID81bh+HcrLadWAACAAQAAgAEAAIAAAAAAAAAAACEWUJKbdMGgSVS3i0tgNel6XgeKWg8o7JbVR7/ums6AOsAFAHxGHl0hFvoPejzvOx0MCmzn0m4XraCy5cktGe+tSLQYWcuKRRypOQFvfWIFnpSXoaSiZ1admHbaYBAa/zjjUpubk5zn+RrpcHcrLadWAACAAQAAgAMAAIAAAAAAAAAAAAEXIFCSm3TBoElUt4tLYDXpel4HiloPKOyW1Ue/7prOgDrAARgg8DYuL3Wm9CClvePrIh2WrmcgzyX4GJDJWx13WstRXmUAAQUgESTaeuySzNBslUViZH9DexOLlXIahL4r8idrvdqz5nEhBxEk2nrskszQbJVFYmR/Q3sTi5VyGoS+K/Ina73as+ZxGQB3Ky2nVgAAgAEAAIAAAACAAAAAAAUAAAAA",
+}
+
+// invalidVectors are the invalid BIP174 test vectors, in hex.
+var invalidVectors = []struct {
+	reason string
+	psbt   string
+}{
+	{"wire format, not PSBT format", "0200000001268171371edff285e937adeea4b37b78000c0566cbb3ad64641713ca42171bf6000000006a473044022070b2245123e6bf474d60c5b50c043d4c691a5d2435f09a34a7662a9dc251790a022001329ca9dacf280bdf30740ec0390422422c81cb45839457aeb76fc12edd95b3012102657d118d3357b8e0f4c2cd46db7b39f6d9c38d9a70abcb9b2de5dc8dbfe4ce31feffffff02d3dff505000000001976a914d0c59903c5bac2868760e90fd521a4665aa7652088ac00e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787b32e1300"},
+	{"missing outputs", "70736274ff0100750200000001268171371edff285e937adeea4b37b78000c0566cbb3ad64641713ca42171bf60000000000feffffff02d3dff505000000001976a914d0c59903c5bac2868760e90fd521a4665aa7652088ac00e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787b32e1300000100fda5010100000000010289a3c71eab4d20e0371bbba4cc698fa295c9463afa2e397f8533ccb62f9567e50100000017160014be18d152a9b012039daf3da7de4f53349eecb985ffffffff86f8aa43a71dff1448893a530a7237ef6b4608bbb2dd2d0171e63aec6a4890b40100000017160014fe3e9ef1a745e974d902c4355943abcb34bd5353ffffffff0200c2eb0b000000001976a91485cff1097fd9e008bb34af709c62197b38978a4888ac72fef84e2c00000017a914339725ba21efd62ac753a9bcd067d6c7a6a39d05870247304402202712be22e0270f394f568311dc7ca9a68970b8025fdd3b240229f07f8a5f3a240220018b38d7dcd314e734c9276bd6fb40f673325bc4baa144c800d2f2f02db2765c012103d2e15674941bad4a996372cb87e1856d3652606d98562fe39c5e9e7e413f210502483045022100d12b852d85dcd961d2f5f4ab660654df6eedcc794c0c33ce5cc309ffb5fce58d022067338a8e0e1725c197fb1a88af59f51e44e4255b20167c8684031c05d1f2592a01210223b72beef0965d10be0778efecd61fcac6f79a4ea169393380734464f84f2ab30000000000"},
+	{"filled in scriptSig in unsigned tx", "70736274ff0100fd0a010200000002ab0949a08c5af7c49b8212f417e2f15ab3f5c33dcf153821a8139f877a5b7be4000000006a47304402204759661797c01b036b25928948686218347d89864b719e1f7fcf57d1e511658702205309eabf56aa4d8891ffd111fdf1336f3a29da866d7f8486d75546ceedaf93190121035cdc61fc7ba971c0b501a646a2a83b102cb43881217ca682dc86e2d73fa88292feffffffab0949a08c5af7c49b8212f417e2f15ab3f5c33dcf153821a8139f877a5b7be40100000000feffffff02603bea0b000000001976a914768a40bbd740cbe81d988e71de2a4d5c71396b1d88ac8e240000000000001976a9146f4620b553fa095e721b9ee0efe9fa039cca459788ac00000000000001012000e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787010416001485d13537f2e265405a34dbafa9e3dda01fb82308000000"},
+	{"no unsigned tx", "70736274ff000100fda5010100000000010289a3c71eab4d20e0371bbba4cc698fa295c9463afa2e397f8533ccb62f9567e50100000017160014be18d152a9b012039daf3da7de4f53349eecb985ffffffff86f8aa43a71dff1448893a530a7237ef6b4608bbb2dd2d0171e63aec6a4890b40100000017160014fe3e9ef1a745e974d902c4355943abcb34bd5353ffffffff0200c2eb0b000000001976a91485cff1097fd9e008bb34af709c62197b38978a4888ac72fef84e2c00000017a914339725ba21efd62ac753a9bcd067d6c7a6a39d05870247304402202712be22e0270f394f568311dc7ca9a68970b8025fdd3b240229f07f8a5f3a240220018b38d7dcd314e734c9276bd6fb40f673325bc4baa144c800d2f2f02db2765c012103d2e15674941bad4a996372cb87e1856d3652606d98562fe39c5e9e7e413f210502483045022100d12b852d85dcd961d2f5f4ab660654df6eedcc794c0c33ce5cc309ffb5fce58d022067338a8e0e1725c197fb1a88af59f51e44e4255b20167c8684031c05d1f2592a01210223b72beef0965d10be0778efecd61fcac6f79a4ea169393380734464f84f2ab30000000000"},
+	{"duplicate keys in an input", "70736274ff0100750200000001268171371edff285e937adeea4b37b78000c0566cbb3ad64641713ca42171bf60000000000feffffff02d3dff505000000001976a914d0c59903c5bac2868760e90fd521a4665aa7652088ac00e1f5050000000017a9143545e6e33b832c47050f24d3eeb93c9c03948bc787b32e1300000100fda5010100000000010289a3c71eab4d20e0371bbba4cc698fa295c9463afa2e397f8533ccb62f9567e50100000017160014be18d152a9b012039daf3da7de4f53349eecb985ffffffff86f8aa43a71dff1448893a530a7237ef6b4608bbb2dd2d0171e63aec6a4890b40100000017160014fe3e9ef1a745e974d902c4355943abcb34bd5353ffffffff0200c2eb0b000000001976a91485cff1097fd9e008bb34af709c62197b38978a4888ac72fef84e2c00000017a914339725ba21efd62ac753a9bcd067d6c7a6a39d05870247304402202712be22e0270f394f568311dc7ca9a68970b8025fdd3b240229f07f8a5f3a240220018b38d7dcd314e734c9276bd6fb40f673325bc4baa144c800d2f2f02db2765c012103d2e15674941bad4a996372cb87e1856d3652606d98562fe39c5e9e7e413f210502483045022100d12b852d85dcd961d2f5f4ab660654df6eedcc794c0c33ce5cc309ffb5fce58d022067338a8e0e1725c197fb1a88af59f51e44e4255b20167c8684031c05d1f2592a01210223b72beef0965d10be0778efecd61fcac6f79a4ea169393380734464f84f2ab30000000001003f0200000001ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff0000000000ffffffff010000000000000000036a010000000000000000"},
+	{"invalid global transaction typed key", "70736274ff020001550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac0000000000010120955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a010104220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681010547522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba67000000800000008004000080220603de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd10b4a6ba670000008000000080050000800000"},
+	{"invalid input witness utxo typed key", "70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac000000000002010020955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a010104220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681010547522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba67000000800000008004000080220603de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd10b4a6ba670000008000000080050000800000"},
+	{"invalid pubkey length for input partial signature typed key", "70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac0000000000010120955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87210203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd46304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a010104220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681010547522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba67000000800000008004000080220603de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd10b4a6ba670000008000000080050000800000"},
+	{"invalid redeemscript typed key", "70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac0000000000010120955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a01020400220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681010547522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba67000000800000008004000080220603de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd10b4a6ba670000008000000080050000800000"},
+	{"invalid witness script typed key", "70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac0000000000010120955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a010104220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d568102050047522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba67000000800000008004000080220603de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd10b4a6ba670000008000000080050000800000"},
+	{"invalid bip32 typed key", "70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac0000000000010120955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a010104220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681010547522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae210603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd10b4a6ba67000000800000008004000080220603de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd10b4a6ba670000008000000080050000800000"},
+	{"invalid non-witness utxo typed key", "70736274ff01009a020000000258e87a21b56daf0c23be8e7070456c336f7cbaa5c8757924f545887bb2abdd750000000000ffffffff838d0427d0ec650a68aa46bb0b098aea4422c071b2ca78352a077959d07cea1d0100000000ffffffff0270aaf00800000000160014d85c2b71d0060b09c9886aeb815e50991dda124d00e1f5050000000016001400aea9a2e5f0f876a588df5546e8742d1d87008f0000000000020000bb0200000001aad73931018bd25f84ae400b68848be09db706eac2ac18298babee71ab656f8b0000000048473044022058f6fc7c6a33e1b31548d481c826c015bd30135aad42cd67790dab66d2ad243b02204a1ced2604c6735b6393e5b41691dd78b00f0c5942fb9f751856faa938157dba01feffffff0280f0fa020000000017a9140fb9463421696b82c833af241c78c17ddbde493487d0f20a270100000017a91429ca74f8a08f81999428185c97b5d852e4063f6187650000000107da00473044022074018ad4180097b873323c0015720b3684cc8123891048e7dbcd9b55ad679c99022073d369b740e3eb53dcefa33823c8070514ca55a7dd9544f157c167913261118c01483045022100f61038b308dc1da865a34852746f015772934208c6d24454393cd99bdf2217770220056e675a675a6d0a02b85b14e5e29074d8a25a9b5760bea2816f661910a006ea01475221029583bf39ae0a609747ad199addd634fa6108559d6c5cd39b4c2183f1ab96e07f2102dab61ff49a14db6a7d02b0cd1fbb78fc4b18312b5b4e54dae4dba2fbfef536d752ae0001012000c2eb0b0000000017a914b7f5faf40e3d40a5a459b1db3535f2b72fa921e8870107232200208c2353173743b595dfb4a07b72ba8e42e3797da74e87fe7d9d7497e3b20289030108da0400473044022062eb7a556107a7c73f45ac4ab5a1dddf6f7075fb1275969a7f383efff784bcb202200c05dbb7470dbf2f08557dd356c7325c1ed30913e996cd3840945db12228da5f01473044022065f45ba5998b59a27ffe1a7bed016af1f1f90d54b3aa8f7450aa5f56a25103bd02207f724703ad1edb96680b284b56d4ffcb88f7fb759eabbe08aa30f29b851383d20147522103089dc10c7ac6db54f91329af617333db388cead0c231f723379d1b99030b02dc21023add904f3d6dcf59ddb906b0dee23529b7ffb9ed50e5e86151926860221f0e7352ae00220203a9a4c37f5996d3aa25dbac6b570af0650394492942460b354753ed9eeca5877110d90c6a4f000000800000008004000080002202027f6399757d2eff55a136ad02c684b1838b6556e5f1b6b34282a94b6b5005109610d90c6a4f00000080000000800500008000"},
+	{"invalid final scriptsig typed key", "70736274ff01009a020000000258e87a21b56daf0c23be8e7070456c336f7cbaa5c8757924f545887bb2abdd750000000000ffffffff838d0427d0ec650a68aa46bb0b098aea4422c071b2ca78352a077959d07cea1d0100000000ffffffff0270aaf00800000000160014d85c2b71d0060b09c9886aeb815e50991dda124d00e1f5050000000016001400aea9a2e5f0f876a588df5546e8742d1d87008f00000000000100bb0200000001aad73931018bd25f84ae400b68848be09db706eac2ac18298babee71ab656f8b0000000048473044022058f6fc7c6a33e1b31548d481c826c015bd30135aad42cd67790dab66d2ad243b02204a1ced2604c6735b6393e5b41691dd78b00f0c5942fb9f751856faa938157dba01feffffff0280f0fa020000000017a9140fb9463421696b82c833af241c78c17ddbde493487d0f20a270100000017a91429ca74f8a08f81999428185c97b5d852e4063f618765000000020700da00473044022074018ad4180097b873323c0015720b3684cc8123891048e7dbcd9b55ad679c99022073d369b740e3eb53dcefa33823c8070514ca55a7dd9544f157c167913261118c01483045022100f61038b308dc1da865a34852746f015772934208c6d24454393cd99bdf2217770220056e675a675a6d0a02b85b14e5e29074d8a25a9b5760bea2816f661910a006ea01475221029583bf39ae0a609747ad199addd634fa6108559d6c5cd39b4c2183f1ab96e07f2102dab61ff49a14db6a7d02b0cd1fbb78fc4b18312b5b4e54dae4dba2fbfef536d752ae0001012000c2eb0b0000000017a914b7f5faf40e3d40a5a459b1db3535f2b72fa921e8870107232200208c2353173743b595dfb4a07b72ba8e42e3797da74e87fe7d9d7497e3b20289030108da0400473044022062eb7a556107a7c73f45ac4ab5a1dddf6f7075fb1275969a7f383efff784bcb202200c05dbb7470dbf2f08557dd356c7325c1ed30913e996cd3840945db12228da5f01473044022065f45ba5998b59a27ffe1a7bed016af1f1f90d54b3aa8f7450aa5f56a25103bd02207f724703ad1edb96680b284b56d4ffcb88f7fb759eabbe08aa30f29b851383d20147522103089dc10c7ac6db54f91329af617333db388cead0c231f723379d1b99030b02dc21023add904f3d6dcf59ddb906b0dee23529b7ffb9ed50e5e86151926860221f0e7352ae00220203a9a4c37f5996d3aa25dbac6b570af0650394492942460b354753ed9eeca5877110d90c6a4f000000800000008004000080002202027f6399757d2eff55a136ad02c684b1838b6556e5f1b6b34282a94b6b5005109610d90c6a4f00000080000000800500008000"},
+	{"invalid final script witness typed key", "70736274ff01009a020000000258e87a21b56daf0c23be8e7070456c336f7cbaa5c8757924f545887bb2abdd750000000000ffffffff838d0427d0ec650a68aa46bb0b098aea4422c071b2ca78352a077959d07cea1d0100000000ffffffff0270aaf00800000000160014d85c2b71d0060b09c9886aeb815e50991dda124d00e1f5050000000016001400aea9a2e5f0f876a588df5546e8742d1d87008f00000000000100bb0200000001aad73931018bd25f84ae400b68848be09db706eac2ac18298babee71ab656f8b0000000048473044022058f6fc7c6a33e1b31548d481c826c015bd30135aad42cd67790dab66d2ad243b02204a1ced2604c6735b6393e5b41691dd78b00f0c5942fb9f751856faa938157dba01feffffff0280f0fa020000000017a9140fb9463421696b82c833af241c78c17ddbde493487d0f20a270100000017a91429ca74f8a08f81999428185c97b5d852e4063f6187650000000107da00473044022074018ad4180097b873323c0015720b3684cc8123891048e7dbcd9b55ad679c99022073d369b740e3eb53dcefa33823c8070514ca55a7dd9544f157c167913261118c01483045022100f61038b308dc1da865a34852746f015772934208c6d24454393cd99bdf2217770220056e675a675a6d0a02b85b14e5e29074d8a25a9b5760bea2816f661910a006ea01475221029583bf39ae0a609747ad199addd634fa6108559d6c5cd39b4c2183f1ab96e07f2102dab61ff49a14db6a7d02b0cd1fbb78fc4b18312b5b4e54dae4dba2fbfef536d752ae0001012000c2eb0b0000000017a914b7f5faf40e3d40a5a459b1db3535f2b72fa921e8870107232200208c2353173743b595dfb4a07b72ba8e42e3797da74e87fe7d9d7497e3b2028903020800da0400473044022062eb7a556107a7c73f45ac4ab5a1dddf6f7075fb1275969a7f383efff784bcb202200c05dbb7470dbf2f08557dd356c7325c1ed30913e996cd3840945db12228da5f01473044022065f45ba5998b59a27ffe1a7bed016af1f1f90d54b3aa8f7450aa5f56a25103bd02207f724703ad1edb96680b284b56d4ffcb88f7fb759eabbe08aa30f29b851383d20147522103089dc10c7ac6db54f91329af617333db388cead0c231f723379d1b99030b02dc21023add904f3d6dcf59ddb906b0dee23529b7ffb9ed50e5e86151926860221f0e7352ae00220203a9a4c37f5996d3aa25dbac6b570af0650394492942460b354753ed9eeca5877110d90c6a4f000000800000008004000080002202027f6399757d2eff55a136ad02c684b1838b6556e5f1b6b34282a94b6b5005109610d90c6a4f00000080000000800500008000"},
+	{"invalid pubkey in output BIP32 derivation paths typed key", "70736274ff01009a020000000258e87a21b56daf0c23be8e7070456c336f7cbaa5c8757924f545887bb2abdd750000000000ffffffff838d0427d0ec650a68aa46bb0b098aea4422c071b2ca78352a077959d07cea1d0100000000ffffffff0270aaf00800000000160014d85c2b71d0060b09c9886aeb815e50991dda124d00e1f5050000000016001400aea9a2e5f0f876a588df5546e8742d1d87008f00000000000100bb0200000001aad73931018bd25f84ae400b68848be09db706eac2ac18298babee71ab656f8b0000000048473044022058f6fc7c6a33e1b31548d481c826c015bd30135aad42cd67790dab66d2ad243b02204a1ced2604c6735b6393e5b41691dd78b00f0c5942fb9f751856faa938157dba01feffffff0280f0fa020000000017a9140fb9463421696b82c833af241c78c17ddbde493487d0f20a270100000017a91429ca74f8a08f81999428185c97b5d852e4063f6187650000000107da00473044022074018ad4180097b873323c0015720b3684cc8123891048e7dbcd9b55ad679c99022073d369b740e3eb53dcefa33823c8070514ca55a7dd9544f157c167913261118c01483045022100f61038b308dc1da865a34852746f015772934208c6d24454393cd99bdf2217770220056e675a675a6d0a02b85b14e5e29074d8a25a9b5760bea2816f661910a006ea01475221029583bf39ae0a609747ad199addd634fa6108559d6c5cd39b4c2183f1ab96e07f2102dab61ff49a14db6a7d02b0cd1fbb78fc4b18312b5b4e54dae4dba2fbfef536d752ae0001012000c2eb0b0000000017a914b7f5faf40e3d40a5a459b1db3535f2b72fa921e8870107232200208c2353173743b595dfb4a07b72ba8e42e3797da74e87fe7d9d7497e3b20289030108da0400473044022062eb7a556107a7c73f45ac4ab5a1dddf6f7075fb1275969a7f383efff784bcb202200c05dbb7470dbf2f08557dd356c7325c1ed30913e996cd3840945db12228da5f01473044022065f45ba5998b59a27ffe1a7bed016af1f1f90d54b3aa8f7450aa5f56a25103bd02207f724703ad1edb96680b284b56d4ffcb88f7fb759eabbe08aa30f29b851383d20147522103089dc10c7ac6db54f91329af617333db388cead0c231f723379d1b99030b02dc21023add904f3d6dcf59ddb906b0dee23529b7ffb9ed50e5e86151926860221f0e7352ae00210203a9a4c37f5996d3aa25dbac6b570af0650394492942460b354753ed9eeca58710d90c6a4f000000800000008004000080002202027f6399757d2eff55a136ad02c684b1838b6556e5f1b6b34282a94b6b5005109610d90c6a4f00000080000000800500008000"},
+	{"invalid input sighash type typed key", "70736274ff0100730200000001301ae986e516a1ec8ac5b4bc6573d32f83b465e23ad76167d68b38e730b4dbdb0000000000ffffffff02747b01000000000017a91403aa17ae882b5d0d54b25d63104e4ffece7b9ea2876043993b0000000017a914b921b1ba6f722e4bfa83b6557a3139986a42ec8387000000000001011f00ca9a3b00000000160014d2d94b64ae08587eefc8eeb187c601e939f9037c0203000100000000010016001462e9e982fff34dd8239610316b090cd2a3b747cb000100220020876bad832f1d168015ed41232a9ea65a1815d9ef13c0ef8759f64b5b2b278a65010125512103b7ce23a01c5b4bf00a642537cdfabb315b668332867478ef51309d2bd57f8a8751ae00"},
+	{"invalid output redeemscript typed key", "70736274ff0100730200000001301ae986e516a1ec8ac5b4bc6573d32f83b465e23ad76167d68b38e730b4dbdb0000000000ffffffff02747b01000000000017a91403aa17ae882b5d0d54b25d63104e4ffece7b9ea2876043993b0000000017a914b921b1ba6f722e4bfa83b6557a3139986a42ec8387000000000001011f00ca9a3b00000000160014d2d94b64ae08587eefc8eeb187c601e939f9037c0002000016001462e9e982fff34dd8239610316b090cd2a3b747cb000100220020876bad832f1d168015ed41232a9ea65a1815d9ef13c0ef8759f64b5b2b278a65010125512103b7ce23a01c5b4bf00a642537cdfabb315b668332867478ef51309d2bd57f8a8751ae00"},
+	{"invalid output witnessScript typed key", "70736274ff0100730200000001301ae986e516a1ec8ac5b4bc6573d32f83b465e23ad76167d68b38e730b4dbdb0000000000ffffffff02747b01000000000017a91403aa17ae882b5d0d54b25d63104e4ffece7b9ea2876043993b0000000017a914b921b1ba6f722e4bfa83b6557a3139986a42ec8387000000000001011f00ca9a3b00000000160014d2d94b64ae08587eefc8eeb187c601e939f9037c00010016001462e9e982fff34dd8239610316b090cd2a3b747cb000100220020876bad832f1d168015ed41232a9ea65a1815d9ef13c0ef8759f64b5b2b278a6521010025512103b7ce23a01c5b4bf00a642537cdfabb315b668332867478ef51309d2bd57f8a8751ae00"},
+	{"invalid duplicate PartialSig", "70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac0000000000010120955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a01220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a010104220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681010547522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba67000000800000008004000080220603de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd10b4a6ba670000008000000080050000800000"},
+	{"invalid duplicate BIP32 derivation (different derivs, same key)", "70736274ff0100550200000001279a2323a5dfb51fc45f220fa58b0fc13e1e3342792a85d7e36cd6333b5cbc390000000000ffffffff01a05aea0b000000001976a914ffe9c0061097cc3b636f2cb0460fa4fc427d2b4588ac0000000000010120955eea0b0000000017a9146345200f68d189e1adc0df1c4d16ea8f14c0dbeb87220203b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4646304302200424b58effaaa694e1559ea5c93bbfd4a89064224055cdf070b6771469442d07021f5c8eb0fea6516d60b8acb33ad64ede60e8785bfb3aa94b99bdf86151db9a9a010104220020771fd18ad459666dd49f3d564e3dbc42f4c84774e360ada16816a8ed488d5681010547522103b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd462103de55d1e1dac805e3f8a58c1fbf9b94c02f3dbaafe127fefca4995f26f82083bd52ae220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba67000000800000008004000080220603b1341ccba7683b6af4f1238cd6e97e7167d569fac47f1e48d47541844355bd4610b4a6ba670000008000000080050000800000"},
+}
+
+// invalidTaprootVectors are the invalid taproot PSBTs of Bitcoin Core, in
+// base64.
+var invalidTaprootVectors = []struct {
+	reason string
+	psbt   string
+}{
+	{"invalid input internal key length", "cHNidP8BAHECAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////Anh8AQAAAAAAFgAUg6fjS9mf8DpJYu+KGhAbspVGHs5gawQqAQAAABYAFHrDad8bIOAz1hFmI5V7CsSfPFLoAAAAAAABASsA8gUqAQAAACJRIFosLPW1LPMfg60ujaY/8DGD7Nj2CcdRCuikjgORCgdXARchAv40kGTJjW4qhT+jybEr2LMEoZwZXGDvp+4jkwRtP6IyAAAA"},
+	{"invalid input key spend schnorr signature", "cHNidP8BAHECAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////Anh8AQAAAAAAFgAUg6fjS9mf8DpJYu+KGhAbspVGHs5gawQqAQAAABYAFHrDad8bIOAz1hFmI5V7CsSfPFLoAAAAAAABASsA8gUqAQAAACJRIFosLPW1LPMfg60ujaY/8DGD7Nj2CcdRCuikjgORCgdXARM/Fzuz02wHSvtxb+xjB6BpouRQuZXzyCeFlFq43w4kJg3NcDsMvzTeOZGEqUgawrNYbbZgHwJqd/fkk4SBvDR1AAAA"},
+	{"invalid input key spend signature length", "cHNidP8BAHECAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////Anh8AQAAAAAAFgAUg6fjS9mf8DpJYu+KGhAbspVGHs5gawQqAQAAABYAFHrDad8bIOAz1hFmI5V7CsSfPFLoAAAAAAABASsA8gUqAQAAACJRIFosLPW1LPMfg60ujaY/8DGD7Nj2CcdRCuikjgORCgdXARNCFzuz02wHSvtxb+xjB6BpouRQuZXzyCeFlFq43w4kJg3NcDsMvzTeOZGEqUgawrNYbbZgHwJqd/fkk4SBvDR1FwGqAAAA"},
+	{"invalid input x-only pubkey in key", "cHNidP8BAHECAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////Anh8AQAAAAAAFgAUg6fjS9mf8DpJYu+KGhAbspVGHs5gawQqAQAAABYAFHrDad8bIOAz1hFmI5V7CsSfPFLoAAAAAAABASsA8gUqAQAAACJRIFosLPW1LPMfg60ujaY/8DGD7Nj2CcdRCuikjgORCgdXIhYC/jSQZMmNbiqFP6PJsSvYswShnBlcYO+n7iOTBG0/ojIZAHcrLadWAACAAQAAgAAAAIABAAAAAAAAAAAAAA=="},
+	{"invalid output internal key length", "cHNidP8BAH0CAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////Aoh7AQAAAAAAFgAUI4KHHH6EIaAAk/dU2RKB5nWHS59gawQqAQAAACJRIFosLPW1LPMfg60ujaY/8DGD7Nj2CcdRCuikjgORCgdXAAAAAAABASsA8gUqAQAAACJRIFosLPW1LPMfg60ujaY/8DGD7Nj2CcdRCuikjgORCgdXAAABBSEC/jSQZMmNbiqFP6PJsSvYswShnBlcYO+n7iOTBG0/ojIA"},
+	{"invalid output BIP32 derivation x-only pubkey in key", "cHNidP8BAH0CAAAAASd0Srq/MCf+DWzyOpbu4u+xiO9SMBlUWFiD5ptmJLJCAAAAAAD/////Aoh7AQAAAAAAFgAUI4KHHH6EIaAAk/dU2RKB5nWHS59gawQqAQAAACJRIFosLPW1LPMfg60ujaY/8DGD7Nj2CcdRCuikjgORCgdXAAAAAAABASsA8gUqAQAAACJRIFosLPW1LPMfg60ujaY/8DGD7Nj2CcdRCuikjgORCgdXAAAiBwL+NJBkyY1uKoU/o8mxK9izBKGcGVxg76fuI5MEbT+iMhkAdystp1YAAIABAACAAAAAgAEAAAAAAAAAAA=="},
+	{"invalid input script spend signature key length", "cHNidP8BAF4CAAAAAZvUh2UjC/mnLmYgAflyVW5U8Mb5f+tWvLVgDYF/aZUmAQAAAAD/////AUjmBSoBAAAAIlEgAw2k/OT32yjCyylRYx4ANxOFZZf+ljiCy1AOaBEsymMAAAAAAAEBKwDyBSoBAAAAIlEgwiR++/2SrEf29AuNQtFpF1oZ+p+hDkol1/NetN2FtpJCFAIssTrGgkjegGqmo2Wc88A+toIdCcgRSk6Gj+vehlu20s2XDhX1P8DIL5UP1WD/qRm3YXK+AXNoqJkTrwdPQAsJQIl1aqNznMxonsD886NgvjLMC1mxbpOh6LtGBXJrLKej/3BsQXZkljKyzGjh+RK4pXjjcZzncQiFx6lm9JvNQ8sAAA=="},
+	{"invalid input script spend signature length", "cHNidP8BAF4CAAAAAZvUh2UjC/mnLmYgAflyVW5U8Mb5f+tWvLVgDYF/aZUmAQAAAAD/////AUjmBSoBAAAAIlEgAw2k/OT32yjCyylRYx4ANxOFZZf+ljiCy1AOaBEsymMAAAAAAAEBKwDyBSoBAAAAIlEgwiR++/2SrEf29AuNQtFpF1oZ+p+hDkol1/NetN2FtpJBFCyxOsaCSN6AaqajZZzzwD62gh0JyBFKToaP696GW7bSzZcOFfU/wMgvlQ/VYP+pGbdhcr4Bc2iomROvB09ACwlCiXVqo3OczGiewPzzo2C+MswLWbFuk6Hou0YFcmssp6P/cGxBdmSWMrLMaOH5ErileONxnOdxCIXHqWb0m81DywEBAAA="},
+	{"invalid encoding of base64 stream", "cHNidP8BAF4CAAAAAZvUh2UjC/mnLmYgAflyVW5U8Mb5f+tWvLVgDYF/aZUmAQAAAAD/////AUjmBSoBAAAAIlEgAw2k/OT32yjCyylRYx4ANxOFZZf+ljiCy1AOaBEsymMAAAAAAAEBKwDyBSoBAAAAIlEgwiR++/2SrEf29AuNQtFpF1oZ+p+hDkol1/NetN2FtpJBFCyxOsaCSN6AaqajZZzzwD62gh0JyBFKToaP696GW7bSzZcOFfU/wMgvlQ/VYP+pGbdhcr4Bc2iomROvB09ACwk5iXVqo3OczGiewPzzo2C+MswLWbFuk6Hou0YFcmssp6P/cGxBdmSWMrLMaOH5ErileONxnOdxCIXHqWb0m81DywAA"},
+	{"invalid input leaf script type control block", "cHNidP8BAF4CAAAAAZvUh2UjC/mnLmYgAflyVW5U8Mb5f+tWvLVgDYF/aZUmAQAAAAD/////AUjmBSoBAAAAIlEgAw2k/OT32yjCyylRYx4ANxOFZZf+ljiCy1AOaBEsymMAAAAAAAEBKwDyBSoBAAAAIlEgwiR++/2SrEf29AuNQtFpF1oZ+p+hDkol1/NetN2FtpJjFcFQkpt0waBJVLeLS2A16XpeB4paDyjsltVHv+6azoA6wG99YgWelJehpKJnVp2YdtpgEBr/OONSm5uTnOf5GulwEV8uSQr3zEXE94UR82BXzlxaXFYyWin7RN/CA/NW4fgAIyAssTrGgkjegGqmo2Wc88A+toIdCcgRSk6Gj+vehlu20qzAAAA="},
+	{"invalid input leaf script type control block", "cHNidP8BAF4CAAAAAZvUh2UjC/mnLmYgAflyVW5U8Mb5f+tWvLVgDYF/aZUmAQAAAAD/////AUjmBSoBAAAAIlEgAw2k/OT32yjCyylRYx4ANxOFZZf+ljiCy1AOaBEsymMAAAAAAAEBKwDyBSoBAAAAIlEgwiR++/2SrEf29AuNQtFpF1oZ+p+hDkol1/NetN2FtpJhFcFQkpt0waBJVLeLS2A16XpeB4paDyjsltVHv+6azoA6wG99YgWelJehpKJnVp2YdtpgEBr/OONSm5uTnOf5GulwEV8uSQr3zEXE94UR82BXzlxaXFYyWin7RN/CA/NW4SMgLLE6xoJI3oBqpqNlnPPAPraCHQnIEUpOho/r3oZbttKswAAA"},
+}
+
+func TestDeserializeValidVectors(t *testing.T) {
+	for i, vector := range validVectors {
+		data, _ := hex.DecodeString(vector)
+		packet, err := Deserialize(data)
+		if err != nil {
+			t.Errorf("vector %d: %v", i, err)
+			continue
+		}
+		serialized, err := packet.Serialize()
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if !bytes.Equal(serialized, data) {
+			t.Errorf("vector %d: round trip gives %x", i, serialized)
+		}
+	}
+	for i, vector := range validTaprootVectors {
+		packet, err := B64Deserialize(vector)
+		if err != nil {
+			t.Errorf("taproot vector %d: %v", i, err)
+			continue
+		}
+		serialized, err := packet.B64Serialize()
+		if err != nil {
+			t.Fatalf("taproot vector %d: %v", i, err)
+		}
+		if serialized != vector {
+			t.Errorf("taproot vector %d: round trip gives %s", i, serialized)
+		}
+	}
+}
+
+func TestDeserializeInvalidVectors(t *testing.T) {
+	for _, vector := range invalidVectors {
+		data, _ := hex.DecodeString(vector.psbt)
+		if _, err := Deserialize(data); err == nil {
+			t.Errorf("%s: no error", vector.reason)
+		}
+	}
+	for _, vector := range invalidTaprootVectors {
+		if _, err := B64Deserialize(vector.psbt); err == nil {
+			t.Errorf("%s: no error", vector.reason)
+		}
+	}
+}
+
+func TestDeserializeKeyData(t *testing.T) {
+	// Vector 5 has an input key 0x0f with key data, which isn't the
+	// version 2 output index field
+	data, _ := hex.DecodeString(validVectors[5])
+	packet, err := Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknowns := packet.Inputs[0].Unknowns
+	if len(unknowns) != 1 || unknowns[0].Key[0] != inOutputIndex || len(unknowns[0].Key) == 1 {
+		t.Fatalf("unknowns %v", unknowns)
+	}
+
+	data, _ = hex.DecodeString(validVectors[7])
+	packet, err = Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet.Inputs) != 0 || len(packet.Outputs) != 1 || packet.TxVersion != 1 {
+		t.Fatalf("packet %+v", packet)
+	}
+
+	// An explicit SIGHASH type of 0 is kept
+	unsigned := tx.NewTx(2)
+	unsigned.AddInput(tx.NewTxIn(&tx.OutPoint{}))
+	unsigned.AddOutput(tx.NewTxOut(1000, tx.WitnessProgramScript(0, make([]byte, 20))))
+	global := []*Unknown{{Key: []byte{globalUnsignedTx}, Value: unsigned.SerializeNoWitness()}}
+	data = rawPSBT(global, []*Unknown{{Key: []byte{inSigHashType}, Value: uint32Bytes(0)}}, nil)
+	packet, err = Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !packet.Inputs[0].HasSigHashType || packet.Inputs[0].SigHashType != 0 {
+		t.Fatalf("input %+v", packet.Inputs[0])
+	}
+	if serialized, _ := packet.Serialize(); !bytes.Equal(serialized, data) {
+		t.Fatalf("round trip gives %x", serialized)
+	}
+}
+
+// rawPSBT encodes maps of key-value pairs as a PSBT.
+func rawPSBT(maps ...[]*Unknown) []byte {
+	buffer := bytes.NewBuffer(append([]byte{}, magic...))
+	for _, pairs := range maps {
+		writeUnknowns(buffer, pairs)
+		buffer.WriteByte(0x00)
+	}
+	return buffer.Bytes()
+}
+
+// without returns pairs without the one of key type keyType.
+func without(pairs []*Unknown, keyType byte) []*Unknown {
+	var kept []*Unknown
+	for _, pair := range pairs {
+		if pair.Key[0] != keyType {
+			kept = append(kept, pair)
+		}
+	}
+	return kept
+}
+
+func with(pairs []*Unknown, key []byte, value []byte) []*Unknown {
+	return append(append([]*Unknown{}, pairs...), &Unknown{Key: key, Value: value})
+}
+
+func TestDeserializeVersion2(t *testing.T) {
+	previousTxID := bytes.Repeat([]byte{0x11}, tx.HashLength)
+	global := []*Unknown{
+		{Key: []byte{globalTxVersion}, Value: uint32Bytes(2)},
+		{Key: []byte{globalInputCount}, Value: []byte{1}},
+		{Key: []byte{globalOutputCount}, Value: []byte{1}},
+		{Key: []byte{globalVersion}, Value: uint32Bytes(2)},
+	}
+	input := []*Unknown{
+		{Key: []byte{inPreviousTxID}, Value: previousTxID},
+		{Key: []byte{inOutputIndex}, Value: uint32Bytes(1)},
+	}
+	output := []*Unknown{
+		{Key: []byte{outAmount}, Value: uint64Bytes(50000)},
+		{Key: []byte{outScript}, Value: tx.WitnessProgramScript(0, make([]byte, 20))},
+	}
+
+	data := rawPSBT(global, input, output)
+	packet, err := Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.Version != 2 || packet.TxVersion != 2 || len(packet.Inputs) != 1 || len(packet.Outputs) != 1 {
+		t.Fatalf("packet %+v", packet)
+	}
+	in := packet.Inputs[0]
+	if !bytes.Equal(in.PreviousOutPoint.Hash[:], previousTxID) || in.PreviousOutPoint.Index != 1 || in.Sequence != tx.MaxSequence {
+		t.Fatalf("input %+v", in)
+	}
+	if packet.Outputs[0].Amount != 50000 {
+		t.Fatalf("amount %d", packet.Outputs[0].Amount)
+	}
+	serialized, err := packet.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(serialized, data) {
+		t.Fatalf("round trip gives %x", serialized)
+	}
+
+	// The same transaction as version 0
+	packet.Version = 0
+	serialized, err = packet.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v0, err := Deserialize(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, _ := packet.UnsignedTx()
+	v0Unsigned, _ := v0.UnsignedTx()
+	if !bytes.Equal(unsigned.Hash(), v0Unsigned.Hash()) {
+		t.Fatal("version 0 and 2 are different transactions")
+	}
+
+	unsignedTx := []*Unknown{{Key: []byte{globalUnsignedTx}, Value: unsigned.SerializeNoWitness()}}
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"no tx version", rawPSBT(without(global, globalTxVersion), input, output), ErrMissingField},
+		{"no input count", rawPSBT(without(global, globalInputCount), output), ErrMissingField},
+		{"no output count", rawPSBT(without(global, globalOutputCount), input), ErrMissingField},
+		{"unsigned tx", rawPSBT(append(unsignedTx, global...), input, output), ErrFieldNotAllowed},
+		{"no previous txid", rawPSBT(global, without(input, inPreviousTxID), output), ErrMissingField},
+		{"no output index", rawPSBT(global, without(input, inOutputIndex), output), ErrMissingField},
+		{"no amount", rawPSBT(global, input, without(output, outAmount)), ErrMissingField},
+		{"no script", rawPSBT(global, input, without(output, outScript)), ErrMissingField},
+		{"tx version 1", rawPSBT(with(without(global, globalTxVersion), []byte{globalTxVersion}, uint32Bytes(1)), input, output), ErrInvalidTxVersion},
+		{"time lock below threshold", rawPSBT(global, with(input, []byte{inRequiredTimeLockTime}, uint32Bytes(lockTimeThreshold-1)), output), ErrInvalidValue},
+		{"height lock above threshold", rawPSBT(global, with(input, []byte{inRequiredHeightLockTime}, uint32Bytes(lockTimeThreshold)), output), ErrInvalidValue},
+		{"version 1", rawPSBT(with(without(global, globalVersion), []byte{globalVersion}, uint32Bytes(1)), input, output), ErrUnsupportedVersion},
+		{"v0 input count", rawPSBT(with(unsignedTx, []byte{globalInputCount}, []byte{1}), input, output), ErrFieldNotAllowed},
+		{"v0 previous txid", rawPSBT(unsignedTx, with(nil, []byte{inPreviousTxID}, previousTxID), nil), ErrFieldNotAllowed},
+		{"v0 amount", rawPSBT(unsignedTx, nil, with(nil, []byte{outAmount}, uint64Bytes(1))), ErrFieldNotAllowed},
+		{"previous txid key data", rawPSBT(global, with(input, []byte{inPreviousTxID, 0x00}, previousTxID), output), ErrInvalidKey},
+	}
+	for _, test := range tests {
+		if _, err := Deserialize(test.data); err != test.err {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	// Version 2 keys with key data are unknowns of a version 0 PSBT
+	data = rawPSBT(with(unsignedTx, []byte{globalInputCount, 0x00}, []byte{1}),
+		with(nil, []byte{inPreviousTxID, 0x00}, previousTxID), with(nil, []byte{outAmount, 0x00}, uint64Bytes(1)))
+	packet, err = Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet.Unknowns) != 1 || len(packet.Inputs[0].Unknowns) != 1 || len(packet.Outputs[0].Unknowns) != 1 {
+		t.Fatal("key data fields should be unknowns")
+	}
+	if serialized, _ := packet.Serialize(); !bytes.Equal(serialized, data) {
+		t.Fatalf("round trip gives %x", serialized)
+	}
+
+	packet.Version, packet.TxVersion = 2, 1
+	if _, err := packet.Serialize(); err != ErrInvalidTxVersion {
+		t.Fatalf("got %v, want %v", err, ErrInvalidTxVersion)
+	}
+}
+
+func TestLockTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		heights []uint32
+		times   []uint32
+		want    uint32
+		err     error
+	}{
+		{"fallback", []uint32{0, 0}, []uint32{0, 0}, 10, nil},
+		{"height", []uint32{100, 200}, []uint32{0, 0}, 200, nil},
+		{"conflict", []uint32{100, 0}, []uint32{0, 600000000}, 0, ErrLockTimeConflict},
+		{"height when all allow", []uint32{100, 200}, []uint32{600000000, 600000001}, 200, nil},
+		{"time", []uint32{0, 200}, []uint32{600000000, 600000001}, 600000001, nil},
+		{"unrequired input", []uint32{0, 200}, []uint32{0, 0}, 200, nil},
+	}
+	for _, test := range tests {
+		packet := &Packet{Version: 2, TxVersion: 2, LockTime: 10}
+		for i := range test.heights {
+			packet.Inputs = append(packet.Inputs, &Input{
+				RequiredHeightLockTime: test.heights[i],
+				RequiredTimeLockTime:   test.times[i],
+			})
+		}
+		lockTime, err := packet.lockTime()
+		if err != test.err || lockTime != test.want {
+			t.Errorf("%s: got %d, %v", test.name, lockTime, err)
+		}
+	}
+}
+
+func testMaster(t *testing.T, seed string) *keystore.Key {
+	t.Helper()
+	data, _ := hex.DecodeString(seed)
+	master, err := keystore.NewMasterKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return master
+}
+
+func testKeyScript(t *testing.T, master *keystore.Key, path string, addrType utils.AddressType) []byte {
+	t.Helper()
+	key, err := master.DeriveChildKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := tx.KeyPkScript(key, addrType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+// verifyECDSA checks a signature, with its hash type byte, of the legacy or
+// segwit version 0 sighash of input i.
+func verifyECDSA(t *testing.T, final *tx.Tx, i int, scriptCode []byte, amount int64, witness bool, sigWithType []byte, publicKey []byte) {
+	t.Helper()
+	hashType := tx.SigHashType(sigWithType[len(sigWithType)-1])
+	var sigHash []byte
+	var err error
+	if witness {
+		sigHash, err = final.WitnessV0SigHash(i, scriptCode, amount, hashType)
+	} else {
+		sigHash, err = final.LegacySigHash(i, scriptCode, hashType)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.ParseDERSignature(sigWithType[:len(sigWithType)-1])
+	if err != nil {
+		t.Fatalf("input %d: %v", i, err)
+	}
+	pub, err := crypto.ParsePublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("input %d: %v", i, err)
+	}
+	if !pub.Verify(sigHash, sig) {
+		t.Errorf("input %d: signature doesn't verify", i)
+	}
+}
+
+func multiSigScript(publicKeys ...[]byte) []byte {
+	script := []byte{op1 + byte(len(publicKeys)) - 1}
+	for _, publicKey := range publicKeys {
+		script = append(script, tx.PushData(publicKey)...)
+	}
+	return append(script, op1+byte(len(publicKeys))-1, opCheckMultiSig)
+}
+
+func TestSignFinalizeExtract(t *testing.T) {
+	a := testMaster(t, "000102030405060708090a0b0c0d0e0f")
+	b := testMaster(t, "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542")
+
+	paths := []string{"m/44'/0'/0'/0/0", "m/49'/0'/0'/0/0", "m/84'/0'/0'/0/0", "m/86'/0'/0'/0/0"}
+	addrTypes := []utils.AddressType{utils.AddressP2PKH, utils.AddressP2SH, utils.AddressP2WPKH, utils.AddressP2TR}
+	var scripts [][]byte
+	for i, path := range paths {
+		scripts = append(scripts, testKeyScript(t, a, path, addrTypes[i]))
+	}
+	keyA, _ := a.DeriveChildKey("m/48'/0'/0'/2'/0/0")
+	keyB, _ := b.DeriveChildKey("m/48'/0'/0'/2'/0/0")
+	witnessScript := multiSigScript(keyA.PublicKey().Key, keyB.PublicKey().Key)
+	scriptHash := sha256.Sum256(witnessScript)
+	scripts = append(scripts, tx.WitnessProgramScript(0, scriptHash[:]))
+
+	// The P2PKH input needs the whole transaction it spends from
+	funding := tx.NewTx(1)
+	funding.AddInput(tx.NewTxIn(&tx.OutPoint{Index: 7}))
+	funding.AddOutput(tx.NewTxOut(10000, scripts[0]))
+
+	unsigned := tx.NewTx(2)
+	for i := range scripts {
+		outPoint := tx.OutPoint{Index: uint32(i)}
+		outPoint.Hash[0] = byte(i)
+		if i == 0 {
+			copy(outPoint.Hash[:], funding.Hash())
+			outPoint.Index = 0
+		}
+		unsigned.AddInput(tx.NewTxIn(&outPoint))
+	}
+	unsigned.AddOutput(tx.NewTxOut(40000, scripts[2]))
+	packet, err := New(unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet.Inputs[0].NonWitnessUtxo = funding
+	for i := 1; i < len(scripts); i++ {
+		packet.Inputs[i].WitnessUtxo = tx.NewTxOut(int64(10000*(i+1)), scripts[i])
+	}
+	packet.Inputs[4].WitnessScript = witnessScript
+	for _, path := range paths {
+		if ok, err := packet.AddDerivation(a, path); err != nil || !ok {
+			t.Fatalf("%s: %v, %v", path, ok, err)
+		}
+	}
+	for _, master := range []*keystore.Key{a, b} {
+		if ok, err := packet.AddDerivation(master, "m/48'/0'/0'/2'/0/0"); err != nil || !ok {
+			t.Fatalf("%v, %v", ok, err)
+		}
+	}
+	if len(packet.Outputs[0].Bip32Derivations) != 1 || packet.Inputs[1].RedeemScript == nil || packet.Inputs[3].TaprootInternalKey == nil {
+		t.Fatal("derivations weren't added")
+	}
+
+	for _, version := range []uint32{0, 2} {
+		packet.Version = version
+		encoded, err := packet.B64Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		packetA, _ := B64Deserialize(encoded)
+		packetB, _ := B64Deserialize(encoded)
+		if n, err := packetA.Sign(a); err != nil || n != 5 {
+			t.Fatalf("version %d: signed %d, %v", version, n, err)
+		}
+		if n, err := packetB.Sign(b); err != nil || n != 1 {
+			t.Fatalf("version %d: signed %d, %v", version, n, err)
+		}
+		if err := packetB.Finalize(); err == nil {
+			t.Fatal("finalized without all signatures")
+		}
+		combined, err := Combine(packetA, packetB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, _ = combined.B64Serialize()
+		combined, _ = B64Deserialize(encoded)
+		if err := combined.Finalize(); err != nil {
+			t.Fatal(err)
+		}
+		final, err := combined.Extract()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stripped, err := tx.Deserialize(final.Serialize())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, in := range stripped.Inputs {
+			in.SignatureScript, in.Witness = nil, nil
+		}
+		if !bytes.Equal(stripped.Hash(), unsigned.Hash()) {
+			t.Fatal("extracted a different transaction")
+		}
+
+		scriptSig := final.Inputs[0].SignatureScript
+		sigLength := int(scriptSig[0])
+		verifyECDSA(t, final, 0, scripts[0], 0, false, scriptSig[1:1+sigLength], scriptSig[2+sigLength:])
+
+		for _, i := range []int{1, 2} {
+			witness := final.Inputs[i].Witness
+			hash160, _ := utils.Hash160(witness[1])
+			verifyECDSA(t, final, i, tx.P2WPKHScriptCode(hash160), int64(10000*(i+1)), true, witness[0], witness[1])
+		}
+		if !bytes.Equal(final.Inputs[1].SignatureScript, tx.PushData(packet.Inputs[1].RedeemScript)) {
+			t.Fatal("P2SH-P2WPKH script sig")
+		}
+
+		prevOuts := make([]*tx.TxOut, len(scripts))
+		for i, in := range combined.Inputs {
+			prevOuts[i], _ = in.prevOut()
+		}
+		sigHash, err := final.TaprootSigHash(3, prevOuts, tx.SigHashDefault, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !crypto.SchnorrVerify(scripts[3][2:], sigHash, final.Inputs[3].Witness[0]) {
+			t.Fatal("taproot signature doesn't verify")
+		}
+
+		witness := final.Inputs[4].Witness
+		if len(witness) != 4 || len(witness[0]) != 0 || !bytes.Equal(witness[3], witnessScript) {
+			t.Fatalf("multisig witness %x", witness)
+		}
+		verifyECDSA(t, final, 4, witnessScript, 50000, true, witness[1], keyA.PublicKey().Key)
+		verifyECDSA(t, final, 4, witnessScript, 50000, true, witness[2], keyB.PublicKey().Key)
+
+		other, _ := New(tx.NewTx(2))
+		if _, err := Combine(packetA, other); err != ErrDifferentTx {
+			t.Fatalf("got %v, want %v", err, ErrDifferentTx)
+		}
+	}
+}
+
+func TestFinalizeChecksSignatures(t *testing.T) {
+	master := testMaster(t, "000102030405060708090a0b0c0d0e0f")
+	paths := []string{"m/84'/0'/0'/0/0", "m/84'/0'/0'/0/1"}
+	unsigned := tx.NewTx(2)
+	for i := range paths {
+		unsigned.AddInput(tx.NewTxIn(&tx.OutPoint{Index: uint32(i)}))
+	}
+	unsigned.AddOutput(tx.NewTxOut(15000, tx.WitnessProgramScript(0, make([]byte, 20))))
+	packet, _ := New(unsigned)
+	for i, path := range paths {
+		packet.Inputs[i].WitnessUtxo = tx.NewTxOut(10000, testKeyScript(t, master, path, utils.AddressP2WPKH))
+		if _, err := packet.AddDerivation(master, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := packet.Sign(master); err != nil || n != 2 {
+		t.Fatalf("signed %d, %v", n, err)
+	}
+	sig0, sig1 := packet.Inputs[0].PartialSigs[0], packet.Inputs[1].PartialSigs[0]
+
+	// A signature of another key
+	packet.Inputs[0].PartialSigs = []*PartialSig{sig1}
+	if err := packet.FinalizeInput(0); err != ErrCannotFinalize {
+		t.Fatalf("got %v, want %v", err, ErrCannotFinalize)
+	}
+	// The right key with a signature of another input
+	packet.Inputs[0].PartialSigs = []*PartialSig{{PubKey: sig0.PubKey, Signature: sig1.Signature}}
+	if err := packet.FinalizeInput(0); err != ErrCannotFinalize {
+		t.Fatalf("got %v, want %v", err, ErrCannotFinalize)
+	}
+	packet.Inputs[0].PartialSigs = []*PartialSig{sig1, {PubKey: sig0.PubKey, Signature: []byte{0x30}}, sig0}
+	if err := packet.FinalizeInput(0); err != nil {
+		t.Fatal(err)
+	}
+	witness := packet.Inputs[0].FinalScriptWitness
+	if len(witness) != 2 || !bytes.Equal(witness[0], sig0.Signature) || !bytes.Equal(witness[1], sig0.PubKey) {
+		t.Fatalf("witness %x", witness)
+	}
+
+	// Multisig signatures are checked the same way
+	fingerprint, _ := master.Fingerprint()
+	multiSigPaths := [][]uint32{{0x80000030, 0x80000000, 0x80000000, 0x80000002, 0, 0}, {0x80000030, 0x80000000, 0x80000000, 0x80000002, 0, 1}}
+	var publicKeys [][]byte
+	for _, path := range multiSigPaths {
+		key, err := master.DerivePath(&keystore.DerivationPath{Absolute: true, Indexes: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKeys = append(publicKeys, key.PublicKey().Key)
+	}
+	script := multiSigScript(publicKeys...)
+	scriptHash := sha256.Sum256(script)
+	p2wsh := tx.WitnessProgramScript(0, scriptHash[:])
+	p2shScript := func(redeemScript []byte) []byte {
+		hash160, _ := utils.Hash160(redeemScript)
+		return tx.P2SHScript(hash160)
+	}
+
+	// Bare, P2SH, P2WSH and P2SH-P2WSH multisig
+	pkScripts := [][]byte{script, p2shScript(script), p2wsh, p2shScript(p2wsh)}
+	redeemScripts := [][]byte{nil, script, nil, p2wsh}
+	witnessScripts := [][]byte{nil, nil, script, script}
+	funding := tx.NewTx(1)
+	funding.AddInput(tx.NewTxIn(&tx.OutPoint{}))
+	for _, pkScript := range pkScripts {
+		funding.AddOutput(tx.NewTxOut(10000, pkScript))
+	}
+	unsigned = tx.NewTx(2)
+	for i := range pkScripts {
+		outPoint := tx.OutPoint{Index: uint32(i)}
+		copy(outPoint.Hash[:], funding.Hash())
+		unsigned.AddInput(tx.NewTxIn(&outPoint))
+	}
+	unsigned.AddOutput(tx.NewTxOut(35000, p2wsh))
+
+	packet, _ = New(unsigned)
+	for i, in := range packet.Inputs {
+		in.NonWitnessUtxo = funding
+		in.RedeemScript = redeemScripts[i]
+		in.WitnessScript = witnessScripts[i]
+		for j, path := range multiSigPaths {
+			in.Bip32Derivations = append(in.Bip32Derivations, &Bip32Derivation{PubKey: publicKeys[j], Fingerprint: fingerprint, Path: path})
+		}
+	}
+	if n, err := packet.Sign(master); err != nil || n != 8 {
+		t.Fatalf("signed %d, %v", n, err)
+	}
+
+	for i, in := range packet.Inputs {
+		first, second := in.PartialSigs[0], in.PartialSigs[1]
+		// The first key's signature of another input
+		other := &PartialSig{PubKey: first.PubKey, Signature: packet.Inputs[(i+1)%len(pkScripts)].PartialSigs[0].Signature}
+		in.PartialSigs = []*PartialSig{other, second}
+		if err := packet.FinalizeInput(i); err != ErrCannotFinalize {
+			t.Fatalf("input %d: got %v, want %v", i, err, ErrCannotFinalize)
+		}
+
+		in.PartialSigs = []*PartialSig{other, second, first}
+		if err := packet.FinalizeInput(i); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+		final := packet.Inputs[i]
+		pushed := bytes.Join(append(final.FinalScriptWitness, final.FinalScriptSig), nil)
+		if !bytes.Contains(pushed, first.Signature) || bytes.Contains(pushed, other.Signature) {
+			t.Errorf("input %d: finalized with the wrong signature", i)
+		}
+		packet.Inputs[i] = in
+	}
+
+	// Scripts must hash to the spent output
+	wrongScript := multiSigScript(publicKeys[1], publicKeys[0])
+	packet.Inputs[1].RedeemScript = wrongScript
+	if err := packet.FinalizeInput(1); err != ErrScriptMismatch {
+		t.Errorf("P2SH: got %v, want %v", err, ErrScriptMismatch)
+	}
+	for _, i := range []int{2, 3} {
+		packet.Inputs[i].WitnessScript = wrongScript
+		if err := packet.FinalizeInput(i); err != ErrScriptMismatch {
+			t.Errorf("input %d: got %v, want %v", i, err, ErrScriptMismatch)
+		}
+	}
+
+	// And so are taproot key path and leaf signatures
+	pkScript := testKeyScript(t, master, "m/86'/0'/0'/0/0", utils.AddressP2TR)
+	unsigned = tx.NewTx(2)
+	unsigned.AddInput(tx.NewTxIn(&tx.OutPoint{}))
+	unsigned.AddOutput(tx.NewTxOut(1000, pkScript))
+	packet, _ = New(unsigned)
+	packet.Inputs[0].WitnessUtxo = tx.NewTxOut(2000, pkScript)
+	if _, err := packet.AddDerivation(master, "m/86'/0'/0'/0/0"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := packet.Sign(master); err != nil || n != 1 {
+		t.Fatalf("signed %d, %v", n, err)
+	}
+	sig := packet.Inputs[0].TaprootKeySpendSig
+	packet.Inputs[0].TaprootKeySpendSig = append(append([]byte{}, sig[:63]...), sig[63]^1)
+	if err := packet.FinalizeInput(0); err != ErrCannotFinalize {
+		t.Fatalf("key path: got %v, want %v", err, ErrCannotFinalize)
+	}
+	packet.Inputs[0].TaprootKeySpendSig = sig
+	if err := packet.FinalizeInput(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid key path signature falls back to a valid leaf signature
+	packet, _, _, _ = tapscriptPacket(t, master)
+	if n, err := packet.Sign(master); err != nil || n != 1 {
+		t.Fatalf("signed %d, %v", n, err)
+	}
+	in := packet.Inputs[0]
+	in.TaprootKeySpendSig = make([]byte, 64)
+	leafSig := in.TaprootScriptSpendSigs[0]
+	in.TaprootScriptSpendSigs = []*TaprootScriptSpendSig{{XOnlyPubKey: leafSig.XOnlyPubKey, LeafHash: leafSig.LeafHash, Signature: sig}}
+	if err := packet.FinalizeInput(0); err != ErrCannotFinalize {
+		t.Fatalf("leaf: got %v, want %v", err, ErrCannotFinalize)
+	}
+	in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs, leafSig)
+	if err := packet.FinalizeInput(0); err != nil {
+		t.Fatal(err)
+	}
+	if witness := packet.Inputs[0].FinalScriptWitness; len(witness) != 3 || !bytes.Equal(witness[0], leafSig.Signature) {
+		t.Fatalf("witness %x", witness)
+	}
+}
+
+func TestSignNonWitnessUtxo(t *testing.T) {
+	master := testMaster(t, "000102030405060708090a0b0c0d0e0f")
+	path := "m/44'/0'/0'/0/0"
+	script := testKeyScript(t, master, path, utils.AddressP2PKH)
+	funding := tx.NewTx(1)
+	funding.AddInput(tx.NewTxIn(&tx.OutPoint{}))
+	funding.AddOutput(tx.NewTxOut(10000, script))
+
+	outPoint := tx.OutPoint{}
+	copy(outPoint.Hash[:], funding.Hash())
+	unsigned := tx.NewTx(2)
+	unsigned.AddInput(tx.NewTxIn(&outPoint))
+	unsigned.AddOutput(tx.NewTxOut(9000, script))
+
+	newPacket := func() *Packet {
+		packet, _ := New(unsigned)
+		packet.Inputs[0].WitnessUtxo = tx.NewTxOut(10000, script)
+		if ok, err := packet.AddDerivation(master, path); err != nil || !ok {
+			t.Fatalf("%v, %v", ok, err)
+		}
+		return packet
+	}
+
+	// The legacy sighash doesn't commit to the amount of a witness UTXO
+	if _, err := newPacket().Sign(master); err != ErrMissingUtxo {
+		t.Fatalf("got %v, want %v", err, ErrMissingUtxo)
+	}
+	packet := newPacket()
+	packet.Inputs[0].NonWitnessUtxo = tx.NewTx(1)
+	if _, err := packet.Sign(master); err != ErrUtxoMismatch {
+		t.Fatalf("got %v, want %v", err, ErrUtxoMismatch)
+	}
+	packet = newPacket()
+	packet.Inputs[0].NonWitnessUtxo = funding
+	if n, err := packet.Sign(master); err != nil || n != 1 {
+		t.Fatalf("signed %d, %v", n, err)
+	}
+	if err := packet.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tapscriptPacket returns a PSBT spending a "<key> OP_CHECKSIG" leaf, with
+// the taproot derivation of its key from master
+func tapscriptPacket(t *testing.T, master *keystore.Key) (*Packet, *keystore.Key, []byte, []byte) {
+	t.Helper()
+	leafKey, _ := master.DeriveChildKey("m/86'/0'/0'/0/5")
+	internalKey, _ := master.DeriveChildKey("m/86'/0'/0'/0/6")
+	script := append(append([]byte{0x20}, leafKey.XOnlyPublicKey()...), opCheckSig)
+	leafHash := tx.TapLeafHash(tx.BaseLeafVersion, script)
+	outputKey, parity, err := crypto.TweakTaprootPublicKey(internalKey.XOnlyPublicKey(), leafHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controlBlock, err := tx.ControlBlock(tx.BaseLeafVersion, parity, internalKey.XOnlyPublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript := tx.WitnessProgramScript(1, outputKey)
+
+	unsigned := tx.NewTx(2)
+	unsigned.AddInput(tx.NewTxIn(&tx.OutPoint{}))
+	unsigned.AddOutput(tx.NewTxOut(1000, pkScript))
+	packet, _ := New(unsigned)
+	in := packet.Inputs[0]
+	in.WitnessUtxo = tx.NewTxOut(2000, pkScript)
+	in.SigHashType = tx.SigHashAll
+	in.TaprootLeafScripts = []*TaprootLeafScript{{ControlBlock: controlBlock, Script: script, LeafVersion: tx.BaseLeafVersion}}
+	fingerprint, _ := master.Fingerprint()
+	in.TaprootBip32Derivations = []*TaprootBip32Derivation{{
+		XOnlyPubKey: leafKey.XOnlyPublicKey(),
+		LeafHashes:  [][]byte{leafHash},
+		Fingerprint: fingerprint,
+		Path:        []uint32{0x80000056, 0x80000000, 0x80000000, 0, 5},
+	}}
+	return packet, leafKey, script, pkScript
+}
+
+func TestTapscriptLeaf(t *testing.T) {
+	master := testMaster(t, "000102030405060708090a0b0c0d0e0f")
+	packet, leafKey, script, pkScript := tapscriptPacket(t, master)
+
+	encoded, _ := packet.B64Serialize()
+	packet, err := B64Deserialize(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := packet.Sign(master); err != nil || n != 1 {
+		t.Fatalf("signed %d, %v", n, err)
+	}
+	if err := packet.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	final, err := packet.Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	witness := final.Inputs[0].Witness
+	if len(witness) != 3 || len(witness[0]) != 65 || witness[0][64] != byte(tx.SigHashAll) {
+		t.Fatalf("witness %x", witness)
+	}
+	sigHash, err := final.TaprootSigHash(0, []*tx.TxOut{tx.NewTxOut(2000, pkScript)}, tx.SigHashAll, nil, tx.NewScriptPathSpend(tx.BaseLeafVersion, script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !crypto.SchnorrVerify(leafKey.XOnlyPublicKey(), sigHash, witness[0][:64]) {
+		t.Fatal("signature doesn't verify")
+	}
+}
+
+func TestSignTaprootSkips(t *testing.T) {
+	master := testMaster(t, "000102030405060708090a0b0c0d0e0f")
+	fingerprint, _ := master.Fingerprint()
+	internalKey, _ := master.DeriveChildKey("m/86'/0'/0'/0/6")
+	withInternalKey := func() *Packet {
+		packet, _, _, _ := tapscriptPacket(t, master)
+		in := packet.Inputs[0]
+		in.TaprootInternalKey = internalKey.XOnlyPublicKey()
+		in.TaprootBip32Derivations = append(in.TaprootBip32Derivations, &TaprootBip32Derivation{
+			XOnlyPubKey: internalKey.XOnlyPublicKey(),
+			Fingerprint: fingerprint,
+			Path:        []uint32{0x80000056, 0x80000000, 0x80000000, 0, 6},
+		})
+		return packet
+	}
+
+	// Without the merkle root only the leaf is signed
+	packet := withInternalKey()
+	if n, err := packet.Sign(master); err != nil || n != 1 || packet.Inputs[0].TaprootKeySpendSig != nil {
+		t.Fatalf("signed %d, %v", n, err)
+	}
+	packet = withInternalKey()
+	packet.Inputs[0].TaprootMerkleRoot = tx.TapLeafHash(tx.BaseLeafVersion, packet.Inputs[0].TaprootLeafScripts[0].Script)
+	if n, err := packet.Sign(master); err != nil || n != 2 {
+		t.Fatalf("with the merkle root: signed %d, %v", n, err)
+	}
+	if err := packet.FinalizeInput(0); err != nil || len(packet.Inputs[0].FinalScriptWitness) != 1 {
+		t.Fatalf("key path: %v", err)
+	}
+
+	// Leaf hashes without a leaf script aren't signed
+	packet, _, _, _ = tapscriptPacket(t, master)
+	packet.Inputs[0].TaprootLeafScripts = nil
+	if n, err := packet.Sign(master); err != nil || n != 0 {
+		t.Fatalf("without leaf script: signed %d, %v", n, err)
+	}
+
+	// An input without its UTXO makes the taproot sighash impossible, but
+	// doesn't stop the other inputs from being signed
+	taprootScript := testKeyScript(t, master, "m/86'/0'/0'/0/0", utils.AddressP2TR)
+	segwitScript := testKeyScript(t, master, "m/84'/0'/0'/0/0", utils.AddressP2WPKH)
+	unsigned := tx.NewTx(2)
+	for i := 0; i < 3; i++ {
+		unsigned.AddInput(tx.NewTxIn(&tx.OutPoint{Index: uint32(i)}))
+	}
+	unsigned.AddOutput(tx.NewTxOut(1000, segwitScript))
+	packet, _ = New(unsigned)
+	packet.Inputs[0].WitnessUtxo = tx.NewTxOut(2000, taprootScript)
+	packet.Inputs[2].WitnessUtxo = tx.NewTxOut(2000, segwitScript)
+	for _, path := range []string{"m/86'/0'/0'/0/0", "m/84'/0'/0'/0/0"} {
+		if _, err := packet.AddDerivation(master, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := packet.Sign(master); err != nil || n != 1 || len(packet.Inputs[2].PartialSigs) != 1 {
+		t.Fatalf("missing UTXO: signed %d, %v", n, err)
+	}
+}
+
+func TestDeserializeErrors(t *testing.T) {
+	data, _ := base64.StdEncoding.DecodeString(validTaprootVectors[0])
+	if _, err := Deserialize(data[:len(data)-1]); err != ErrUnexpectedEOF {
+		t.Fatalf("got %v, want %v", err, ErrUnexpectedEOF)
+	}
+	if _, err := Deserialize(append(data, 0x00)); err != ErrTrailingData {
+		t.Fatalf("got %v, want %v", err, ErrTrailingData)
+	}
+	if _, err := Deserialize(data[1:]); err != ErrInvalidMagic {
+		t.Fatalf("got %v, want %v", err, ErrInvalidMagic)
+	}
+
+	// A key type that isn't a complete compact size isn't key type 0
+	unsigned := tx.NewTx(2)
+	unsigned.AddInput(tx.NewTxIn(&tx.OutPoint{}))
+	unsigned.AddOutput(tx.NewTxOut(1000, tx.WitnessProgramScript(0, make([]byte, 20))))
+	global := []*Unknown{{Key: []byte{globalUnsignedTx}, Value: unsigned.SerializeNoWitness()}}
+	truncated := []byte{0xfd}
+	for i, data := range [][]byte{
+		rawPSBT(with(global, truncated, []byte{1}), nil, nil),
+		rawPSBT(global, with(nil, truncated, []byte{1}), nil),
+		rawPSBT(global, nil, with(nil, truncated, []byte{1})),
+	} {
+		if _, err := Deserialize(data); err != ErrInvalidKey {
+			t.Errorf("map %d: got %v, want %v", i, err, ErrInvalidKey)
+		}
+	}
+}
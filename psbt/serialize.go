@@ -0,0 +1,895 @@
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/tx"
+)
+
+// Global key types
+const (
+	globalUnsignedTx       = 0x00
+	globalXPub             = 0x01
+	globalTxVersion        = 0x02
+	globalFallbackLockTime = 0x03
+	globalInputCount       = 0x04
+	globalOutputCount      = 0x05
+	globalTxModifiable     = 0x06
+	globalVersion          = 0xfb
+)
+
+// Input key types
+const (
+	inNonWitnessUtxo         = 0x00
+	inWitnessUtxo            = 0x01
+	inPartialSig             = 0x02
+	inSigHashType            = 0x03
+	inRedeemScript           = 0x04
+	inWitnessScript          = 0x05
+	inBip32Derivation        = 0x06
+	inFinalScriptSig         = 0x07
+	inFinalScriptWitness     = 0x08
+	inRipemd160              = 0x0a
+	inSha256                 = 0x0b
+	inHash160                = 0x0c
+	inHash256                = 0x0d
+	inPreviousTxID           = 0x0e
+	inOutputIndex            = 0x0f
+	inSequence               = 0x10
+	inRequiredTimeLockTime   = 0x11
+	inRequiredHeightLockTime = 0x12
+	inTaprootKeySig          = 0x13
+	inTaprootScriptSig       = 0x14
+	inTaprootLeafScript      = 0x15
+	inTaprootBip32Derivation = 0x16
+	inTaprootInternalKey     = 0x17
+	inTaprootMerkleRoot      = 0x18
+)
+
+// Output key types
+const (
+	outRedeemScript           = 0x00
+	outWitnessScript          = 0x01
+	outBip32Derivation        = 0x02
+	outAmount                 = 0x03
+	outScript                 = 0x04
+	outTaprootInternalKey     = 0x05
+	outTaprootTree            = 0x06
+	outTaprootBip32Derivation = 0x07
+)
+
+// lockTimeThreshold separates block heights from timestamps in lock times
+const lockTimeThreshold = 500000000
+
+var magic = []byte{'p', 's', 'b', 't', 0xff}
+
+var (
+	// ErrInvalidMagic is returned when data doesn't start with "psbt\xff"
+	ErrInvalidMagic = errors.New("Invalid PSBT magic bytes")
+
+	// ErrUnexpectedEOF is returned when serialized data ends early
+	ErrUnexpectedEOF = errors.New("Unexpected end of PSBT data")
+
+	// ErrTrailingData is returned when bytes remain after the last map
+	ErrTrailingData = errors.New("Trailing data after PSBT")
+
+	// ErrDuplicateKey is returned when a map holds the same key twice
+	ErrDuplicateKey = errors.New("Duplicate PSBT key")
+
+	// ErrInvalidKey is returned when a key has the wrong length for its type
+	ErrInvalidKey = errors.New("Invalid PSBT key")
+
+	// ErrInvalidValue is returned when a value can't be decoded for its key
+	// type
+	ErrInvalidValue = errors.New("Invalid PSBT value")
+
+	// ErrMissingField is returned when a field required by the PSBT version
+	// is absent
+	ErrMissingField = errors.New("PSBT is missing a required field")
+
+	// ErrFieldNotAllowed is returned when a field isn't allowed in the PSBT
+	// version
+	ErrFieldNotAllowed = errors.New("PSBT field not allowed in this version")
+)
+
+// Deserialize decodes a binary PSBT of version 0 or 2.
+func Deserialize(data []byte) (*Packet, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return nil, ErrInvalidMagic
+	}
+	r := &reader{data: data, pos: len(magic)}
+
+	pairs, err := r.readMap()
+	if err != nil {
+		return nil, err
+	}
+	packet, unsigned, inputCount, outputCount, err := parseGlobal(pairs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < inputCount; i++ {
+		pairs, err := r.readMap()
+		if err != nil {
+			return nil, err
+		}
+		in, err := parseInput(pairs, packet.Version)
+		if err != nil {
+			return nil, err
+		}
+		if unsigned != nil {
+			in.PreviousOutPoint = unsigned.Inputs[i].PreviousOutPoint
+			in.Sequence = unsigned.Inputs[i].Sequence
+		}
+		packet.Inputs = append(packet.Inputs, in)
+	}
+
+	for i := uint64(0); i < outputCount; i++ {
+		pairs, err := r.readMap()
+		if err != nil {
+			return nil, err
+		}
+		out, err := parseOutput(pairs, packet.Version)
+		if err != nil {
+			return nil, err
+		}
+		if unsigned != nil {
+			out.Amount = unsigned.Outputs[i].Value
+			out.Script = unsigned.Outputs[i].PkScript
+		}
+		packet.Outputs = append(packet.Outputs, out)
+	}
+
+	if r.remaining() != 0 {
+		return nil, ErrTrailingData
+	}
+	return packet, nil
+}
+
+// B64Deserialize decodes a base64 PSBT.
+func B64Deserialize(s string) (*Packet, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Deserialize(data)
+}
+
+// Serialize encodes the PSBT in the binary format of its version.
+func (packet *Packet) Serialize() ([]byte, error) {
+	if packet.Version != 0 && packet.Version != 2 {
+		return nil, ErrUnsupportedVersion
+	}
+	if packet.Version == 2 && packet.TxVersion < 2 {
+		return nil, ErrInvalidTxVersion
+	}
+
+	buffer := bytes.NewBuffer(append([]byte{}, magic...))
+	if packet.Version == 0 {
+		unsigned, err := packet.UnsignedTx()
+		if err != nil {
+			return nil, err
+		}
+		writePair(buffer, globalUnsignedTx, nil, unsigned.SerializeNoWitness())
+	}
+	for _, xpub := range packet.XPubs {
+		writePair(buffer, globalXPub, xpub.ExtendedKey, serializeOrigin(xpub.Fingerprint, xpub.Path))
+	}
+	if packet.Version == 2 {
+		writePair(buffer, globalTxVersion, nil, uint32Bytes(uint32(packet.TxVersion)))
+		if packet.LockTime != 0 {
+			writePair(buffer, globalFallbackLockTime, nil, uint32Bytes(packet.LockTime))
+		}
+		writePair(buffer, globalInputCount, nil, varIntBytes(uint64(len(packet.Inputs))))
+		writePair(buffer, globalOutputCount, nil, varIntBytes(uint64(len(packet.Outputs))))
+		if packet.TxModifiable != 0 {
+			writePair(buffer, globalTxModifiable, nil, []byte{packet.TxModifiable})
+		}
+		writePair(buffer, globalVersion, nil, uint32Bytes(packet.Version))
+	}
+	writeUnknowns(buffer, packet.Unknowns)
+	buffer.WriteByte(0x00)
+
+	for _, in := range packet.Inputs {
+		in.serialize(buffer, packet.Version)
+	}
+	for _, out := range packet.Outputs {
+		out.serialize(buffer, packet.Version)
+	}
+	return buffer.Bytes(), nil
+}
+
+// B64Serialize encodes the PSBT in base64.
+func (packet *Packet) B64Serialize() (string, error) {
+	data, err := packet.Serialize()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (in *Input) serialize(buffer *bytes.Buffer, version uint32) {
+	if in.NonWitnessUtxo != nil {
+		writePair(buffer, inNonWitnessUtxo, nil, in.NonWitnessUtxo.Serialize())
+	}
+	if in.WitnessUtxo != nil {
+		writePair(buffer, inWitnessUtxo, nil, serializeTxOut(in.WitnessUtxo))
+	}
+	for _, sig := range in.PartialSigs {
+		writePair(buffer, inPartialSig, sig.PubKey, sig.Signature)
+	}
+	if in.SigHashType != 0 || in.HasSigHashType {
+		writePair(buffer, inSigHashType, nil, uint32Bytes(uint32(in.SigHashType)))
+	}
+	if in.RedeemScript != nil {
+		writePair(buffer, inRedeemScript, nil, in.RedeemScript)
+	}
+	if in.WitnessScript != nil {
+		writePair(buffer, inWitnessScript, nil, in.WitnessScript)
+	}
+	for _, derivation := range in.Bip32Derivations {
+		writePair(buffer, inBip32Derivation, derivation.PubKey, serializeOrigin(derivation.Fingerprint, derivation.Path))
+	}
+	if in.FinalScriptSig != nil {
+		writePair(buffer, inFinalScriptSig, nil, in.FinalScriptSig)
+	}
+	if in.FinalScriptWitness != nil {
+		writePair(buffer, inFinalScriptWitness, nil, serializeWitness(in.FinalScriptWitness))
+	}
+	if version == 2 {
+		writePair(buffer, inPreviousTxID, nil, in.PreviousOutPoint.Hash[:])
+		writePair(buffer, inOutputIndex, nil, uint32Bytes(in.PreviousOutPoint.Index))
+		if in.Sequence != tx.MaxSequence {
+			writePair(buffer, inSequence, nil, uint32Bytes(in.Sequence))
+		}
+		if in.RequiredTimeLockTime != 0 {
+			writePair(buffer, inRequiredTimeLockTime, nil, uint32Bytes(in.RequiredTimeLockTime))
+		}
+		if in.RequiredHeightLockTime != 0 {
+			writePair(buffer, inRequiredHeightLockTime, nil, uint32Bytes(in.RequiredHeightLockTime))
+		}
+	}
+	if in.TaprootKeySpendSig != nil {
+		writePair(buffer, inTaprootKeySig, nil, in.TaprootKeySpendSig)
+	}
+	for _, sig := range in.TaprootScriptSpendSigs {
+		writePair(buffer, inTaprootScriptSig, append(copyBytes(sig.XOnlyPubKey), sig.LeafHash...), sig.Signature)
+	}
+	for _, leaf := range in.TaprootLeafScripts {
+		writePair(buffer, inTaprootLeafScript, leaf.ControlBlock, append(copyBytes(leaf.Script), leaf.LeafVersion))
+	}
+	for _, derivation := range in.TaprootBip32Derivations {
+		writePair(buffer, inTaprootBip32Derivation, derivation.XOnlyPubKey, serializeTaprootOrigin(derivation))
+	}
+	if in.TaprootInternalKey != nil {
+		writePair(buffer, inTaprootInternalKey, nil, in.TaprootInternalKey)
+	}
+	if in.TaprootMerkleRoot != nil {
+		writePair(buffer, inTaprootMerkleRoot, nil, in.TaprootMerkleRoot)
+	}
+	writeUnknowns(buffer, in.Unknowns)
+	buffer.WriteByte(0x00)
+}
+
+func (out *Output) serialize(buffer *bytes.Buffer, version uint32) {
+	if out.RedeemScript != nil {
+		writePair(buffer, outRedeemScript, nil, out.RedeemScript)
+	}
+	if out.WitnessScript != nil {
+		writePair(buffer, outWitnessScript, nil, out.WitnessScript)
+	}
+	for _, derivation := range out.Bip32Derivations {
+		writePair(buffer, outBip32Derivation, derivation.PubKey, serializeOrigin(derivation.Fingerprint, derivation.Path))
+	}
+	if version == 2 {
+		writePair(buffer, outAmount, nil, uint64Bytes(uint64(out.Amount)))
+		writePair(buffer, outScript, nil, out.Script)
+	}
+	if out.TaprootInternalKey != nil {
+		writePair(buffer, outTaprootInternalKey, nil, out.TaprootInternalKey)
+	}
+	if out.TaprootTree != nil {
+		writePair(buffer, outTaprootTree, nil, out.TaprootTree)
+	}
+	for _, derivation := range out.TaprootBip32Derivations {
+		writePair(buffer, outTaprootBip32Derivation, derivation.XOnlyPubKey, serializeTaprootOrigin(derivation))
+	}
+	writeUnknowns(buffer, out.Unknowns)
+	buffer.WriteByte(0x00)
+}
+
+// parseGlobal decodes the global map. For version 0 it also returns the
+// unsigned transaction, whose inputs and outputs fill the packet's.
+func parseGlobal(pairs []*Unknown) (*Packet, *tx.Tx, uint64, uint64, error) {
+	version, err := parseVersion(pairs)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	packet := &Packet{Version: version}
+	var unsigned *tx.Tx
+	var inputCount, outputCount uint64
+	hasTxVersion, hasInputCount, hasOutputCount, hasV2Fields := false, false, false, false
+
+	for _, pair := range pairs {
+		keyType, keyData, err := splitKey(pair.Key)
+		if err != nil {
+			return nil, nil, 0, 0, err
+		}
+		if keyType >= globalTxVersion && keyType <= globalTxModifiable && version < 2 && len(keyData) != 0 {
+			// Not a field of this version, so an unknown key
+			packet.Unknowns = append(packet.Unknowns, pair)
+			continue
+		}
+		if keyType != globalXPub && keyType <= globalTxModifiable || keyType == globalVersion {
+			if len(keyData) != 0 {
+				return nil, nil, 0, 0, ErrInvalidKey
+			}
+		}
+
+		switch keyType {
+		case globalUnsignedTx:
+			unsigned, err = tx.DeserializeNoWitness(pair.Value)
+			if err != nil {
+				return nil, nil, 0, 0, err
+			}
+			for _, in := range unsigned.Inputs {
+				if len(in.SignatureScript) != 0 || len(in.Witness) != 0 {
+					return nil, nil, 0, 0, ErrTxNotUnsigned
+				}
+			}
+		case globalXPub:
+			if len(keyData) != 78 {
+				return nil, nil, 0, 0, ErrInvalidKey
+			}
+			fingerprint, path, err := parseOrigin(pair.Value)
+			if err != nil {
+				return nil, nil, 0, 0, err
+			}
+			packet.XPubs = append(packet.XPubs, &XPub{ExtendedKey: keyData, Fingerprint: fingerprint, Path: path})
+		case globalTxVersion:
+			var version uint32
+			version, err = parseUint32(pair.Value)
+			packet.TxVersion = int32(version)
+			hasTxVersion, hasV2Fields = true, true
+		case globalFallbackLockTime:
+			packet.LockTime, err = parseUint32(pair.Value)
+			hasV2Fields = true
+		case globalInputCount:
+			inputCount, err = parseVarInt(pair.Value)
+			hasInputCount, hasV2Fields = true, true
+		case globalOutputCount:
+			outputCount, err = parseVarInt(pair.Value)
+			hasOutputCount, hasV2Fields = true, true
+		case globalTxModifiable:
+			if len(pair.Value) != 1 {
+				return nil, nil, 0, 0, ErrInvalidValue
+			}
+			packet.TxModifiable = pair.Value[0]
+			hasV2Fields = true
+		case globalVersion:
+			// Already read by parseVersion
+		default:
+			packet.Unknowns = append(packet.Unknowns, pair)
+		}
+		if err != nil {
+			return nil, nil, 0, 0, err
+		}
+	}
+
+	switch packet.Version {
+	case 0:
+		if unsigned == nil {
+			return nil, nil, 0, 0, ErrMissingField
+		}
+		if hasV2Fields {
+			return nil, nil, 0, 0, ErrFieldNotAllowed
+		}
+		packet.TxVersion = unsigned.Version
+		packet.LockTime = unsigned.LockTime
+		inputCount, outputCount = uint64(len(unsigned.Inputs)), uint64(len(unsigned.Outputs))
+	case 2:
+		if unsigned != nil {
+			return nil, nil, 0, 0, ErrFieldNotAllowed
+		}
+		if !hasTxVersion || !hasInputCount || !hasOutputCount {
+			return nil, nil, 0, 0, ErrMissingField
+		}
+		if packet.TxVersion < 2 {
+			return nil, nil, 0, 0, ErrInvalidTxVersion
+		}
+	default:
+		return nil, nil, 0, 0, ErrUnsupportedVersion
+	}
+	return packet, unsigned, inputCount, outputCount, nil
+}
+
+func parseInput(pairs []*Unknown, version uint32) (*Input, error) {
+	in := &Input{Sequence: tx.MaxSequence}
+	hasPreviousTxID, hasOutputIndex := false, false
+
+	for _, pair := range pairs {
+		keyType, keyData, err := splitKey(pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		if keyType >= inPreviousTxID && keyType <= inRequiredHeightLockTime && version < 2 {
+			if len(keyData) != 0 {
+				in.Unknowns = append(in.Unknowns, pair)
+				continue
+			}
+			return nil, ErrFieldNotAllowed
+		}
+		switch keyType {
+		case inPartialSig, inBip32Derivation:
+			if len(keyData) != 33 && len(keyData) != 65 {
+				return nil, ErrInvalidKey
+			}
+		case inTaprootScriptSig:
+			if len(keyData) != 64 {
+				return nil, ErrInvalidKey
+			}
+		case inTaprootLeafScript:
+			if len(keyData) < 33 || (len(keyData)-33)%32 != 0 || len(keyData) > 33+128*32 {
+				return nil, ErrInvalidKey
+			}
+		case inTaprootBip32Derivation:
+			if len(keyData) != 32 {
+				return nil, ErrInvalidKey
+			}
+		case inRipemd160, inSha256, inHash160, inHash256:
+			// Hash preimages are keyed by their hash and kept as unknowns
+		default:
+			if keyType <= inTaprootMerkleRoot && len(keyData) != 0 {
+				return nil, ErrInvalidKey
+			}
+		}
+
+		switch keyType {
+		case inNonWitnessUtxo:
+			in.NonWitnessUtxo, err = tx.Deserialize(pair.Value)
+		case inWitnessUtxo:
+			in.WitnessUtxo, err = parseTxOut(pair.Value)
+		case inPartialSig:
+			in.PartialSigs = append(in.PartialSigs, &PartialSig{PubKey: keyData, Signature: pair.Value})
+		case inSigHashType:
+			var hashType uint32
+			hashType, err = parseUint32(pair.Value)
+			in.SigHashType, in.HasSigHashType = tx.SigHashType(hashType), true
+		case inRedeemScript:
+			in.RedeemScript = pair.Value
+		case inWitnessScript:
+			in.WitnessScript = pair.Value
+		case inBip32Derivation:
+			var derivation *Bip32Derivation
+			derivation, err = parseDerivation(keyData, pair.Value)
+			if err == nil {
+				in.Bip32Derivations = append(in.Bip32Derivations, derivation)
+			}
+		case inFinalScriptSig:
+			in.FinalScriptSig = pair.Value
+		case inFinalScriptWitness:
+			in.FinalScriptWitness, err = parseWitness(pair.Value)
+		case inPreviousTxID:
+			if len(pair.Value) != tx.HashLength {
+				return nil, ErrInvalidValue
+			}
+			copy(in.PreviousOutPoint.Hash[:], pair.Value)
+			hasPreviousTxID = true
+		case inOutputIndex:
+			in.PreviousOutPoint.Index, err = parseUint32(pair.Value)
+			hasOutputIndex = true
+		case inSequence:
+			in.Sequence, err = parseUint32(pair.Value)
+		case inRequiredTimeLockTime:
+			in.RequiredTimeLockTime, err = parseUint32(pair.Value)
+			if err == nil && in.RequiredTimeLockTime < lockTimeThreshold {
+				err = ErrInvalidValue
+			}
+		case inRequiredHeightLockTime:
+			in.RequiredHeightLockTime, err = parseUint32(pair.Value)
+			if err == nil && (in.RequiredHeightLockTime == 0 || in.RequiredHeightLockTime >= lockTimeThreshold) {
+				err = ErrInvalidValue
+			}
+		case inTaprootKeySig:
+			if len(pair.Value) != 64 && len(pair.Value) != 65 {
+				return nil, ErrInvalidValue
+			}
+			in.TaprootKeySpendSig = pair.Value
+		case inTaprootScriptSig:
+			if len(pair.Value) != 64 && len(pair.Value) != 65 {
+				return nil, ErrInvalidValue
+			}
+			in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs, &TaprootScriptSpendSig{
+				XOnlyPubKey: keyData[:32],
+				LeafHash:    keyData[32:],
+				Signature:   pair.Value,
+			})
+		case inTaprootLeafScript:
+			if len(pair.Value) == 0 {
+				return nil, ErrInvalidValue
+			}
+			in.TaprootLeafScripts = append(in.TaprootLeafScripts, &TaprootLeafScript{
+				ControlBlock: keyData,
+				Script:       pair.Value[:len(pair.Value)-1],
+				LeafVersion:  pair.Value[len(pair.Value)-1],
+			})
+		case inTaprootBip32Derivation:
+			var derivation *TaprootBip32Derivation
+			derivation, err = parseTaprootDerivation(keyData, pair.Value)
+			if err == nil {
+				in.TaprootBip32Derivations = append(in.TaprootBip32Derivations, derivation)
+			}
+		case inTaprootInternalKey:
+			if len(pair.Value) != 32 {
+				return nil, ErrInvalidValue
+			}
+			in.TaprootInternalKey = pair.Value
+		case inTaprootMerkleRoot:
+			if len(pair.Value) != 32 {
+				return nil, ErrInvalidValue
+			}
+			in.TaprootMerkleRoot = pair.Value
+		default:
+			in.Unknowns = append(in.Unknowns, pair)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if version == 2 && (!hasPreviousTxID || !hasOutputIndex) {
+		return nil, ErrMissingField
+	}
+	return in, nil
+}
+
+func parseOutput(pairs []*Unknown, version uint32) (*Output, error) {
+	out := &Output{}
+	hasAmount, hasScript := false, false
+
+	for _, pair := range pairs {
+		keyType, keyData, err := splitKey(pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		if (keyType == outAmount || keyType == outScript) && version < 2 {
+			if len(keyData) != 0 {
+				out.Unknowns = append(out.Unknowns, pair)
+				continue
+			}
+			return nil, ErrFieldNotAllowed
+		}
+		switch keyType {
+		case outBip32Derivation:
+			if len(keyData) != 33 && len(keyData) != 65 {
+				return nil, ErrInvalidKey
+			}
+		case outTaprootBip32Derivation:
+			if len(keyData) != 32 {
+				return nil, ErrInvalidKey
+			}
+		default:
+			if keyType <= outTaprootBip32Derivation && len(keyData) != 0 {
+				return nil, ErrInvalidKey
+			}
+		}
+
+		switch keyType {
+		case outRedeemScript:
+			out.RedeemScript = pair.Value
+		case outWitnessScript:
+			out.WitnessScript = pair.Value
+		case outBip32Derivation:
+			var derivation *Bip32Derivation
+			derivation, err = parseDerivation(keyData, pair.Value)
+			if err == nil {
+				out.Bip32Derivations = append(out.Bip32Derivations, derivation)
+			}
+		case outAmount:
+			if len(pair.Value) != 8 {
+				return nil, ErrInvalidValue
+			}
+			out.Amount = int64(binary.LittleEndian.Uint64(pair.Value))
+			hasAmount = true
+		case outScript:
+			out.Script = pair.Value
+			hasScript = true
+		case outTaprootInternalKey:
+			if len(pair.Value) != 32 {
+				return nil, ErrInvalidValue
+			}
+			out.TaprootInternalKey = pair.Value
+		case outTaprootTree:
+			if len(pair.Value) == 0 {
+				return nil, ErrInvalidValue
+			}
+			out.TaprootTree = pair.Value
+		case outTaprootBip32Derivation:
+			var derivation *TaprootBip32Derivation
+			derivation, err = parseTaprootDerivation(keyData, pair.Value)
+			if err == nil {
+				out.TaprootBip32Derivations = append(out.TaprootBip32Derivations, derivation)
+			}
+		default:
+			out.Unknowns = append(out.Unknowns, pair)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if version == 2 && (!hasAmount || !hasScript) {
+		return nil, ErrMissingField
+	}
+	return out, nil
+}
+
+// parseVersion returns the PSBT version of the global map, 0 when absent.
+// It's needed first as it decides which key types are fields.
+func parseVersion(pairs []*Unknown) (uint32, error) {
+	for _, pair := range pairs {
+		if bytes.Equal(pair.Key, []byte{globalVersion}) {
+			return parseUint32(pair.Value)
+		}
+	}
+	return 0, nil
+}
+
+// splitKey returns the key type and the key data. Key types are compact
+// size integers, in practice always a single byte.
+func splitKey(key []byte) (uint64, []byte, error) {
+	r := &reader{data: key}
+	keyType, err := r.readVarInt()
+	if err != nil {
+		return 0, nil, ErrInvalidKey
+	}
+	return keyType, key[r.pos:], nil
+}
+
+func parseOrigin(value []byte) ([]byte, []uint32, error) {
+	if len(value) < 4 || len(value)%4 != 0 {
+		return nil, nil, ErrInvalidValue
+	}
+	path := make([]uint32, 0, len(value)/4-1)
+	for i := 4; i < len(value); i += 4 {
+		path = append(path, binary.LittleEndian.Uint32(value[i:]))
+	}
+	return value[:4], path, nil
+}
+
+func serializeOrigin(fingerprint []byte, path []uint32) []byte {
+	value := copyBytes(fingerprint)
+	for _, index := range path {
+		value = append(value, uint32Bytes(index)...)
+	}
+	return value
+}
+
+func parseDerivation(pubKey []byte, value []byte) (*Bip32Derivation, error) {
+	fingerprint, path, err := parseOrigin(value)
+	if err != nil {
+		return nil, err
+	}
+	return &Bip32Derivation{PubKey: pubKey, Fingerprint: fingerprint, Path: path}, nil
+}
+
+func parseTaprootDerivation(xOnlyPubKey []byte, value []byte) (*TaprootBip32Derivation, error) {
+	r := &reader{data: value}
+	count, err := r.readVarInt()
+	if err != nil {
+		return nil, ErrInvalidValue
+	}
+	if count > uint64(r.remaining()/32) {
+		return nil, ErrInvalidValue
+	}
+	derivation := &TaprootBip32Derivation{XOnlyPubKey: xOnlyPubKey}
+	for i := uint64(0); i < count; i++ {
+		leafHash, _ := r.readBytes(32)
+		derivation.LeafHashes = append(derivation.LeafHashes, leafHash)
+	}
+	derivation.Fingerprint, derivation.Path, err = parseOrigin(value[r.pos:])
+	if err != nil {
+		return nil, err
+	}
+	return derivation, nil
+}
+
+func serializeTaprootOrigin(derivation *TaprootBip32Derivation) []byte {
+	value := varIntBytes(uint64(len(derivation.LeafHashes)))
+	for _, leafHash := range derivation.LeafHashes {
+		value = append(value, leafHash...)
+	}
+	return append(value, serializeOrigin(derivation.Fingerprint, derivation.Path)...)
+}
+
+func parseTxOut(value []byte) (*tx.TxOut, error) {
+	r := &reader{data: value}
+	amount, err := r.readBytes(8)
+	if err != nil {
+		return nil, ErrInvalidValue
+	}
+	script, err := r.readVarBytes()
+	if err != nil || r.remaining() != 0 {
+		return nil, ErrInvalidValue
+	}
+	return tx.NewTxOut(int64(binary.LittleEndian.Uint64(amount)), script), nil
+}
+
+func serializeTxOut(out *tx.TxOut) []byte {
+	value := uint64Bytes(uint64(out.Value))
+	value = append(value, varIntBytes(uint64(len(out.PkScript)))...)
+	return append(value, out.PkScript...)
+}
+
+func parseWitness(value []byte) ([][]byte, error) {
+	r := &reader{data: value}
+	count, err := r.readVarInt()
+	if err != nil || count > uint64(r.remaining()) {
+		return nil, ErrInvalidValue
+	}
+	witness := make([][]byte, 0, count)
+	for i := uint64(0); i < count; i++ {
+		item, err := r.readVarBytes()
+		if err != nil {
+			return nil, ErrInvalidValue
+		}
+		witness = append(witness, item)
+	}
+	if r.remaining() != 0 {
+		return nil, ErrInvalidValue
+	}
+	return witness, nil
+}
+
+func serializeWitness(witness [][]byte) []byte {
+	value := varIntBytes(uint64(len(witness)))
+	for _, item := range witness {
+		value = append(value, varIntBytes(uint64(len(item)))...)
+		value = append(value, item...)
+	}
+	return value
+}
+
+func parseUint32(value []byte) (uint32, error) {
+	if len(value) != 4 {
+		return 0, ErrInvalidValue
+	}
+	return binary.LittleEndian.Uint32(value), nil
+}
+
+func parseVarInt(value []byte) (uint64, error) {
+	r := &reader{data: value}
+	v, err := r.readVarInt()
+	if err != nil || r.remaining() != 0 {
+		return 0, ErrInvalidValue
+	}
+	return v, nil
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func uint64Bytes(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
+
+func varIntBytes(v uint64) []byte {
+	switch {
+	case v < 0xfd:
+		return []byte{byte(v)}
+	case v <= 0xffff:
+		b := []byte{0xfd, 0, 0}
+		binary.LittleEndian.PutUint16(b[1:], uint16(v))
+		return b
+	case v <= 0xffffffff:
+		return append([]byte{0xfe}, uint32Bytes(uint32(v))...)
+	}
+	return append([]byte{0xff}, uint64Bytes(v)...)
+}
+
+func writePair(buffer *bytes.Buffer, keyType byte, keyData []byte, value []byte) {
+	buffer.Write(varIntBytes(uint64(1 + len(keyData))))
+	buffer.WriteByte(keyType)
+	buffer.Write(keyData)
+	buffer.Write(varIntBytes(uint64(len(value))))
+	buffer.Write(value)
+}
+
+func writeUnknowns(buffer *bytes.Buffer, unknowns []*Unknown) {
+	for _, unknown := range unknowns {
+		buffer.Write(varIntBytes(uint64(len(unknown.Key))))
+		buffer.Write(unknown.Key)
+		buffer.Write(varIntBytes(uint64(len(unknown.Value))))
+		buffer.Write(unknown.Value)
+	}
+}
+
+// reader reads PSBT key-value maps from a byte slice.
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *reader) readBytes(n int) ([]byte, error) {
+	if n < 0 || n > r.remaining() {
+		return nil, ErrUnexpectedEOF
+	}
+	b := append([]byte{}, r.data[r.pos:r.pos+n]...)
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) readVarInt() (uint64, error) {
+	prefix, err := r.readBytes(1)
+	if err != nil {
+		return 0, err
+	}
+	var size int
+	switch prefix[0] {
+	case 0xfd:
+		size = 2
+	case 0xfe:
+		size = 4
+	case 0xff:
+		size = 8
+	default:
+		return uint64(prefix[0]), nil
+	}
+	b, err := r.readBytes(size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for i := size - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, nil
+}
+
+func (r *reader) readVarBytes() ([]byte, error) {
+	n, err := r.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.remaining()) {
+		return nil, ErrUnexpectedEOF
+	}
+	return r.readBytes(int(n))
+}
+
+// readMap reads key-value pairs up to the 0x00 separator, rejecting
+// duplicate keys.
+func (r *reader) readMap() ([]*Unknown, error) {
+	var pairs []*Unknown
+	seen := make(map[string]bool)
+	for {
+		key, err := r.readVarBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(key) == 0 {
+			return pairs, nil
+		}
+		if seen[string(key)] {
+			return nil, ErrDuplicateKey
+		}
+		seen[string(key)] = true
+		value, err := r.readVarBytes()
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, &Unknown{Key: key, Value: value})
+	}
+}
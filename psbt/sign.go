@@ -0,0 +1,394 @@
+package psbt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/crypto"
+	"github.com/icodeface/go-blockchain-kit/keystore"
+	"github.com/icodeface/go-blockchain-kit/tx"
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+var (
+	// ErrMissingUtxo is returned when an input has neither a witness nor a
+	// non-witness UTXO, or signing a non-segwit input without the
+	// non-witness UTXO
+	ErrMissingUtxo = errors.New("Input is missing the output it spends")
+
+	// ErrUtxoMismatch is returned when the non-witness UTXO isn't the
+	// transaction the input spends from
+	ErrUtxoMismatch = errors.New("Non-witness UTXO doesn't match the input")
+
+	// ErrMissingScript is returned when a P2SH or P2WSH input lacks its
+	// redeem or witness script
+	ErrMissingScript = errors.New("Input is missing its redeem or witness script")
+
+	// ErrScriptMismatch is returned when a redeem or witness script doesn't
+	// hash to the spent output
+	ErrScriptMismatch = errors.New("Redeem or witness script doesn't match the spent output")
+
+	// ErrDerivationMismatch is returned when the key derived from a BIP32
+	// derivation isn't the recorded public key
+	ErrDerivationMismatch = errors.New("Derived key doesn't match the BIP32 derivation")
+)
+
+// AddDerivation derives path from master and records the origin of the
+// derived key in every input and output paying to it, either directly as
+// P2PKH, P2SH-P2WPKH, P2WPKH or BIP86 P2TR, or through a redeem or witness
+// script holding its public key. It reports whether anything matched.
+func (packet *Packet) AddDerivation(master *keystore.Key, path string) (bool, error) {
+	if master.Depth != 0 {
+		return false, keystore.ErrNotMasterKey
+	}
+	fingerprint, err := master.Fingerprint()
+	if err != nil {
+		return false, err
+	}
+	derivationPath, err := keystore.ParseDerivationPath(path)
+	if err != nil {
+		return false, err
+	}
+	key, err := master.DerivePath(derivationPath)
+	if err != nil {
+		return false, err
+	}
+	defer key.Zero()
+	publicKey := key.PublicKey()
+
+	matched := false
+	for _, in := range packet.Inputs {
+		prevOut, err := in.prevOut()
+		if err != nil {
+			continue
+		}
+		match, err := addKeyOrigin(publicKey, fingerprint, derivationPath.Indexes, prevOut.PkScript, in.RedeemScript, in.WitnessScript,
+			&in.RedeemScript, &in.TaprootInternalKey, &in.Bip32Derivations, &in.TaprootBip32Derivations)
+		if err != nil {
+			return false, err
+		}
+		matched = matched || match
+	}
+	for _, out := range packet.Outputs {
+		match, err := addKeyOrigin(publicKey, fingerprint, derivationPath.Indexes, out.Script, out.RedeemScript, out.WitnessScript,
+			&out.RedeemScript, &out.TaprootInternalKey, &out.Bip32Derivations, &out.TaprootBip32Derivations)
+		if err != nil {
+			return false, err
+		}
+		matched = matched || match
+	}
+	return matched, nil
+}
+
+// addKeyOrigin adds the derivation of publicKey to an input or output
+// paying to pkScript when it involves the key.
+func addKeyOrigin(publicKey *keystore.Key, fingerprint []byte, path []uint32, pkScript, redeemScript, witnessScript []byte,
+	redeemScriptField *[]byte, internalKeyField *[]byte, derivations *[]*Bip32Derivation, taprootDerivations *[]*TaprootBip32Derivation) (bool, error) {
+	addrType, _ := tx.ScriptAddressType(pkScript)
+	keyScript, err := tx.KeyPkScript(publicKey, addrType)
+	direct := err == nil && bytes.Equal(keyScript, pkScript)
+
+	if direct && addrType == utils.AddressP2TR {
+		xOnly := publicKey.XOnlyPublicKey()
+		for _, derivation := range *taprootDerivations {
+			if bytes.Equal(derivation.XOnlyPubKey, xOnly) {
+				return true, nil
+			}
+		}
+		*taprootDerivations = append(*taprootDerivations, &TaprootBip32Derivation{
+			XOnlyPubKey: xOnly,
+			Fingerprint: copyBytes(fingerprint),
+			Path:        append([]uint32{}, path...),
+		})
+		*internalKeyField = xOnly
+		return true, nil
+	}
+
+	if direct && addrType == utils.AddressP2SH && redeemScript == nil {
+		if *redeemScriptField, err = publicKey.P2WPKHRedeemScript(); err != nil {
+			return false, err
+		}
+	}
+	if !direct && !bytes.Contains(redeemScript, publicKey.Key) && !bytes.Contains(witnessScript, publicKey.Key) {
+		return false, nil
+	}
+
+	for _, derivation := range *derivations {
+		if bytes.Equal(derivation.PubKey, publicKey.Key) {
+			return true, nil
+		}
+	}
+	*derivations = append(*derivations, &Bip32Derivation{
+		PubKey:      copyBytes(publicKey.Key),
+		Fingerprint: copyBytes(fingerprint),
+		Path:        append([]uint32{}, path...),
+	})
+	return true, nil
+}
+
+// Sign signs every input holding a BIP32 or taproot derivation from
+// master, for the ECDSA public keys, the taproot key path and the
+// recorded tapleaves. Non-segwit inputs are only signed with their
+// non-witness UTXO. It returns the number of signatures added.
+func (packet *Packet) Sign(master *keystore.Key) (int, error) {
+	if !master.IsPrivate {
+		return 0, keystore.ErrSignWithPublicKey
+	}
+	fingerprint, err := master.Fingerprint()
+	if err != nil {
+		return 0, err
+	}
+	unsigned, err := packet.UnsignedTx()
+	if err != nil {
+		return 0, err
+	}
+
+	signed := 0
+	for i, in := range packet.Inputs {
+		if in.IsFinalized() {
+			continue
+		}
+		for _, derivation := range in.Bip32Derivations {
+			if !bytes.Equal(derivation.Fingerprint, fingerprint) || in.hasPartialSig(derivation.PubKey) {
+				continue
+			}
+			if err := packet.signECDSA(unsigned, i, master, derivation); err != nil {
+				return signed, err
+			}
+			signed++
+		}
+		for _, derivation := range in.TaprootBip32Derivations {
+			if !bytes.Equal(derivation.Fingerprint, fingerprint) {
+				continue
+			}
+			n, err := packet.signTaproot(unsigned, i, master, derivation)
+			if err != nil {
+				return signed, err
+			}
+			signed += n
+		}
+	}
+	return signed, nil
+}
+
+func (packet *Packet) signECDSA(unsigned *tx.Tx, i int, master *keystore.Key, derivation *Bip32Derivation) error {
+	in := packet.Inputs[i]
+	key, err := master.DerivePath(derivation.DerivationPath())
+	if err != nil {
+		return err
+	}
+	defer key.Zero()
+	if !bytes.Equal(key.PublicKey().Key, derivation.PubKey) {
+		return ErrDerivationMismatch
+	}
+
+	prevOut, err := in.prevOut()
+	if err != nil {
+		return err
+	}
+	hashType := in.SigHashType
+	if hashType == 0 {
+		hashType = tx.SigHashAll
+	}
+
+	script := prevOut.PkScript
+	addrType, program := tx.ScriptAddressType(script)
+	if addrType == utils.AddressP2SH {
+		if in.RedeemScript == nil {
+			return ErrMissingScript
+		}
+		hash160, err := utils.Hash160(in.RedeemScript)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(hash160, program) {
+			return ErrScriptMismatch
+		}
+		script = in.RedeemScript
+		addrType, program = tx.ScriptAddressType(script)
+	}
+
+	if addrType == utils.AddressP2PKH || addrType == utils.AddressP2WPKH {
+		hash160, err := utils.Hash160(derivation.PubKey)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(hash160, program) {
+			return ErrDerivationMismatch
+		}
+	}
+
+	var sigHash []byte
+	switch addrType {
+	case utils.AddressP2WPKH:
+		sigHash, err = unsigned.WitnessV0SigHash(i, tx.P2WPKHScriptCode(program), prevOut.Value, hashType)
+	case utils.AddressP2WSH:
+		if in.WitnessScript == nil {
+			return ErrMissingScript
+		}
+		if hash := sha256.Sum256(in.WitnessScript); !bytes.Equal(hash[:], program) {
+			return ErrScriptMismatch
+		}
+		sigHash, err = unsigned.WitnessV0SigHash(i, in.WitnessScript, prevOut.Value, hashType)
+	default:
+		// The amount isn't signed here, so a witness UTXO could lie about it
+		var nonWitnessOut *tx.TxOut
+		if nonWitnessOut, err = in.nonWitnessPrevOut(); err != nil {
+			return err
+		}
+		if !bytes.Equal(nonWitnessOut.PkScript, prevOut.PkScript) {
+			return ErrUtxoMismatch
+		}
+		sigHash, err = unsigned.LegacySigHash(i, script, hashType)
+	}
+	if err != nil {
+		return err
+	}
+
+	sig, err := key.Sign(sigHash)
+	if err != nil {
+		return err
+	}
+	in.PartialSigs = append(in.PartialSigs, &PartialSig{
+		PubKey:    copyBytes(derivation.PubKey),
+		Signature: append(sig.Serialize(), byte(hashType)),
+	})
+	return nil
+}
+
+// signTaproot signs input i with the key of a taproot derivation: the key
+// path when the key tweaks to the output key, and every leaf of the
+// derivation that a TaprootLeafScripts entry commits to. Inputs that can't
+// be signed, for instance because another input's UTXO is missing, are
+// skipped.
+func (packet *Packet) signTaproot(unsigned *tx.Tx, i int, master *keystore.Key, derivation *TaprootBip32Derivation) (int, error) {
+	in := packet.Inputs[i]
+	prevOuts := make([]*tx.TxOut, len(packet.Inputs))
+	for j, other := range packet.Inputs {
+		prevOut, err := other.prevOut()
+		if err != nil {
+			return 0, nil
+		}
+		prevOuts[j] = prevOut
+	}
+	addrType, program := tx.ScriptAddressType(prevOuts[i].PkScript)
+	if addrType != utils.AddressP2TR {
+		return 0, nil
+	}
+
+	key, err := master.DerivePath(derivation.DerivationPath())
+	if err != nil {
+		return 0, err
+	}
+	defer key.Zero()
+	if !bytes.Equal(key.XOnlyPublicKey(), derivation.XOnlyPubKey) {
+		return 0, ErrDerivationMismatch
+	}
+
+	signed := 0
+	if len(derivation.LeafHashes) == 0 && in.TaprootKeySpendSig == nil {
+		// Without the merkle root of a script tree, the key doesn't tweak
+		// to the output key and the key path can't be signed
+		outputKey, _, err := crypto.TweakTaprootPublicKey(derivation.XOnlyPubKey, in.TaprootMerkleRoot)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(outputKey, program) {
+			sigHash, err := unsigned.TaprootSigHash(i, prevOuts, in.SigHashType, nil, nil)
+			if err != nil {
+				return 0, err
+			}
+			sig, err := key.SignTaprootKeyPath(sigHash, in.TaprootMerkleRoot, auxRand())
+			if err != nil {
+				return 0, err
+			}
+			in.TaprootKeySpendSig = tx.TaprootSignature(sig, in.SigHashType)
+			signed++
+		}
+	}
+
+	for _, leafHash := range derivation.LeafHashes {
+		if in.hasTaprootScriptSig(derivation.XOnlyPubKey, leafHash) || !in.hasTaprootLeaf(leafHash) {
+			continue
+		}
+		scriptPath := &tx.ScriptPathSpend{LeafHash: leafHash, CodeSeparatorPos: tx.NoCodeSeparator}
+		sigHash, err := unsigned.TaprootSigHash(i, prevOuts, in.SigHashType, nil, scriptPath)
+		if err != nil {
+			return signed, err
+		}
+		sig, err := key.SignSchnorr(sigHash, auxRand())
+		if err != nil {
+			return signed, err
+		}
+		in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs, &TaprootScriptSpendSig{
+			XOnlyPubKey: copyBytes(derivation.XOnlyPubKey),
+			LeafHash:    copyBytes(leafHash),
+			Signature:   tx.TaprootSignature(sig, in.SigHashType),
+		})
+		signed++
+	}
+	return signed, nil
+}
+
+// prevOut returns the output the input spends, from the witness UTXO or
+// the non-witness UTXO.
+func (in *Input) prevOut() (*tx.TxOut, error) {
+	if in.WitnessUtxo != nil {
+		return in.WitnessUtxo, nil
+	}
+	return in.nonWitnessPrevOut()
+}
+
+// nonWitnessPrevOut returns the output the input spends from the
+// non-witness UTXO, checking that it's the transaction the input spends
+// from.
+func (in *Input) nonWitnessPrevOut() (*tx.TxOut, error) {
+	if in.NonWitnessUtxo == nil {
+		return nil, ErrMissingUtxo
+	}
+	if !bytes.Equal(in.NonWitnessUtxo.Hash(), in.PreviousOutPoint.Hash[:]) ||
+		int(in.PreviousOutPoint.Index) >= len(in.NonWitnessUtxo.Outputs) {
+		return nil, ErrUtxoMismatch
+	}
+	return in.NonWitnessUtxo.Outputs[in.PreviousOutPoint.Index], nil
+}
+
+func (in *Input) hasPartialSig(publicKey []byte) bool {
+	for _, sig := range in.PartialSigs {
+		if bytes.Equal(sig.PubKey, publicKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func (in *Input) hasTaprootScriptSig(xOnlyPubKey []byte, leafHash []byte) bool {
+	for _, sig := range in.TaprootScriptSpendSigs {
+		if bytes.Equal(sig.XOnlyPubKey, xOnlyPubKey) && bytes.Equal(sig.LeafHash, leafHash) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTaprootLeaf reports whether one of the input's leaf scripts hashes to
+// leafHash.
+func (in *Input) hasTaprootLeaf(leafHash []byte) bool {
+	for _, leaf := range in.TaprootLeafScripts {
+		if bytes.Equal(tx.TapLeafHash(leaf.LeafVersion, leaf.Script), leafHash) {
+			return true
+		}
+	}
+	return false
+}
+
+func auxRand() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		// Signing without fresh randomness is still secure, see BIP340
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,352 @@
+package tx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/keystore"
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+// DustLimit is the smallest output the builder creates, smaller change is
+// left to the fee
+const DustLimit = int64(546)
+
+var (
+	// ErrNoInputs is returned when building a transaction without UTXOs
+	ErrNoInputs = errors.New("Transaction has no inputs")
+
+	// ErrNoOutputs is returned when building a transaction without outputs
+	ErrNoOutputs = errors.New("Transaction has no outputs")
+
+	// ErrInvalidAmount is returned for outputs of zero or negative value
+	ErrInvalidAmount = errors.New("Output value should be positive")
+
+	// ErrDustOutput is returned for outputs below DustLimit
+	ErrDustOutput = errors.New("Output value is below the dust limit")
+
+	// ErrExcessFee is returned when, without a change address, the inputs
+	// leave more than DustLimit above the estimated fee
+	ErrExcessFee = errors.New("Fee exceeds the estimate by more than the dust limit")
+
+	// ErrInvalidFeeRate is returned when the fee rate isn't positive
+	ErrInvalidFeeRate = errors.New("Fee rate should be positive")
+
+	// ErrInsufficientFunds is returned when the UTXOs don't cover the
+	// outputs and the fee
+	ErrInsufficientFunds = errors.New("Insufficient funds")
+
+	// ErrUnsupportedScript is returned when spending an output the builder
+	// can't sign
+	ErrUnsupportedScript = errors.New("Unsupported output script")
+
+	// ErrKeyNotFound is returned when no key can spend an UTXO
+	ErrKeyNotFound = errors.New("No key found for the UTXO")
+
+	// ErrKeyScriptMismatch is returned when the resolved key doesn't own the
+	// spent output
+	ErrKeyScriptMismatch = errors.New("Key doesn't match the UTXO script")
+)
+
+// UTXO is an unspent output to fund a transaction with.
+type UTXO struct {
+	OutPoint OutPoint
+	Value    int64
+	PkScript []byte
+}
+
+// KeyResolver picks the private key spending an UTXO, typically the
+// derived key of the address it pays to.
+type KeyResolver interface {
+	ResolveKey(utxo *UTXO) (*keystore.Key, error)
+}
+
+// KeyList is a KeyResolver searching a list of private keys for the one
+// whose P2PKH, P2SH-P2WPKH, P2WPKH or BIP86 P2TR script the UTXO pays to.
+type KeyList []*keystore.Key
+
+// ResolveKey returns the first key owning utxo
+func (keys KeyList) ResolveKey(utxo *UTXO) (*keystore.Key, error) {
+	addrType, _ := ScriptAddressType(utxo.PkScript)
+	for _, key := range keys {
+		script, err := KeyPkScript(key, addrType)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(script, utxo.PkScript) {
+			return key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// SignedTx is a fully signed transaction ready to broadcast.
+type SignedTx struct {
+	Tx          *Tx
+	Fee         int64
+	VSize       int64
+	ChangeIndex int // index of the change output, -1 without change
+}
+
+// Hex returns the raw transaction to broadcast.
+func (signed *SignedTx) Hex() string {
+	return signed.Tx.Hex()
+}
+
+// Builder assembles and signs a transaction spending P2PKH, P2SH-P2WPKH,
+// P2WPKH and P2TR key path outputs. Change below DustLimit is dropped.
+type Builder struct {
+	Version  int32
+	LockTime uint32
+
+	// AllowExcessFee lets Sign leave more than DustLimit above the
+	// estimated fee to the miners when there is no change address
+	AllowExcessFee bool
+
+	network      *utils.Network
+	utxos        []*UTXO
+	outputs      []*TxOut
+	changeScript []byte
+	feeRate      int64
+}
+
+// NewBuilder returns a version 2 transaction builder paying to addresses of
+// net. A nil net means MainNet.
+func NewBuilder(net *utils.Network) *Builder {
+	if net == nil {
+		net = utils.MainNet
+	}
+	return &Builder{Version: 2, network: net}
+}
+
+// AddUTXO adds an output to spend.
+func (builder *Builder) AddUTXO(utxo *UTXO) {
+	builder.utxos = append(builder.utxos, utxo)
+}
+
+// AddOutput pays value satoshis to address. value can't be below DustLimit.
+func (builder *Builder) AddOutput(address string, value int64) error {
+	if value <= 0 {
+		return ErrInvalidAmount
+	}
+	if value < DustLimit {
+		return ErrDustOutput
+	}
+	script, err := PayToAddrScript(address, builder.network)
+	if err != nil {
+		return err
+	}
+	builder.outputs = append(builder.outputs, NewTxOut(value, script))
+	return nil
+}
+
+// SetChangeAddress sends whatever the outputs and fee leave to address.
+// Without a change address Sign fails with ErrExcessFee when the remainder
+// exceeds the fee by more than DustLimit, unless AllowExcessFee is set.
+func (builder *Builder) SetChangeAddress(address string) error {
+	script, err := PayToAddrScript(address, builder.network)
+	if err != nil {
+		return err
+	}
+	builder.changeScript = script
+	return nil
+}
+
+// SetFeeRate sets the fee rate in satoshis per virtual byte.
+func (builder *Builder) SetFeeRate(satPerVByte int64) {
+	builder.feeRate = satPerVByte
+}
+
+// Sign builds the transaction, adds change and signs every input with the
+// key resolver picks for it. ECDSA inputs sign SIGHASH_ALL and taproot
+// inputs SIGHASH_DEFAULT.
+func (builder *Builder) Sign(resolver KeyResolver) (*SignedTx, error) {
+	if len(builder.utxos) == 0 {
+		return nil, ErrNoInputs
+	}
+	if len(builder.outputs) == 0 {
+		return nil, ErrNoOutputs
+	}
+	if builder.feeRate <= 0 {
+		return nil, ErrInvalidFeeRate
+	}
+
+	keys := make([]*keystore.Key, len(builder.utxos))
+	prevOuts := make([]*TxOut, len(builder.utxos))
+	var inputValue, outputValue int64
+	tx := NewTx(builder.Version)
+	tx.LockTime = builder.LockTime
+	for i, utxo := range builder.utxos {
+		key, err := resolver.ResolveKey(utxo)
+		if err != nil {
+			return nil, err
+		}
+		addrType, _ := ScriptAddressType(utxo.PkScript)
+		script, err := KeyPkScript(key, addrType)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(script, utxo.PkScript) {
+			return nil, ErrKeyScriptMismatch
+		}
+		keys[i] = key
+		prevOuts[i] = NewTxOut(utxo.Value, utxo.PkScript)
+		inputValue += utxo.Value
+		tx.AddInput(NewTxIn(&utxo.OutPoint))
+	}
+	for _, out := range builder.outputs {
+		outputValue += out.Value
+		tx.AddOutput(NewTxOut(out.Value, out.PkScript))
+	}
+
+	changeIndex := -1
+	if builder.changeScript != nil {
+		tx.AddOutput(NewTxOut(0, builder.changeScript))
+		change := inputValue - outputValue - builder.fee(tx, prevOuts)
+		if change >= DustLimit {
+			changeIndex = len(tx.Outputs) - 1
+			tx.Outputs[changeIndex].Value = change
+		} else {
+			tx.Outputs = tx.Outputs[:len(tx.Outputs)-1]
+		}
+	}
+	if changeIndex < 0 {
+		fee := builder.fee(tx, prevOuts)
+		if inputValue-outputValue < fee {
+			return nil, ErrInsufficientFunds
+		}
+		// Dust change dropped into the fee is expected; without a change
+		// address the excess is most likely a mistake
+		if builder.changeScript == nil && !builder.AllowExcessFee && inputValue-outputValue-fee > DustLimit {
+			return nil, ErrExcessFee
+		}
+	}
+
+	for i, key := range keys {
+		if err := signInput(tx, i, prevOuts, key); err != nil {
+			return nil, err
+		}
+	}
+
+	fee := inputValue
+	for _, out := range tx.Outputs {
+		fee -= out.Value
+	}
+	return &SignedTx{Tx: tx, Fee: fee, VSize: tx.VSize(), ChangeIndex: changeIndex}, nil
+}
+
+// fee returns the fee of tx once signed, estimated with maximum size
+// signatures.
+func (builder *Builder) fee(tx *Tx, prevOuts []*TxOut) int64 {
+	estimate := tx.Copy()
+	for i, in := range estimate.Inputs {
+		addrType, _ := ScriptAddressType(prevOuts[i].PkScript)
+		switch addrType {
+		case utils.AddressP2PKH:
+			in.SignatureScript = append(PushData(make([]byte, 73)), PushData(make([]byte, 33))...)
+		case utils.AddressP2SH:
+			in.SignatureScript = PushData(make([]byte, 22))
+			in.Witness = [][]byte{make([]byte, 73), make([]byte, 33)}
+		case utils.AddressP2WPKH:
+			in.Witness = [][]byte{make([]byte, 73), make([]byte, 33)}
+		case utils.AddressP2TR:
+			in.Witness = [][]byte{make([]byte, 64)}
+		}
+	}
+	return estimate.VSize() * builder.feeRate
+}
+
+// KeyPkScript returns the output script of key for an address type: P2PKH,
+// P2SH for P2SH-P2WPKH, P2WPKH or P2TR for BIP86 key path outputs.
+func KeyPkScript(key *keystore.Key, addrType utils.AddressType) ([]byte, error) {
+	publicKey := key.PublicKey().Key
+	switch addrType {
+	case utils.AddressP2PKH, utils.AddressP2WPKH:
+		hash160, err := utils.Hash160(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if addrType == utils.AddressP2PKH {
+			return P2PKHScript(hash160), nil
+		}
+		return WitnessProgramScript(0, hash160), nil
+	case utils.AddressP2SH:
+		redeemScript, err := key.P2WPKHRedeemScript()
+		if err != nil {
+			return nil, err
+		}
+		hash160, err := utils.Hash160(redeemScript)
+		if err != nil {
+			return nil, err
+		}
+		return P2SHScript(hash160), nil
+	case utils.AddressP2TR:
+		outputKey, _, err := key.TaprootOutputKey(nil)
+		if err != nil {
+			return nil, err
+		}
+		return WitnessProgramScript(1, outputKey), nil
+	}
+	return nil, ErrUnsupportedScript
+}
+
+// signInput signs input i of tx, which spends prevOuts[i] owned by key,
+// and sets its signature script and witness.
+func signInput(tx *Tx, i int, prevOuts []*TxOut, key *keystore.Key) error {
+	in := tx.Inputs[i]
+	addrType, _ := ScriptAddressType(prevOuts[i].PkScript)
+	if addrType == utils.AddressP2TR {
+		sigHash, err := tx.TaprootSigHash(i, prevOuts, SigHashDefault, nil, nil)
+		if err != nil {
+			return err
+		}
+		auxRand := make([]byte, 32)
+		if _, err := rand.Read(auxRand); err != nil {
+			return err
+		}
+		sig, err := key.SignTaprootKeyPath(sigHash, nil, auxRand)
+		if err != nil {
+			return err
+		}
+		in.Witness = [][]byte{TaprootSignature(sig, SigHashDefault)}
+		return nil
+	}
+
+	publicKey := key.PublicKey().Key
+	hash160, err := utils.Hash160(publicKey)
+	if err != nil {
+		return err
+	}
+	var sigHash []byte
+	if addrType == utils.AddressP2PKH {
+		sigHash, err = tx.LegacySigHash(i, prevOuts[i].PkScript, SigHashAll)
+	} else {
+		sigHash, err = tx.WitnessV0SigHash(i, P2WPKHScriptCode(hash160), prevOuts[i].Value, SigHashAll)
+	}
+	if err != nil {
+		return err
+	}
+	sig, err := key.Sign(sigHash)
+	if err != nil {
+		return err
+	}
+	encoded := append(sig.Serialize(), byte(SigHashAll))
+
+	switch addrType {
+	case utils.AddressP2PKH:
+		in.SignatureScript = append(PushData(encoded), PushData(publicKey)...)
+	case utils.AddressP2SH:
+		redeemScript, err := key.P2WPKHRedeemScript()
+		if err != nil {
+			return err
+		}
+		in.SignatureScript = PushData(redeemScript)
+		in.Witness = [][]byte{encoded, publicKey}
+	case utils.AddressP2WPKH:
+		in.Witness = [][]byte{encoded, publicKey}
+	default:
+		return ErrUnsupportedScript
+	}
+	return nil
+}
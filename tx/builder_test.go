@@ -0,0 +1,313 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/icodeface/go-blockchain-kit/crypto"
+	"github.com/icodeface/go-blockchain-kit/keystore"
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+// testUTXOs returns one key and UTXO per address type
+func testUTXOs(t *testing.T, addrTypes ...utils.AddressType) (KeyList, []*UTXO) {
+	t.Helper()
+	master, err := keystore.NewMasterKey(mustDecodeHex("000102030405060708090a0b0c0d0e0f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys KeyList
+	var utxos []*UTXO
+	for i, addrType := range addrTypes {
+		key, err := master.NewChildKey(uint32(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkScript, err := KeyPkScript(key, addrType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		utxo := &UTXO{Value: int64(10000 * (i + 1)), PkScript: pkScript}
+		utxo.OutPoint.Hash[0] = byte(i)
+		keys = append(keys, key)
+		utxos = append(utxos, utxo)
+	}
+	return keys, utxos
+}
+
+// scriptPushes splits a signature script made of direct pushes
+func scriptPushes(t *testing.T, script []byte) [][]byte {
+	t.Helper()
+	var pushes [][]byte
+	for len(script) > 0 {
+		size := int(script[0])
+		if size == 0 || size >= 0x4c || size >= len(script) {
+			t.Fatalf("unexpected signature script %x", script)
+		}
+		pushes = append(pushes, script[1:1+size])
+		script = script[1+size:]
+	}
+	return pushes
+}
+
+// verifyECDSAInput checks an ECDSA signature and its public key against the
+// sighash of input i
+func verifyECDSAInput(t *testing.T, signed *Tx, i int, prevOut *TxOut, sigWithType []byte, publicKey []byte, witness bool) {
+	t.Helper()
+	if sigWithType[len(sigWithType)-1] != byte(SigHashAll) {
+		t.Fatalf("input %d: hash type %#x", i, sigWithType[len(sigWithType)-1])
+	}
+	hash160, err := utils.Hash160(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sigHash []byte
+	if witness {
+		sigHash, err = signed.WitnessV0SigHash(i, P2WPKHScriptCode(hash160), prevOut.Value, SigHashAll)
+	} else {
+		if !bytes.Equal(prevOut.PkScript, P2PKHScript(hash160)) {
+			t.Fatalf("input %d: public key doesn't match the script", i)
+		}
+		sigHash, err = signed.LegacySigHash(i, prevOut.PkScript, SigHashAll)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.ParseDERSignature(sigWithType[:len(sigWithType)-1])
+	if err != nil {
+		t.Fatalf("input %d: %v", i, err)
+	}
+	pub, err := crypto.ParsePublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("input %d: %v", i, err)
+	}
+	if !pub.Verify(sigHash, sig) {
+		t.Errorf("input %d: signature doesn't verify", i)
+	}
+	if !sig.IsLowS() {
+		t.Errorf("input %d: signature has a high S", i)
+	}
+}
+
+func TestBuilderSign(t *testing.T) {
+	keys, utxos := testUTXOs(t, utils.AddressP2PKH, utils.AddressP2SH, utils.AddressP2WPKH, utils.AddressP2TR)
+	builder := NewBuilder(nil)
+	for _, utxo := range utxos {
+		builder.AddUTXO(utxo)
+	}
+	if err := builder.AddOutput("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", 50000); err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.AddOutput("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", 20000); err != nil {
+		t.Fatal(err)
+	}
+	changeAddress, err := keys[0].P2WPKHAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.SetChangeAddress(changeAddress); err != nil {
+		t.Fatal(err)
+	}
+	builder.SetFeeRate(5)
+
+	signed, err := builder.Sign(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.ChangeIndex != 2 {
+		t.Fatalf("change index %d, want 2", signed.ChangeIndex)
+	}
+	// Signatures may be shorter than the estimate
+	if signed.Fee < signed.VSize*5 || signed.Fee > signed.VSize*5+5*4 {
+		t.Errorf("fee %d for %d vbytes at 5 sat/vbyte", signed.Fee, signed.VSize)
+	}
+	if signed.Fee+signed.Tx.Outputs[2].Value != 100000-70000 {
+		t.Errorf("fee %d and change %d don't add up", signed.Fee, signed.Tx.Outputs[2].Value)
+	}
+
+	parsed, err := DeserializeHex(signed.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevOuts := make([]*TxOut, len(utxos))
+	for i, utxo := range utxos {
+		prevOuts[i] = NewTxOut(utxo.Value, utxo.PkScript)
+	}
+
+	// P2PKH
+	in := parsed.Inputs[0]
+	pushes := scriptPushes(t, in.SignatureScript)
+	if len(pushes) != 2 || len(in.Witness) != 0 {
+		t.Fatalf("P2PKH input: %d pushes, %d witness items", len(pushes), len(in.Witness))
+	}
+	verifyECDSAInput(t, parsed, 0, prevOuts[0], pushes[0], pushes[1], false)
+
+	// P2SH-P2WPKH
+	in = parsed.Inputs[1]
+	pushes = scriptPushes(t, in.SignatureScript)
+	if len(pushes) != 1 || len(in.Witness) != 2 {
+		t.Fatalf("P2SH-P2WPKH input: %d pushes, %d witness items", len(pushes), len(in.Witness))
+	}
+	redeemHash, _ := utils.Hash160(pushes[0])
+	pubKeyHash, _ := utils.Hash160(in.Witness[1])
+	if !bytes.Equal(prevOuts[1].PkScript, P2SHScript(redeemHash)) || !bytes.Equal(pushes[0], WitnessProgramScript(0, pubKeyHash)) {
+		t.Errorf("P2SH-P2WPKH redeem script %x doesn't match", pushes[0])
+	}
+	verifyECDSAInput(t, parsed, 1, prevOuts[1], in.Witness[0], in.Witness[1], true)
+
+	// P2WPKH
+	in = parsed.Inputs[2]
+	if len(in.SignatureScript) != 0 || len(in.Witness) != 2 {
+		t.Fatalf("P2WPKH input: %x, %d witness items", in.SignatureScript, len(in.Witness))
+	}
+	pubKeyHash, _ = utils.Hash160(in.Witness[1])
+	if !bytes.Equal(prevOuts[2].PkScript, WitnessProgramScript(0, pubKeyHash)) {
+		t.Error("P2WPKH public key doesn't match the script")
+	}
+	verifyECDSAInput(t, parsed, 2, prevOuts[2], in.Witness[0], in.Witness[1], true)
+
+	// P2TR key path, SIGHASH_DEFAULT
+	in = parsed.Inputs[3]
+	if len(in.SignatureScript) != 0 || len(in.Witness) != 1 || len(in.Witness[0]) != 64 {
+		t.Fatalf("P2TR input: %x, witness %x", in.SignatureScript, in.Witness)
+	}
+	sigHash, err := parsed.TaprootSigHash(3, prevOuts, SigHashDefault, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !crypto.SchnorrVerify(prevOuts[3].PkScript[2:], sigHash, in.Witness[0]) {
+		t.Error("P2TR signature doesn't verify")
+	}
+}
+
+func TestBuilderFee(t *testing.T) {
+	keys, utxos := testUTXOs(t, utils.AddressP2WPKH)
+	const destination = "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"
+
+	// 10000 - 9500 leaves 500, below the dust limit above a 110 sat fee
+	builder := NewBuilder(nil)
+	builder.AddUTXO(utxos[0])
+	if err := builder.AddOutput(destination, 9500); err != nil {
+		t.Fatal(err)
+	}
+	builder.SetFeeRate(1)
+	signed, err := builder.Sign(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Fee != 500 || signed.ChangeIndex != -1 {
+		t.Errorf("fee %d, change index %d", signed.Fee, signed.ChangeIndex)
+	}
+
+	builder.SetFeeRate(20)
+	if _, err := builder.Sign(keys); err != ErrInsufficientFunds {
+		t.Errorf("fee above the remainder: got %v, want %v", err, ErrInsufficientFunds)
+	}
+
+	// Without change, a remainder well above the fee isn't burnt silently
+	builder = NewBuilder(nil)
+	builder.AddUTXO(utxos[0])
+	if err := builder.AddOutput(destination, 5000); err != nil {
+		t.Fatal(err)
+	}
+	builder.SetFeeRate(1)
+	if _, err := builder.Sign(keys); err != ErrExcessFee {
+		t.Errorf("excess fee: got %v, want %v", err, ErrExcessFee)
+	}
+	builder.AllowExcessFee = true
+	if signed, err := builder.Sign(keys); err != nil || signed.Fee != 5000 {
+		t.Errorf("allowed excess fee: got %v, %v", signed, err)
+	}
+
+	// With a change address the remainder comes back
+	builder.AllowExcessFee = false
+	changeAddress, _ := keys[0].P2WPKHAddress()
+	if err := builder.SetChangeAddress(changeAddress); err != nil {
+		t.Fatal(err)
+	}
+	signed, err = builder.Sign(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.ChangeIndex != 1 || signed.Fee > 200 {
+		t.Errorf("fee %d, change index %d", signed.Fee, signed.ChangeIndex)
+	}
+
+	// Change below the dust limit goes to the fee, even when dropping the
+	// change output leaves more than the dust limit above the fee
+	for _, value := range []int64{8050, 8200, 8330} {
+		builder = NewBuilder(nil)
+		builder.AddUTXO(utxos[0])
+		if err := builder.AddOutput(destination, value); err != nil {
+			t.Fatal(err)
+		}
+		if err := builder.SetChangeAddress(changeAddress); err != nil {
+			t.Fatal(err)
+		}
+		builder.SetFeeRate(10)
+		signed, err := builder.Sign(keys)
+		if err != nil {
+			t.Errorf("output of %d: %v", value, err)
+			continue
+		}
+		if signed.ChangeIndex != -1 || signed.Fee != 10000-value {
+			t.Errorf("output of %d: fee %d, change index %d", value, signed.Fee, signed.ChangeIndex)
+		}
+	}
+}
+
+func TestBuilderInvalid(t *testing.T) {
+	keys, utxos := testUTXOs(t, utils.AddressP2WPKH, utils.AddressP2PKH)
+	const destination = "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"
+
+	builder := NewBuilder(nil)
+	for _, value := range []int64{0, -1} {
+		if err := builder.AddOutput(destination, value); err != ErrInvalidAmount {
+			t.Errorf("value %d: got %v, want %v", value, err, ErrInvalidAmount)
+		}
+	}
+	if err := builder.AddOutput(destination, DustLimit-1); err != ErrDustOutput {
+		t.Errorf("dust output: got %v, want %v", err, ErrDustOutput)
+	}
+	if err := builder.AddOutput(destination, DustLimit); err != nil {
+		t.Errorf("output at the dust limit: %v", err)
+	}
+	if err := builder.AddOutput("tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", 1000); err == nil {
+		t.Error("accepted a testnet address on mainnet")
+	}
+
+	empty := NewBuilder(nil)
+	if _, err := empty.Sign(keys); err != ErrNoInputs {
+		t.Errorf("no inputs: got %v, want %v", err, ErrNoInputs)
+	}
+	empty.AddUTXO(utxos[0])
+	if _, err := empty.Sign(keys); err != ErrNoOutputs {
+		t.Errorf("no outputs: got %v, want %v", err, ErrNoOutputs)
+	}
+	builder.AddUTXO(utxos[1])
+	if _, err := builder.Sign(keys); err != ErrInvalidFeeRate {
+		t.Errorf("no fee rate: got %v, want %v", err, ErrInvalidFeeRate)
+	}
+	builder.SetFeeRate(1)
+	if _, err := builder.Sign(KeyList{keys[0]}); err != ErrKeyNotFound {
+		t.Errorf("missing key: got %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestKeyListResolveKey(t *testing.T) {
+	keys, utxos := testUTXOs(t, utils.AddressP2PKH, utils.AddressP2SH, utils.AddressP2WPKH, utils.AddressP2TR)
+	for i, utxo := range utxos {
+		key, err := keys.ResolveKey(utxo)
+		if err != nil {
+			t.Fatalf("utxo %d: %v", i, err)
+		}
+		if key != keys[i] {
+			t.Errorf("utxo %d: resolved key %d", i, i)
+		}
+	}
+	unknown := &UTXO{Value: 1000, PkScript: mustDecodeHex("0014" + hex.EncodeToString(make([]byte, 20)))}
+	if _, err := keys.ResolveKey(unknown); err != ErrKeyNotFound {
+		t.Errorf("unknown script: got %v, want %v", err, ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,116 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+const (
+	opPushData1   = 0x4c
+	opPushData2   = 0x4d
+	opDup         = 0x76
+	opEqual       = 0x87
+	opEqualVerify = 0x88
+	opHash160     = 0xa9
+	opCheckSig    = 0xac
+	op1           = 0x51
+)
+
+var (
+	// ErrUnsupportedAddress is returned for addresses whose output script
+	// can't be built
+	ErrUnsupportedAddress = errors.New("Unsupported address type")
+)
+
+// P2PKHScript returns the output script paying to a 20 bytes public key
+// hash.
+func P2PKHScript(hash160 []byte) []byte {
+	script := []byte{opDup, opHash160, 0x14}
+	script = append(script, hash160...)
+	return append(script, opEqualVerify, opCheckSig)
+}
+
+// P2SHScript returns the output script paying to a 20 bytes script hash.
+func P2SHScript(hash160 []byte) []byte {
+	script := []byte{opHash160, 0x14}
+	script = append(script, hash160...)
+	return append(script, opEqual)
+}
+
+// WitnessProgramScript returns the output script paying to a SegWit
+// witness program of the given version.
+func WitnessProgramScript(version byte, program []byte) []byte {
+	op := byte(0x00)
+	if version != 0 {
+		op = op1 - 1 + version
+	}
+	return append([]byte{op, byte(len(program))}, program...)
+}
+
+// PayToAddrScript returns the output script paying to an address of net.
+func PayToAddrScript(address string, net *utils.Network) ([]byte, error) {
+	addrType, hash, err := utils.DecodeAddress(address, net)
+	if err != nil {
+		return nil, err
+	}
+
+	switch addrType {
+	case utils.AddressP2PKH:
+		return P2PKHScript(hash), nil
+	case utils.AddressP2SH:
+		return P2SHScript(hash), nil
+	case utils.AddressP2WPKH, utils.AddressP2WSH:
+		return WitnessProgramScript(0, hash), nil
+	case utils.AddressP2TR:
+		return WitnessProgramScript(1, hash), nil
+	case utils.AddressWitnessUnknown:
+		version, program, err := utils.DecodeSegWitAddress(net.Bech32HRP, address)
+		if err != nil {
+			return nil, err
+		}
+		return WitnessProgramScript(version, program), nil
+	}
+	return nil, ErrUnsupportedAddress
+}
+
+// ScriptAddressType reports the kind of output pkScript is along with its
+// hash or witness program. Non standard scripts are AddressUnknown.
+func ScriptAddressType(pkScript []byte) (utils.AddressType, []byte) {
+	switch n := len(pkScript); {
+	case n == 25 && pkScript[0] == opDup && pkScript[1] == opHash160 && pkScript[2] == 0x14 &&
+		pkScript[23] == opEqualVerify && pkScript[24] == opCheckSig:
+		return utils.AddressP2PKH, pkScript[3:23]
+	case n == 23 && pkScript[0] == opHash160 && pkScript[1] == 0x14 && pkScript[22] == opEqual:
+		return utils.AddressP2SH, pkScript[2:22]
+	case n == 22 && pkScript[0] == 0x00 && pkScript[1] == 0x14:
+		return utils.AddressP2WPKH, pkScript[2:]
+	case n == 34 && pkScript[0] == 0x00 && pkScript[1] == 0x20:
+		return utils.AddressP2WSH, pkScript[2:]
+	case n == 34 && pkScript[0] == op1 && pkScript[1] == 0x20:
+		return utils.AddressP2TR, pkScript[2:]
+	case n >= 4 && n <= 42 && pkScript[0] >= op1 && pkScript[0] <= op1+15 && int(pkScript[1]) == n-2:
+		return utils.AddressWitnessUnknown, pkScript[2:]
+	}
+	return utils.AddressUnknown, nil
+}
+
+// PushData returns the minimal script push of data.
+func PushData(data []byte) []byte {
+	buffer := new(bytes.Buffer)
+	switch n := len(data); {
+	case n < opPushData1:
+		buffer.WriteByte(byte(n))
+	case n <= 0xff:
+		buffer.Write([]byte{opPushData1, byte(n)})
+	default:
+		var b [2]byte
+		binary.LittleEndian.PutUint16(b[:], uint16(n))
+		buffer.WriteByte(opPushData2)
+		buffer.Write(b[:])
+	}
+	buffer.Write(data)
+	return buffer.Bytes()
+}
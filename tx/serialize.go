@@ -0,0 +1,350 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+const (
+	witnessMarker = 0x00
+	witnessFlag   = 0x01
+)
+
+var (
+	// ErrUnexpectedEOF is returned when serialized data ends early
+	ErrUnexpectedEOF = errors.New("Unexpected end of transaction data")
+
+	// ErrNonCanonicalVarInt is returned when a variable length integer isn't
+	// minimally encoded
+	ErrNonCanonicalVarInt = errors.New("Non canonical variable length integer")
+
+	// ErrInvalidWitnessFlag is returned when the SegWit marker isn't followed
+	// by the 0x01 flag
+	ErrInvalidWitnessFlag = errors.New("Invalid SegWit flag")
+
+	// ErrSuperfluousWitness is returned when a SegWit serialization carries
+	// no witness data at all
+	ErrSuperfluousWitness = errors.New("SegWit serialization without witness data")
+
+	// ErrTrailingData is returned when bytes remain after the transaction
+	ErrTrailingData = errors.New("Trailing data after transaction")
+)
+
+// Serialize encodes the transaction, in the BIP144 SegWit format when any
+// input has witness data and in the legacy format otherwise.
+func (tx *Tx) Serialize() []byte {
+	return tx.serialize(tx.HasWitness())
+}
+
+// SerializeNoWitness encodes the transaction in the legacy format, as used
+// for the txid.
+func (tx *Tx) SerializeNoWitness() []byte {
+	return tx.serialize(false)
+}
+
+// Hex returns the hex encoding of Serialize.
+func (tx *Tx) Hex() string {
+	return hex.EncodeToString(tx.Serialize())
+}
+
+func (tx *Tx) serialize(witness bool) []byte {
+	buffer := new(bytes.Buffer)
+	writeUint32(buffer, uint32(tx.Version))
+	if witness {
+		buffer.Write([]byte{witnessMarker, witnessFlag})
+	}
+
+	writeVarInt(buffer, uint64(len(tx.Inputs)))
+	for _, in := range tx.Inputs {
+		buffer.Write(in.PreviousOutPoint.Hash[:])
+		writeUint32(buffer, in.PreviousOutPoint.Index)
+		writeVarBytes(buffer, in.SignatureScript)
+		writeUint32(buffer, in.Sequence)
+	}
+
+	writeVarInt(buffer, uint64(len(tx.Outputs)))
+	for _, out := range tx.Outputs {
+		writeTxOut(buffer, out)
+	}
+
+	if witness {
+		for _, in := range tx.Inputs {
+			writeVarInt(buffer, uint64(len(in.Witness)))
+			for _, item := range in.Witness {
+				writeVarBytes(buffer, item)
+			}
+		}
+	}
+
+	writeUint32(buffer, tx.LockTime)
+	return buffer.Bytes()
+}
+
+// Deserialize decodes a transaction in the legacy or SegWit format. The
+// whole of data must be a single transaction.
+func Deserialize(data []byte) (*Tx, error) {
+	return deserialize(data, true)
+}
+
+// DeserializeNoWitness decodes a transaction in the legacy format only, so
+// a zero input count means no inputs rather than the SegWit marker. Unsigned
+// transactions without inputs, as found in PSBTs, need it.
+func DeserializeNoWitness(data []byte) (*Tx, error) {
+	return deserialize(data, false)
+}
+
+func deserialize(data []byte, allowWitness bool) (*Tx, error) {
+	r := &reader{data: data}
+	tx := &Tx{}
+
+	version, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	tx.Version = int32(version)
+
+	// A zero input count is the SegWit marker, real transactions always
+	// have inputs
+	witness := false
+	inputCount, err := r.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if inputCount == 0 && allowWitness {
+		flag, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if flag != witnessFlag {
+			return nil, ErrInvalidWitnessFlag
+		}
+		witness = true
+		if inputCount, err = r.readVarInt(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Every input takes at least 41 bytes, which bounds the allocation
+	if inputCount > uint64(r.remaining()/41) {
+		return nil, ErrUnexpectedEOF
+	}
+	tx.Inputs = make([]*TxIn, inputCount)
+	for i := range tx.Inputs {
+		in := &TxIn{}
+		hash, err := r.readBytes(HashLength)
+		if err != nil {
+			return nil, err
+		}
+		copy(in.PreviousOutPoint.Hash[:], hash)
+		if in.PreviousOutPoint.Index, err = r.readUint32(); err != nil {
+			return nil, err
+		}
+		if in.SignatureScript, err = r.readVarBytes(); err != nil {
+			return nil, err
+		}
+		if in.Sequence, err = r.readUint32(); err != nil {
+			return nil, err
+		}
+		tx.Inputs[i] = in
+	}
+
+	// Every output takes at least 9 bytes
+	outputCount, err := r.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if outputCount > uint64(r.remaining()/9) {
+		return nil, ErrUnexpectedEOF
+	}
+	tx.Outputs = make([]*TxOut, outputCount)
+	for i := range tx.Outputs {
+		if tx.Outputs[i], err = r.readTxOut(); err != nil {
+			return nil, err
+		}
+	}
+
+	if witness {
+		for _, in := range tx.Inputs {
+			itemCount, err := r.readVarInt()
+			if err != nil {
+				return nil, err
+			}
+			if itemCount > uint64(r.remaining()) {
+				return nil, ErrUnexpectedEOF
+			}
+			for j := uint64(0); j < itemCount; j++ {
+				item, err := r.readVarBytes()
+				if err != nil {
+					return nil, err
+				}
+				in.Witness = append(in.Witness, item)
+			}
+		}
+		if !tx.HasWitness() {
+			return nil, ErrSuperfluousWitness
+		}
+	}
+
+	if tx.LockTime, err = r.readUint32(); err != nil {
+		return nil, err
+	}
+	if r.remaining() != 0 {
+		return nil, ErrTrailingData
+	}
+	return tx, nil
+}
+
+// DeserializeHex decodes a hex encoded transaction.
+func DeserializeHex(s string) (*Tx, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Deserialize(data)
+}
+
+// reader reads little-endian bitcoin encodings from a byte slice.
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *reader) readBytes(n int) ([]byte, error) {
+	if n < 0 || n > r.remaining() {
+		return nil, ErrUnexpectedEOF
+	}
+	b := append([]byte{}, r.data[r.pos:r.pos+n]...)
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) readByte() (byte, error) {
+	b, err := r.readBytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *reader) readUint32() (uint32, error) {
+	b, err := r.readBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *reader) readUint64() (uint64, error) {
+	b, err := r.readBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// readVarInt reads a minimally encoded CompactSize integer.
+func (r *reader) readVarInt() (uint64, error) {
+	prefix, err := r.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var value, min uint64
+	switch prefix {
+	case 0xfd:
+		b, err := r.readBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		value, min = uint64(binary.LittleEndian.Uint16(b)), 0xfd
+	case 0xfe:
+		v, err := r.readUint32()
+		if err != nil {
+			return 0, err
+		}
+		value, min = uint64(v), 0x10000
+	case 0xff:
+		v, err := r.readUint64()
+		if err != nil {
+			return 0, err
+		}
+		value, min = v, 0x100000000
+	default:
+		return uint64(prefix), nil
+	}
+
+	if value < min {
+		return 0, ErrNonCanonicalVarInt
+	}
+	return value, nil
+}
+
+func (r *reader) readVarBytes() ([]byte, error) {
+	n, err := r.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.remaining()) {
+		return nil, ErrUnexpectedEOF
+	}
+	return r.readBytes(int(n))
+}
+
+func (r *reader) readTxOut() (*TxOut, error) {
+	value, err := r.readUint64()
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := r.readVarBytes()
+	if err != nil {
+		return nil, err
+	}
+	return NewTxOut(int64(value), pkScript), nil
+}
+
+func writeUint32(buffer *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buffer.Write(b[:])
+}
+
+func writeUint64(buffer *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	buffer.Write(b[:])
+}
+
+// writeVarInt writes v as a minimally encoded CompactSize integer.
+func writeVarInt(buffer *bytes.Buffer, v uint64) {
+	switch {
+	case v < 0xfd:
+		buffer.WriteByte(byte(v))
+	case v <= 0xffff:
+		buffer.WriteByte(0xfd)
+		var b [2]byte
+		binary.LittleEndian.PutUint16(b[:], uint16(v))
+		buffer.Write(b[:])
+	case v <= 0xffffffff:
+		buffer.WriteByte(0xfe)
+		writeUint32(buffer, uint32(v))
+	default:
+		buffer.WriteByte(0xff)
+		writeUint64(buffer, v)
+	}
+}
+
+func writeVarBytes(buffer *bytes.Buffer, b []byte) {
+	writeVarInt(buffer, uint64(len(b)))
+	buffer.Write(b)
+}
+
+func writeTxOut(buffer *bytes.Buffer, out *TxOut) {
+	writeUint64(buffer, uint64(out.Value))
+	writeVarBytes(buffer, out.PkScript)
+}
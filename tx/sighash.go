@@ -0,0 +1,209 @@
+package tx
+
+import (
+	"bytes"
+	"errors"
+)
+
+// SigHashType selects which parts of a transaction a signature commits to.
+type SigHashType uint32
+
+const (
+	SigHashAll          SigHashType = 0x01
+	SigHashNone         SigHashType = 0x02
+	SigHashSingle       SigHashType = 0x03
+	SigHashAnyOneCanPay SigHashType = 0x80
+
+	sigHashMask = 0x1f
+)
+
+const opCodeSeparator = 0xab
+
+var (
+	// ErrInputIndexOutOfRange is returned when signing an input the
+	// transaction doesn't have
+	ErrInputIndexOutOfRange = errors.New("Input index out of range")
+)
+
+// LegacySigHash computes the pre-SegWit signature hash of input inputIndex
+// spending an output locked by subScript. As in Bitcoin Core, SIGHASH_SINGLE
+// without a matching output signs the value 1 instead of failing.
+func (tx *Tx) LegacySigHash(inputIndex int, subScript []byte, hashType SigHashType) ([]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return nil, ErrInputIndexOutOfRange
+	}
+
+	baseType := hashType & sigHashMask
+	if baseType == SigHashSingle && inputIndex >= len(tx.Outputs) {
+		one := make([]byte, HashLength)
+		one[0] = 0x01
+		return one, nil
+	}
+
+	txCopy := tx.Copy()
+	for i, in := range txCopy.Inputs {
+		in.SignatureScript = nil
+		in.Witness = nil
+		if i != inputIndex && (baseType == SigHashNone || baseType == SigHashSingle) {
+			in.Sequence = 0
+		}
+	}
+	signed := txCopy.Inputs[inputIndex]
+
+	switch baseType {
+	case SigHashNone:
+		txCopy.Outputs = nil
+	case SigHashSingle:
+		txCopy.Outputs = txCopy.Outputs[:inputIndex+1]
+		for i := 0; i < inputIndex; i++ {
+			txCopy.Outputs[i] = NewTxOut(-1, nil)
+		}
+	}
+
+	if hashType&SigHashAnyOneCanPay != 0 {
+		txCopy.Inputs = txCopy.Inputs[inputIndex : inputIndex+1]
+	}
+
+	// The legacy serialization, with the script code of the signed input
+	// written as Bitcoin Core does
+	buffer := new(bytes.Buffer)
+	writeUint32(buffer, uint32(txCopy.Version))
+	writeVarInt(buffer, uint64(len(txCopy.Inputs)))
+	for _, in := range txCopy.Inputs {
+		buffer.Write(in.PreviousOutPoint.Hash[:])
+		writeUint32(buffer, in.PreviousOutPoint.Index)
+		if in == signed {
+			writeScriptCode(buffer, subScript)
+		} else {
+			writeVarBytes(buffer, nil)
+		}
+		writeUint32(buffer, in.Sequence)
+	}
+	writeVarInt(buffer, uint64(len(txCopy.Outputs)))
+	for _, out := range txCopy.Outputs {
+		writeTxOut(buffer, out)
+	}
+	writeUint32(buffer, txCopy.LockTime)
+	writeUint32(buffer, uint32(hashType))
+	return doubleSha256(buffer.Bytes()), nil
+}
+
+// WitnessV0SigHash computes the BIP143 signature hash of SegWit version 0
+// input inputIndex spending amount satoshis. scriptCode is the witness
+// script for P2WSH, or P2WPKHScriptCode for P2WPKH.
+func (tx *Tx) WitnessV0SigHash(inputIndex int, scriptCode []byte, amount int64, hashType SigHashType) ([]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return nil, ErrInputIndexOutOfRange
+	}
+
+	baseType := hashType & sigHashMask
+	anyoneCanPay := hashType&SigHashAnyOneCanPay != 0
+	var hashPrevouts, hashSequence, hashOutputs [HashLength]byte
+
+	if !anyoneCanPay {
+		buffer := new(bytes.Buffer)
+		for _, in := range tx.Inputs {
+			buffer.Write(in.PreviousOutPoint.Hash[:])
+			writeUint32(buffer, in.PreviousOutPoint.Index)
+		}
+		copy(hashPrevouts[:], doubleSha256(buffer.Bytes()))
+	}
+
+	if !anyoneCanPay && baseType != SigHashSingle && baseType != SigHashNone {
+		buffer := new(bytes.Buffer)
+		for _, in := range tx.Inputs {
+			writeUint32(buffer, in.Sequence)
+		}
+		copy(hashSequence[:], doubleSha256(buffer.Bytes()))
+	}
+
+	if baseType != SigHashSingle && baseType != SigHashNone {
+		buffer := new(bytes.Buffer)
+		for _, out := range tx.Outputs {
+			writeTxOut(buffer, out)
+		}
+		copy(hashOutputs[:], doubleSha256(buffer.Bytes()))
+	} else if baseType == SigHashSingle && inputIndex < len(tx.Outputs) {
+		buffer := new(bytes.Buffer)
+		writeTxOut(buffer, tx.Outputs[inputIndex])
+		copy(hashOutputs[:], doubleSha256(buffer.Bytes()))
+	}
+
+	in := tx.Inputs[inputIndex]
+	buffer := new(bytes.Buffer)
+	writeUint32(buffer, uint32(tx.Version))
+	buffer.Write(hashPrevouts[:])
+	buffer.Write(hashSequence[:])
+	buffer.Write(in.PreviousOutPoint.Hash[:])
+	writeUint32(buffer, in.PreviousOutPoint.Index)
+	writeVarBytes(buffer, scriptCode)
+	writeUint64(buffer, uint64(amount))
+	writeUint32(buffer, in.Sequence)
+	buffer.Write(hashOutputs[:])
+	writeUint32(buffer, tx.LockTime)
+	writeUint32(buffer, uint32(hashType))
+	return doubleSha256(buffer.Bytes()), nil
+}
+
+// P2WPKHScriptCode returns the BIP143 script code of a P2WPKH output, the
+// P2PKH script of the 20 bytes public key hash.
+func P2WPKHScriptCode(hash160 []byte) []byte {
+	return P2PKHScript(hash160)
+}
+
+// writeScriptCode writes script without its OP_CODESEPARATOR opcodes, as
+// Bitcoin Core's SerializeScriptCode does. When a push runs past the end of
+// the script, the length prefix still counts every byte but the removed
+// separators, while only the bytes up to the failing push are written.
+func writeScriptCode(buffer *bytes.Buffer, script []byte) {
+	stripped, separators := removeCodeSeparators(script)
+	writeVarInt(buffer, uint64(len(script)-separators))
+	buffer.Write(stripped)
+}
+
+// removeCodeSeparators drops OP_CODESEPARATOR opcodes from script and
+// returns how many it dropped. Like Bitcoin Core's GetOp, it stops at a push
+// running past the end of the script, keeping only its opcode and complete
+// length bytes.
+func removeCodeSeparators(script []byte) ([]byte, int) {
+	stripped := make([]byte, 0, len(script))
+	separators := 0
+	for i := 0; i < len(script); {
+		op := script[i]
+		size := 1
+		var dataSize int
+		switch {
+		case op >= 0x01 && op <= 0x4b:
+			dataSize = int(op)
+		case op == 0x4c:
+			if i+1 >= len(script) {
+				return append(stripped, op), separators
+			}
+			size += 1
+			dataSize = int(script[i+1])
+		case op == 0x4d:
+			if i+2 >= len(script) {
+				return append(stripped, op), separators
+			}
+			size += 2
+			dataSize = int(script[i+1]) | int(script[i+2])<<8
+		case op == 0x4e:
+			if i+4 >= len(script) {
+				return append(stripped, op), separators
+			}
+			size += 4
+			dataSize = int(uint32(script[i+1]) | uint32(script[i+2])<<8 | uint32(script[i+3])<<16 | uint32(script[i+4])<<24)
+		}
+		if dataSize > len(script)-i-size {
+			return append(stripped, script[i:i+size]...), separators
+		}
+		size += dataSize
+		if op == opCodeSeparator {
+			separators++
+		} else {
+			stripped = append(stripped, script[i:i+size]...)
+		}
+		i += size
+	}
+	return stripped, separators
+}
@@ -0,0 +1,182 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// TestLegacySigHashVectors runs Bitcoin Core's sighash.json vectors
+func TestLegacySigHashVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/sighash.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors [][]interface{}
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	for i, vector := range vectors {
+		// The first row documents the format
+		if len(vector) != 5 {
+			continue
+		}
+		rawTx, _ := vector[0].(string)
+		rawScript, _ := vector[1].(string)
+		inputIndex, _ := vector[2].(float64)
+		hashType, _ := vector[3].(float64)
+		want, _ := vector[4].(string)
+
+		tx, err := DeserializeHex(rawTx)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		script, err := hex.DecodeString(rawScript)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		hash, err := tx.LegacySigHash(int(inputIndex), script, SigHashType(uint32(int32(hashType))))
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		// Hashes are displayed byte reversed, like txids
+		if got := hex.EncodeToString(reverseBytes(hash)); got != want {
+			t.Errorf("vector %d: sighash %s, want %s", i, got, want)
+		}
+		count++
+	}
+	if count != 500 {
+		t.Errorf("ran %d vectors, want 500", count)
+	}
+}
+
+func TestWitnessV0SigHashBIP143(t *testing.T) {
+	const (
+		p2wshTx        = "0100000002fe3dc9208094f3ffd12645477b3dc56f60ec4fa8e6f5d67c565d1c6b9216b36e0000000000ffffffff0815cf020f013ed6cf91d29f4202e8a58726b1ac6c79da47c23d1bee0a6925f80000000000ffffffff0100f2052a010000001976a914a30741f8145e5acadf23f751864167f32e0963f788ac00000000"
+		multisigTx     = "010000000136641869ca081e70f394c6948e8af409e18b619df2ed74aa106c1ca29787b96e0100000000ffffffff0200e9a435000000001976a914389ffce9cd9ae88dcc0631e88a821ffdbe9bfe2688acc0832f05000000001976a9147480a33f950689af511e6e84c138dbbd3c3ee41588ac00000000"
+		multisigScript = "56210307b8ae49ac90a048e9b53357a2354b3334e9c8bee813ecb98e99a7e07e8c3ba32103b28f0c28bfab54554ae8c658ac5c3e0ce6e79ad336331f78c428dd43eea8449b21034b8113d703413d57761b8b9781957b8c0ac1dfe69f492580ca4195f50376ba4a21033400f6afecb833092a9a21cfdf1ed1376e58c5d1f47de74683123987e967a8f42103a6d48b1131e94ba04d9737d61acdaa1322008af9602b3b14862c07a1789aac162102d8b661b0b3302ee2f162b09e07a55ad5dfbe673a9f01d9f0c19617681024306b56ae"
+	)
+	tests := []struct {
+		name       string
+		tx         string
+		inputIndex int
+		scriptCode string
+		amount     int64
+		hashType   SigHashType
+		sigHash    string
+	}{
+		{
+			"native P2WPKH",
+			"0100000002fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f0000000000eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac11000000",
+			1, hex.EncodeToString(P2WPKHScriptCode(mustDecodeHex("1d0f172a0ecb48aee1be1f2687d2963ae33f71a1"))), 600000000, SigHashAll,
+			"c37af31116d1b27caf68aae9e3ac82f1477929014d5b917657d0eb49478cb670",
+		},
+		{
+			"P2SH-P2WPKH",
+			"0100000001db6b1b20aa0fd7b23880be2ecbd4a98130974cf4748fb66092ac4d3ceb1a54770100000000feffffff02b8b4eb0b000000001976a914a457b684d7f0d539a46a45bbc043f35b59d0d96388ac0008af2f000000001976a914fd270b1ee6abcaea97fea7ad0402e8bd8ad6d77c88ac92040000",
+			0, hex.EncodeToString(P2WPKHScriptCode(mustDecodeHex("79091972186c449eb1ded22b78e40d009bdf0089"))), 1000000000, SigHashAll,
+			"64f3b0f4dd2bb3aa1ce8566d220cc74dda9df97d8490cc81d89d735c92e59fb6",
+		},
+		{
+			"native P2WSH before OP_CODESEPARATOR", p2wshTx,
+			1, "21026dccc749adc2a9d0d89497ac511f760f45c47dc5ed9cf352a58ac706453880aeadab210255a9626aebf5e29c0e6538428ba0d1dcf6ca98ffdf086aa8ced5e0d0215ea465ac", 4900000000, SigHashSingle,
+			"82dde6e4f1e94d02c2b7ad03d2115d691f48d064e9d52f58194a6637e4194391",
+		},
+		{
+			"native P2WSH after OP_CODESEPARATOR", p2wshTx,
+			1, "210255a9626aebf5e29c0e6538428ba0d1dcf6ca98ffdf086aa8ced5e0d0215ea465ac", 4900000000, SigHashSingle,
+			"fef7bd749cce710c5c052bd796df1af0d935e59cea63736268bcbe2d2134fc47",
+		},
+		{"P2SH-P2WSH ALL", multisigTx, 0, multisigScript, 987654321, SigHashAll,
+			"185c0be5263dce5b4bb50a047973c1b6272bfbd0103a89444597dc40b248ee7c"},
+		{"P2SH-P2WSH NONE", multisigTx, 0, multisigScript, 987654321, SigHashNone,
+			"e9733bc60ea13c95c6527066bb975a2ff29a925e80aa14c213f686cbae5d2f36"},
+		{"P2SH-P2WSH SINGLE", multisigTx, 0, multisigScript, 987654321, SigHashSingle,
+			"1e1f1c303dc025bd664acb72e583e933fae4cff9148bf78c157d1e8f78530aea"},
+		{"P2SH-P2WSH ALL|ANYONECANPAY", multisigTx, 0, multisigScript, 987654321, SigHashAll | SigHashAnyOneCanPay,
+			"2a67f03e63a6a422125878b40b82da593be8d4efaafe88ee528af6e5a9955c6e"},
+		{"P2SH-P2WSH NONE|ANYONECANPAY", multisigTx, 0, multisigScript, 987654321, SigHashNone | SigHashAnyOneCanPay,
+			"781ba15f3779d5542ce8ecb5c18716733a5ee42a6f51488ec96154934e2c890a"},
+		{"P2SH-P2WSH SINGLE|ANYONECANPAY", multisigTx, 0, multisigScript, 987654321, SigHashSingle | SigHashAnyOneCanPay,
+			"511e8e52ed574121fc1b654970395502128263f62662e076dc6baf05c2e6a99b"},
+	}
+	for _, test := range tests {
+		tx, err := DeserializeHex(test.tx)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		hash, err := tx.WitnessV0SigHash(test.inputIndex, mustDecodeHex(test.scriptCode), test.amount, test.hashType)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := hex.EncodeToString(hash); got != test.sigHash {
+			t.Errorf("%s: sighash %s, want %s", test.name, got, test.sigHash)
+		}
+	}
+}
+
+// TestWriteScriptCode checks the script code bytes against Bitcoin Core's
+// SerializeScriptCode, whose length prefix keeps counting the bytes after a
+// truncated push
+func TestWriteScriptCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"no separator", "76a914000000000000000000000000000000000000000088ac", "1976a914000000000000000000000000000000000000000088ac"},
+		{"separators", "ab51ab52ab", "025152"},
+		{"separator byte in push data", "02abab51", "0402abab51"},
+		{"truncated direct push", "ab5103abab", "045103"},
+		{"truncated OP_PUSHDATA1 length", "ab514c", "02514c"},
+		{"truncated OP_PUSHDATA1 data", "ab514c05abab", "05514c05"},
+		{"truncated OP_PUSHDATA2 length", "514d01", "03514d"},
+		{"truncated OP_PUSHDATA2 data", "ab4d0200ab", "044d0200"},
+		{"truncated OP_PUSHDATA4 length", "4e010000", "044e"},
+		{"truncated OP_PUSHDATA4 data", "4e02000000ab", "064e02000000"},
+	}
+	for _, test := range tests {
+		buffer := new(bytes.Buffer)
+		writeScriptCode(buffer, mustDecodeHex(test.script))
+		if got := hex.EncodeToString(buffer.Bytes()); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+// TestLegacySigHashTruncatedPush signs a script code with a truncated
+// OP_PUSHDATA1 after an OP_CODESEPARATOR, which Bitcoin Core's sighash.json
+// doesn't cover
+func TestLegacySigHashTruncatedPush(t *testing.T) {
+	tx, err := DeserializeHex("010000000111111111111111111111111111111111111111111111111111111111111111110000000000ffffffff01e803000000000000015100000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := tx.LegacySigHash(0, mustDecodeHex("ab514c05abab"), SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(hash), "103afdc7f83ff4554ef1a76cd305d15bec46b3ba0874f2133b4caa44edc7a5e2"; got != want {
+		t.Errorf("sighash %s, want %s", got, want)
+	}
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func reverseBytes(b []byte) []byte {
+	reversed := make([]byte, len(b))
+	for i := range b {
+		reversed[len(b)-1-i] = b[i]
+	}
+	return reversed
+}
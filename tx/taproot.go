@@ -0,0 +1,233 @@
+package tx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/icodeface/go-blockchain-kit/crypto"
+)
+
+const (
+	// SigHashDefault signs like SigHashAll and is only valid for taproot
+	// inputs, where it is encoded as a 64 bytes signature
+	SigHashDefault SigHashType = 0x00
+
+	// BaseLeafVersion is the BIP342 tapscript leaf version
+	BaseLeafVersion = 0xc0
+
+	// NoCodeSeparator is the codeseparator position when the executed script
+	// has none
+	NoCodeSeparator = uint32(0xffffffff)
+
+	annexTag = 0x50
+)
+
+var (
+	// ErrInvalidSigHashType is returned for hash types BIP341 doesn't define
+	ErrInvalidSigHashType = errors.New("Invalid taproot signature hash type")
+
+	// ErrPrevOutsMismatch is returned when the spent outputs don't match the
+	// transaction inputs one to one
+	ErrPrevOutsMismatch = errors.New("Previous outputs don't match the transaction inputs")
+
+	// ErrNoSingleOutput is returned for SIGHASH_SINGLE without an output at
+	// the input's index, which taproot doesn't allow
+	ErrNoSingleOutput = errors.New("No output matches the SIGHASH_SINGLE input")
+
+	// ErrInvalidAnnex is returned when an annex doesn't start with 0x50
+	ErrInvalidAnnex = errors.New("Taproot annex should start with 0x50")
+
+	// ErrInvalidLeafHash is returned when a tapleaf hash isn't 32 bytes
+	ErrInvalidLeafHash = errors.New("Tapleaf hash should be 32 bytes")
+
+	// ErrInvalidMerklePath is returned when a control block path holds
+	// nodes that aren't 32 bytes or more than 128 of them
+	ErrInvalidMerklePath = errors.New("Invalid taproot merkle path")
+)
+
+// ScriptPathSpend identifies the tapscript leaf executed by a script path
+// spend, committed to by the BIP342 signature message extension.
+type ScriptPathSpend struct {
+	LeafHash         []byte
+	CodeSeparatorPos uint32
+}
+
+// NewScriptPathSpend returns the script path spend of script at the given
+// leaf version, executed without OP_CODESEPARATOR.
+func NewScriptPathSpend(leafVersion byte, script []byte) *ScriptPathSpend {
+	return &ScriptPathSpend{
+		LeafHash:         TapLeafHash(leafVersion, script),
+		CodeSeparatorPos: NoCodeSeparator,
+	}
+}
+
+// TaprootSigHash computes the BIP341 signature hash of input inputIndex.
+// prevOuts are the outputs spent by every input, in input order. annex is
+// the optional annex including its 0x50 prefix. scriptPath is nil for key
+// path spends and set for tapscript spends as in BIP342.
+func (tx *Tx) TaprootSigHash(inputIndex int, prevOuts []*TxOut, hashType SigHashType, annex []byte, scriptPath *ScriptPathSpend) ([]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return nil, ErrInputIndexOutOfRange
+	}
+	if len(prevOuts) != len(tx.Inputs) {
+		return nil, ErrPrevOutsMismatch
+	}
+	if !isValidTaprootSigHashType(hashType) {
+		return nil, ErrInvalidSigHashType
+	}
+	if len(annex) != 0 && annex[0] != annexTag {
+		return nil, ErrInvalidAnnex
+	}
+	if scriptPath != nil && len(scriptPath.LeafHash) != HashLength {
+		return nil, ErrInvalidLeafHash
+	}
+
+	baseType := hashType & 0x03
+	anyoneCanPay := hashType&SigHashAnyOneCanPay != 0
+	if baseType == SigHashSingle && inputIndex >= len(tx.Outputs) {
+		return nil, ErrNoSingleOutput
+	}
+
+	// The epoch byte precedes the signature message
+	msg := bytes.NewBuffer([]byte{0x00})
+	msg.WriteByte(byte(hashType))
+	writeUint32(msg, uint32(tx.Version))
+	writeUint32(msg, tx.LockTime)
+
+	if !anyoneCanPay {
+		prevouts, amounts, scripts, sequences := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+		for i, in := range tx.Inputs {
+			prevouts.Write(in.PreviousOutPoint.Hash[:])
+			writeUint32(prevouts, in.PreviousOutPoint.Index)
+			writeUint64(amounts, uint64(prevOuts[i].Value))
+			writeVarBytes(scripts, prevOuts[i].PkScript)
+			writeUint32(sequences, in.Sequence)
+		}
+		msg.Write(sha256Hash(prevouts.Bytes()))
+		msg.Write(sha256Hash(amounts.Bytes()))
+		msg.Write(sha256Hash(scripts.Bytes()))
+		msg.Write(sha256Hash(sequences.Bytes()))
+	}
+
+	if baseType != SigHashNone && baseType != SigHashSingle {
+		outputs := new(bytes.Buffer)
+		for _, out := range tx.Outputs {
+			writeTxOut(outputs, out)
+		}
+		msg.Write(sha256Hash(outputs.Bytes()))
+	}
+
+	var spendType byte
+	if scriptPath != nil {
+		spendType |= 0x02
+	}
+	if len(annex) != 0 {
+		spendType |= 0x01
+	}
+	msg.WriteByte(spendType)
+
+	if anyoneCanPay {
+		in := tx.Inputs[inputIndex]
+		msg.Write(in.PreviousOutPoint.Hash[:])
+		writeUint32(msg, in.PreviousOutPoint.Index)
+		writeTxOut(msg, prevOuts[inputIndex])
+		writeUint32(msg, in.Sequence)
+	} else {
+		writeUint32(msg, uint32(inputIndex))
+	}
+
+	if len(annex) != 0 {
+		buffer := new(bytes.Buffer)
+		writeVarBytes(buffer, annex)
+		msg.Write(sha256Hash(buffer.Bytes()))
+	}
+
+	if baseType == SigHashSingle {
+		buffer := new(bytes.Buffer)
+		writeTxOut(buffer, tx.Outputs[inputIndex])
+		msg.Write(sha256Hash(buffer.Bytes()))
+	}
+
+	if scriptPath != nil {
+		msg.Write(scriptPath.LeafHash)
+		// key_version 0 is the only one BIP342 defines
+		msg.WriteByte(0x00)
+		writeUint32(msg, scriptPath.CodeSeparatorPos)
+	}
+
+	return crypto.TaggedHash("TapSighash", msg.Bytes()), nil
+}
+
+// TaprootSignature encodes a 64 bytes Schnorr signature for a witness,
+// appending the hash type unless it is SigHashDefault.
+func TaprootSignature(sig []byte, hashType SigHashType) []byte {
+	encoded := append([]byte{}, sig...)
+	if hashType != SigHashDefault {
+		encoded = append(encoded, byte(hashType))
+	}
+	return encoded
+}
+
+// TapLeafHash returns the BIP341 tapleaf hash of script at leafVersion.
+func TapLeafHash(leafVersion byte, script []byte) []byte {
+	buffer := bytes.NewBuffer([]byte{leafVersion})
+	writeVarBytes(buffer, script)
+	return crypto.TaggedHash("TapLeaf", buffer.Bytes())
+}
+
+// TapBranchHash returns the BIP341 hash of a script tree branch, which
+// sorts its two children.
+func TapBranchHash(a []byte, b []byte) []byte {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return crypto.TaggedHash("TapBranch", a, b)
+}
+
+// TaprootMerkleRoot returns the script tree root reached from leafHash
+// along merklePath, the sibling hashes from the leaf up.
+func TaprootMerkleRoot(leafHash []byte, merklePath ...[]byte) []byte {
+	node := leafHash
+	for _, sibling := range merklePath {
+		node = TapBranchHash(node, sibling)
+	}
+	return node
+}
+
+// ControlBlock builds the BIP341 control block proving that a leaf is part
+// of the script tree of an output. outputParity is the y parity of the
+// tweaked output key and internalKey the 32 bytes x-only internal key.
+func ControlBlock(leafVersion byte, outputParity byte, internalKey []byte, merklePath ...[]byte) ([]byte, error) {
+	if len(internalKey) != 32 {
+		return nil, crypto.ErrInvalidPublicKeyFormat
+	}
+	if len(merklePath) > 128 {
+		return nil, ErrInvalidMerklePath
+	}
+	block := []byte{leafVersion&0xfe | outputParity&0x01}
+	block = append(block, internalKey...)
+	for _, node := range merklePath {
+		if len(node) != HashLength {
+			return nil, ErrInvalidMerklePath
+		}
+		block = append(block, node...)
+	}
+	return block, nil
+}
+
+// isValidTaprootSigHashType reports whether BIP341 defines hashType.
+func isValidTaprootSigHashType(hashType SigHashType) bool {
+	switch hashType &^ SigHashAnyOneCanPay {
+	case SigHashAll, SigHashNone, SigHashSingle:
+		return true
+	case SigHashDefault:
+		return hashType == SigHashDefault
+	}
+	return false
+}
+
+func sha256Hash(data []byte) []byte {
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
@@ -0,0 +1,229 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/icodeface/go-blockchain-kit/crypto"
+)
+
+// bip341Tx and bip341Utxos are the unsigned transaction and spent outputs of
+// the BIP341 keyPathSpending wallet test vectors
+const bip341Tx = "02000000097de20cbff686da83a54981d2b9bab3586f4ca7e48f57f5b55963115f3b334e9c010000000000000000d7b7cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd990000000000fffffffff8e1f583384333689228c5d28eac13366be082dc57441760d957275419a418420000000000fffffffff0689180aa63b30cb162a73c6d2a38b7eeda2a83ece74310fda0843ad604853b0100000000feffffffaa5202bdf6d8ccd2ee0f0202afbbb7461d9264a25e5bfd3c5a52ee1239e0ba6c0000000000feffffff956149bdc66faa968eb2be2d2faa29718acbfe3941215893a2a3446d32acd050000000000000000000e664b9773b88c09c32cb70a2a3e4da0ced63b7ba3b22f848531bbb1d5d5f4c94010000000000000000e9aa6b8e6c9de67619e6a3924ae25696bb7b694bb677a632a74ef7eadfd4eabf0000000000ffffffffa778eb6a263dc090464cd125c466b5a99667720b1c110468831d058aa1b82af10100000000ffffffff0200ca9a3b000000001976a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac807840cb0000000020ac9a87f5594be208f8532db38cff670c450ed2fea8fcdefcc9a663f78bab962b0065cd1d"
+
+var bip341Utxos = []struct {
+	pkScript string
+	amount   int64
+}{
+	{"512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343", 420000000},
+	{"5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3", 462000000},
+	{"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac", 294000000},
+	{"5120e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e", 504000000},
+	{"512091b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605", 630000000},
+	{"00147dd65592d0ab2fe0d0257d571abf032cd9db93dc", 378000000},
+	{"512075169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831", 672000000},
+	{"5120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5", 546000000},
+	{"512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220", 588000000},
+}
+
+func bip341PrevOuts() []*TxOut {
+	prevOuts := make([]*TxOut, len(bip341Utxos))
+	for i, utxo := range bip341Utxos {
+		prevOuts[i] = NewTxOut(utxo.amount, mustDecodeHex(utxo.pkScript))
+	}
+	return prevOuts
+}
+
+func TestTaprootSigHashBIP341(t *testing.T) {
+	tx, err := DeserializeHex(bip341Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevOuts := bip341PrevOuts()
+
+	tests := []struct {
+		inputIndex  int
+		internalKey string
+		merkleRoot  string
+		hashType    SigHashType
+		sigHash     string
+		witness     string
+	}{
+		{0, "6b973d88838f27366ed61c9ad6367663045cb456e28335c109e30717ae0c6baa", "", SigHashSingle,
+			"2514a6272f85cfa0f45eb907fcb0d121b808ed37c6ea160a5a9046ed5526d555",
+			"ed7c1647cb97379e76892be0cacff57ec4a7102aa24296ca39af7541246d8ff14d38958d4cc1e2e478e4d4a764bbfd835b16d4e314b72937b29833060b87276c03"},
+		{1, "1e4da49f6aaf4e5cd175fe08a32bb5cb4863d963921255f33d3bc31e1343907f", "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21", SigHashSingle | SigHashAnyOneCanPay,
+			"325a644af47e8a5a2591cda0ab0723978537318f10e6a63d4eed783b96a71a4d",
+			"052aedffc554b41f52b521071793a6b88d6dbca9dba94cf34c83696de0c1ec35ca9c5ed4ab28059bd606a4f3a657eec0bb96661d42921b5f50a95ad33675b54f83"},
+		{3, "d3c7af07da2d54f7a7735d3d0fc4f0a73164db638b2f2f7c43f711f6d4aa7e64", "c525714a7f49c28aedbbba78c005931a81c234b2f6c99a73e4d06082adc8bf2b", SigHashAll,
+			"bf013ea93474aa67815b1b6cc441d23b64fa310911d991e713cd34c7f5d46669",
+			"ff45f742a876139946a149ab4d9185574b98dc919d2eb6754f8abaa59d18b025637a3aa043b91817739554f4ed2026cf8022dbd83e351ce1fabc272841d2510a01"},
+		{4, "f36bb07a11e469ce941d16b63b11b9b9120a84d9d87cff2c84a8d4affb438f4e", "ccbd66c6f7e8fdab47b3a486f59d28262be857f30d4773f2d5ea47f7761ce0e2", SigHashDefault,
+			"4f900a0bae3f1446fd48490c2958b5a023228f01661cda3496a11da502a7f7ef",
+			"b4010dd48a617db09926f729e79c33ae0b4e94b79f04a1ae93ede6315eb3669de185a17d2b0ac9ee09fd4c64b678a0b61a0a86fa888a273c8511be83bfd6810f"},
+		{6, "415cfe9c15d9cea27d8104d5517c06e9de48e2f986b695e4f5ffebf230e725d8", "2f6b2c5397b6d68ca18e09a3f05161668ffe93a988582d55c6f07bd5b3329def", SigHashNone,
+			"15f25c298eb5cdc7eb1d638dd2d45c97c4c59dcaec6679cfc16ad84f30876b85",
+			"a3785919a2ce3c4ce26f298c3d51619bc474ae24014bcdd31328cd8cfbab2eff3395fa0a16fe5f486d12f22a9cedded5ae74feb4bbe5351346508c5405bcfee002"},
+		{7, "c7b0e81f0a9a0b0499e112279d718cca98e79a12e2f137c72ae5b213aad0d103", "6c2dc106ab816b73f9d07e3cd1ef2c8c1256f519748e0813e4edd2405d277bef", SigHashNone | SigHashAnyOneCanPay,
+			"cd292de50313804dabe4685e83f923d2969577191a3e1d2882220dca88cbeb10",
+			"ea0c6ba90763c2d3a296ad82ba45881abb4f426b3f87af162dd24d5109edc1cdd11915095ba47c3a9963dc1e6c432939872bc49212fe34c632cd3ab9fed429c482"},
+		{8, "77863416be0d0665e517e1c375fd6f75839544eca553675ef7fdf4949518ebaa", "ab179431c28d3b68fb798957faf5497d69c883c6fb1e1cd9f81483d87bac90cc", SigHashAll | SigHashAnyOneCanPay,
+			"cccb739eca6c13a8a89e6e5cd317ffe55669bbda23f2fd37b0f18755e008edd2",
+			"bbc9584a11074e83bc8c6759ec55401f0ae7b03ef290c3139814f545b58a9f8127258000874f44bc46db7646322107d4d86aec8e73b8719a61fff761d75b5dd981"},
+	}
+	for _, test := range tests {
+		sigHash, err := tx.TaprootSigHash(test.inputIndex, prevOuts, test.hashType, nil, nil)
+		if err != nil {
+			t.Fatalf("input %d: %v", test.inputIndex, err)
+		}
+		if got := hex.EncodeToString(sigHash); got != test.sigHash {
+			t.Errorf("input %d: sighash %s, want %s", test.inputIndex, got, test.sigHash)
+			continue
+		}
+
+		var merkleRoot []byte
+		if test.merkleRoot != "" {
+			merkleRoot = mustDecodeHex(test.merkleRoot)
+		}
+		tweaked, err := crypto.TweakTaprootPrivateKey(mustDecodeHex(test.internalKey), merkleRoot)
+		if err != nil {
+			t.Fatalf("input %d: %v", test.inputIndex, err)
+		}
+		// The vectors sign with an all zero aux_rand
+		sig, err := crypto.SchnorrSign(tweaked, sigHash, make([]byte, 32))
+		if err != nil {
+			t.Fatalf("input %d: %v", test.inputIndex, err)
+		}
+		if got := hex.EncodeToString(TaprootSignature(sig, test.hashType)); got != test.witness {
+			t.Errorf("input %d: witness %s, want %s", test.inputIndex, got, test.witness)
+		}
+		outputKey := prevOuts[test.inputIndex].PkScript[2:]
+		if !crypto.SchnorrVerify(outputKey, sigHash, sig) {
+			t.Errorf("input %d: signature doesn't verify against the output key", test.inputIndex)
+		}
+	}
+}
+
+func TestTaprootSigHashAnnex(t *testing.T) {
+	tx, err := DeserializeHex(bip341Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevOuts := bip341PrevOuts()
+
+	hashTypes := []SigHashType{SigHashDefault, SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyOneCanPay, SigHashNone | SigHashAnyOneCanPay, SigHashSingle | SigHashAnyOneCanPay}
+	for _, hashType := range hashTypes {
+		plain, err := tx.TaprootSigHash(0, prevOuts, hashType, nil, nil)
+		if err != nil {
+			t.Fatalf("%#x: %v", hashType, err)
+		}
+		annex, err := tx.TaprootSigHash(0, prevOuts, hashType, []byte{annexTag}, nil)
+		if err != nil {
+			t.Fatalf("%#x: %v", hashType, err)
+		}
+		otherAnnex, err := tx.TaprootSigHash(0, prevOuts, hashType, []byte{annexTag, 0x01}, nil)
+		if err != nil {
+			t.Fatalf("%#x: %v", hashType, err)
+		}
+		if bytes.Equal(plain, annex) || bytes.Equal(annex, otherAnnex) {
+			t.Errorf("%#x: sighash doesn't commit to the annex", hashType)
+		}
+		scriptPath, err := tx.TaprootSigHash(0, prevOuts, hashType, nil, NewScriptPathSpend(BaseLeafVersion, []byte{0x51}))
+		if err != nil {
+			t.Fatalf("%#x: %v", hashType, err)
+		}
+		if bytes.Equal(plain, scriptPath) {
+			t.Errorf("%#x: script path sighash equals the key path one", hashType)
+		}
+	}
+
+	// ANYONECANPAY only commits to its own input's outpoint and amount
+	anyoneCanPay := SigHashAll | SigHashAnyOneCanPay
+	want, err := tx.TaprootSigHash(3, prevOuts, anyoneCanPay, []byte{annexTag, 0x02}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := tx.Copy()
+	changed.Inputs[0].PreviousOutPoint.Index++
+	changedPrevOuts := bip341PrevOuts()
+	changedPrevOuts[0].Value++
+	got, err := changed.TaprootSigHash(3, changedPrevOuts, anyoneCanPay, []byte{annexTag, 0x02}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("ANYONECANPAY sighash commits to other inputs")
+	}
+	if all, _ := changed.TaprootSigHash(3, changedPrevOuts, SigHashAll, nil, nil); bytes.Equal(all, want) {
+		t.Error("SIGHASH_ALL sighash ignores other inputs")
+	}
+}
+
+func TestTaprootSigHashInvalid(t *testing.T) {
+	tx, err := DeserializeHex(bip341Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevOuts := bip341PrevOuts()
+
+	for _, hashType := range []SigHashType{0x04, 0x80, 0x84, 0xff} {
+		if _, err := tx.TaprootSigHash(0, prevOuts, hashType, nil, nil); err != ErrInvalidSigHashType {
+			t.Errorf("hash type %#x: got %v, want %v", hashType, err, ErrInvalidSigHashType)
+		}
+	}
+	if _, err := tx.TaprootSigHash(2, prevOuts, SigHashSingle, nil, nil); err != ErrNoSingleOutput {
+		t.Errorf("SIGHASH_SINGLE without output: got %v, want %v", err, ErrNoSingleOutput)
+	}
+	if _, err := tx.TaprootSigHash(0, prevOuts[1:], SigHashAll, nil, nil); err != ErrPrevOutsMismatch {
+		t.Errorf("missing previous output: got %v, want %v", err, ErrPrevOutsMismatch)
+	}
+	if _, err := tx.TaprootSigHash(0, prevOuts, SigHashAll, []byte{0x51}, nil); err != ErrInvalidAnnex {
+		t.Errorf("annex without 0x50: got %v, want %v", err, ErrInvalidAnnex)
+	}
+	if _, err := tx.TaprootSigHash(len(tx.Inputs), prevOuts, SigHashAll, nil, nil); err != ErrInputIndexOutOfRange {
+		t.Errorf("input out of range: got %v, want %v", err, ErrInputIndexOutOfRange)
+	}
+}
+
+// TestTaprootScriptTree checks the single leaf scriptPubKey vector of BIP341,
+// whose output is spent by input 1 of the keyPathSpending vectors
+func TestTaprootScriptTree(t *testing.T) {
+	internalKey := mustDecodeHex("187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27")
+	script := mustDecodeHex("20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac")
+
+	leafHash := TapLeafHash(BaseLeafVersion, script)
+	const wantLeafHash = "5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21"
+	if hex.EncodeToString(leafHash) != wantLeafHash {
+		t.Errorf("leaf hash %x, want %s", leafHash, wantLeafHash)
+	}
+	merkleRoot := TaprootMerkleRoot(leafHash)
+	if !bytes.Equal(merkleRoot, leafHash) {
+		t.Errorf("single leaf merkle root %x, want the leaf hash", merkleRoot)
+	}
+	outputKey, parity, err := crypto.TweakTaprootPublicKey(internalKey, merkleRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bip341Utxos[1].pkScript[4:]; hex.EncodeToString(outputKey) != want {
+		t.Errorf("output key %x, want %s", outputKey, want)
+	}
+	controlBlock, err := ControlBlock(BaseLeafVersion, parity, internalKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantControlBlock = "c1187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27"
+	if hex.EncodeToString(controlBlock) != wantControlBlock {
+		t.Errorf("control block %x, want %s", controlBlock, wantControlBlock)
+	}
+
+	// Branches sort their children
+	a, b := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	if !bytes.Equal(TapBranchHash(a, b), TapBranchHash(b, a)) {
+		t.Error("branch hash depends on child order")
+	}
+	if _, err := ControlBlock(BaseLeafVersion, parity, internalKey, []byte{1}); err != ErrInvalidMerklePath {
+		t.Errorf("short merkle node: got %v, want %v", err, ErrInvalidMerklePath)
+	}
+}
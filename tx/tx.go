@@ -0,0 +1,187 @@
+package tx
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/icodeface/go-blockchain-kit/utils"
+)
+
+const (
+	// HashLength is the length of transaction hashes
+	HashLength = 32
+
+	// MaxSequence is the sequence of a final input
+	MaxSequence = uint32(0xffffffff)
+)
+
+var (
+	// ErrInvalidTxID is returned when a txid isn't 64 hex characters
+	ErrInvalidTxID = errors.New("Invalid txid")
+)
+
+// OutPoint references an output of a previous transaction. Hash is in
+// internal byte order, the reverse of the displayed txid.
+type OutPoint struct {
+	Hash  [HashLength]byte
+	Index uint32
+}
+
+// TxIn is a transaction input.
+type TxIn struct {
+	PreviousOutPoint OutPoint
+	SignatureScript  []byte
+	Sequence         uint32
+	Witness          [][]byte
+}
+
+// TxOut is a transaction output paying Value satoshis to PkScript.
+type TxOut struct {
+	Value    int64
+	PkScript []byte
+}
+
+// Tx is a bitcoin transaction.
+type Tx struct {
+	Version  int32
+	Inputs   []*TxIn
+	Outputs  []*TxOut
+	LockTime uint32
+}
+
+// NewOutPoint returns the outpoint of output index of the transaction with
+// the given displayed txid.
+func NewOutPoint(txid string, index uint32) (*OutPoint, error) {
+	b, err := hex.DecodeString(txid)
+	if err != nil || len(b) != HashLength {
+		return nil, ErrInvalidTxID
+	}
+	outPoint := &OutPoint{Index: index}
+	for i := range b {
+		outPoint.Hash[HashLength-1-i] = b[i]
+	}
+	return outPoint, nil
+}
+
+// TxID returns the displayed txid of the referenced transaction.
+func (outPoint *OutPoint) TxID() string {
+	return hashToString(outPoint.Hash[:])
+}
+
+// String returns the outpoint as txid:index.
+func (outPoint *OutPoint) String() string {
+	return fmt.Sprintf("%s:%d", outPoint.TxID(), outPoint.Index)
+}
+
+// NewTxIn returns an input spending outPoint with a final sequence.
+func NewTxIn(outPoint *OutPoint) *TxIn {
+	return &TxIn{PreviousOutPoint: *outPoint, Sequence: MaxSequence}
+}
+
+// NewTxOut returns an output paying value to pkScript.
+func NewTxOut(value int64, pkScript []byte) *TxOut {
+	return &TxOut{Value: value, PkScript: pkScript}
+}
+
+// NewTx returns an empty transaction of the given version.
+func NewTx(version int32) *Tx {
+	return &Tx{Version: version}
+}
+
+// AddInput appends an input to the transaction.
+func (tx *Tx) AddInput(in *TxIn) {
+	tx.Inputs = append(tx.Inputs, in)
+}
+
+// AddOutput appends an output to the transaction.
+func (tx *Tx) AddOutput(out *TxOut) {
+	tx.Outputs = append(tx.Outputs, out)
+}
+
+// HasWitness reports whether any input carries witness data, in which case
+// the transaction serializes in the SegWit format.
+func (tx *Tx) HasWitness() bool {
+	for _, in := range tx.Inputs {
+		if len(in.Witness) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns the double sha256 of the transaction without witness data,
+// in internal byte order.
+func (tx *Tx) Hash() []byte {
+	return doubleSha256(tx.SerializeNoWitness())
+}
+
+// WitnessHash returns the double sha256 of the full transaction, in
+// internal byte order. It equals Hash for transactions without witness.
+func (tx *Tx) WitnessHash() []byte {
+	return doubleSha256(tx.Serialize())
+}
+
+// TxID returns the displayed transaction id.
+func (tx *Tx) TxID() string {
+	return hashToString(tx.Hash())
+}
+
+// WTxID returns the displayed witness transaction id.
+func (tx *Tx) WTxID() string {
+	return hashToString(tx.WitnessHash())
+}
+
+// Weight returns the BIP141 weight, three times the size without witness
+// plus the full size.
+func (tx *Tx) Weight() int64 {
+	return int64(3*len(tx.SerializeNoWitness()) + len(tx.Serialize()))
+}
+
+// VSize returns the virtual size in vbytes, the weight divided by four and
+// rounded up.
+func (tx *Tx) VSize() int64 {
+	return (tx.Weight() + 3) / 4
+}
+
+// Copy returns a deep copy of the transaction.
+func (tx *Tx) Copy() *Tx {
+	copied := &Tx{Version: tx.Version, LockTime: tx.LockTime}
+	for _, in := range tx.Inputs {
+		inCopy := &TxIn{
+			PreviousOutPoint: in.PreviousOutPoint,
+			SignatureScript:  copyBytes(in.SignatureScript),
+			Sequence:         in.Sequence,
+		}
+		for _, item := range in.Witness {
+			inCopy.Witness = append(inCopy.Witness, copyBytes(item))
+		}
+		copied.Inputs = append(copied.Inputs, inCopy)
+	}
+	for _, out := range tx.Outputs {
+		copied.Outputs = append(copied.Outputs, NewTxOut(out.Value, copyBytes(out.PkScript)))
+	}
+	return copied
+}
+
+func doubleSha256(data []byte) []byte {
+	// Hashing an in-memory buffer can't fail
+	hash, _ := utils.HashDoubleSha256(data)
+	return hash
+}
+
+// hashToString returns the reversed hex encoding used to display hashes.
+func hashToString(hash []byte) string {
+	reversed := make([]byte, len(hash))
+	for i := range hash {
+		reversed[len(hash)-1-i] = hash[i]
+	}
+	return hex.EncodeToString(reversed)
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
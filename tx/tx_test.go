@@ -0,0 +1,121 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+const (
+	// genesisCoinbase is the coinbase transaction of the mainnet genesis block
+	genesisCoinbase = "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000"
+
+	// bip143P2WPKH is the signed native P2WPKH example of BIP143
+	bip143P2WPKH = "01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000"
+)
+
+func TestTxRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		txid    string
+		wtxid   string
+		witness bool
+	}{
+		{"genesis coinbase", genesisCoinbase,
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b", false},
+		{"BIP143 P2WPKH", bip143P2WPKH,
+			"e8151a2af31c368a35053ddd4bdb285a8595c769a3ad83e0fa02314a602d4609",
+			"c36c38370907df2324d9ce9d149d191192f338b37665a82e78e76a12c909b762", true},
+	}
+	for _, test := range tests {
+		tx, err := DeserializeHex(test.raw)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if tx.Hex() != test.raw {
+			t.Errorf("%s: re-serialized as %s", test.name, tx.Hex())
+		}
+		if tx.HasWitness() != test.witness {
+			t.Errorf("%s: has witness %v, want %v", test.name, tx.HasWitness(), test.witness)
+		}
+		if tx.TxID() != test.txid {
+			t.Errorf("%s: txid %s, want %s", test.name, tx.TxID(), test.txid)
+		}
+		if tx.WTxID() != test.wtxid {
+			t.Errorf("%s: wtxid %s, want %s", test.name, tx.WTxID(), test.wtxid)
+		}
+		if tx.Copy().Hex() != test.raw {
+			t.Errorf("%s: copy differs", test.name)
+		}
+
+		stripped, err := DeserializeNoWitness(tx.SerializeNoWitness())
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if stripped.TxID() != test.txid || stripped.HasWitness() {
+			t.Errorf("%s: legacy serialization gave txid %s", test.name, stripped.TxID())
+		}
+	}
+}
+
+func TestDeserializeNoWitness(t *testing.T) {
+	// version 2, no inputs, one output, zero lock time
+	raw, _ := hex.DecodeString("0200000000" + "01" + "e803000000000000" + "016a" + "00000000")
+	tx, err := DeserializeNoWitness(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Inputs) != 0 || len(tx.Outputs) != 1 || tx.Outputs[0].Value != 1000 {
+		t.Errorf("parsed %d inputs, %d outputs", len(tx.Inputs), len(tx.Outputs))
+	}
+	if !bytes.Equal(tx.SerializeNoWitness(), raw) {
+		t.Errorf("re-serialized as %x", tx.SerializeNoWitness())
+	}
+
+	// Deserialize takes the zero input count for the SegWit marker
+	if _, err := Deserialize(raw); err == nil {
+		t.Error("Deserialize accepted a transaction without inputs")
+	}
+
+	segwit, _ := hex.DecodeString(bip143P2WPKH)
+	if _, err := DeserializeNoWitness(segwit); err == nil {
+		t.Error("DeserializeNoWitness accepted a SegWit serialization")
+	}
+}
+
+func TestDeserializeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		err  error
+	}{
+		{"empty", "", ErrUnexpectedEOF},
+		{"version only", "01000000", ErrUnexpectedEOF},
+		{"truncated", bip143P2WPKH[:len(bip143P2WPKH)-2], ErrUnexpectedEOF},
+		{"trailing data", bip143P2WPKH + "00", ErrTrailingData},
+		{"bad witness flag", "0100000000020000000000", ErrInvalidWitnessFlag},
+		{"non canonical count", "01000000fd0100", ErrNonCanonicalVarInt},
+	}
+	for _, test := range tests {
+		if _, err := DeserializeHex(test.raw); err != test.err {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	// A SegWit serialization whose inputs all have empty witnesses
+	tx, err := DeserializeHex(bip143P2WPKH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Inputs[1].Witness = nil
+	raw := tx.SerializeNoWitness()
+	superfluous := append(append([]byte{}, raw[:4]...), witnessMarker, witnessFlag)
+	superfluous = append(superfluous, raw[4:len(raw)-4]...)
+	superfluous = append(superfluous, 0, 0)
+	superfluous = append(superfluous, raw[len(raw)-4:]...)
+	if _, err := Deserialize(superfluous); err != ErrSuperfluousWitness {
+		t.Errorf("superfluous witness: got %v, want %v", err, ErrSuperfluousWitness)
+	}
+}
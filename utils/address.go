@@ -5,11 +5,16 @@ import (
 	"errors"
 	"bytes"
 	"strings"
+	"github.com/icodeface/go-blockchain-kit/crypto"
 )
 
 const hash160Length = 20
 const b58addressLength = 34
 
+// ErrInvalidB58Address is returned when a base58check address doesn't hold a
+// version byte and a 20 bytes hash
+var ErrInvalidB58Address = errors.New("Invalid base58check address")
+
 
 func Hash160ToB58Address(hash160 []byte, addrType int) (string, error) {
 	if len(hash160) != hash160Length {
@@ -35,19 +40,190 @@ func B58AddressToHash160(address string) (hash160 []byte, addrType int, err erro
 	return data[len(data)-hash160Length:], int(data[0]), nil
 }
 
+// IsB58Address reports whether address is a base58check P2PKH or P2SH
+// address with a known prefix
 func IsB58Address(address string) (bool) {
+	addrKind, _, err := DecodeB58Address(address)
+	return err == nil && addrKind != AddressUnknown
+}
+
+// AddressType is the kind of script an address pays to
+type AddressType int
+
+const (
+	AddressUnknown AddressType = iota
+	AddressP2PKH
+	AddressP2SH
+	AddressP2WPKH
+	AddressP2WSH
+	AddressP2TR
+	AddressWitnessUnknown // a valid SegWit address of a future witness version
+)
+
+const (
+	witnessScriptHashLength = 32
+	taprootKeyLength        = 32
+)
+
+// ErrInvalidWitnessScript is returned when building a P2WSH address from an
+// empty witness script
+var ErrInvalidWitnessScript = errors.New("Invalid witness script")
+
+// PublicKeyToP2WPKHAddress returns the native SegWit (bech32) address paying
+// to the hash160 of a 33 bytes compressed public key.
+func PublicKeyToP2WPKHAddress(publicKey []byte, hrp string) (string, error) {
+	if err := ValidatePublicKey(publicKey); err != nil {
+		return "", err
+	}
+	hash160, err := Hash160(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return EncodeSegWitAddress(hrp, 0, hash160)
+}
+
+// WitnessScriptToP2WSHAddress returns the native SegWit (bech32) address
+// paying to the sha256 of a witness script.
+func WitnessScriptToP2WSHAddress(witnessScript []byte, hrp string) (string, error) {
+	if len(witnessScript) == 0 {
+		return "", ErrInvalidWitnessScript
+	}
+	hash, err := HashSha256(witnessScript)
+	if err != nil {
+		return "", err
+	}
+	return EncodeSegWitAddress(hrp, 0, hash)
+}
 
-	hash160, addrType, err := B58AddressToHash160(address)
+// PublicKeyToP2TRAddress returns the taproot (bech32m) address for a 32 bytes
+// x-only internal key, tweaked with an optional script tree merkle root as
+// per BIP341.
+func PublicKeyToP2TRAddress(internalKey []byte, merkleRoot []byte, hrp string) (string, error) {
+	outputKey, _, err := crypto.TweakTaprootPublicKey(internalKey, merkleRoot)
 	if err != nil {
-		return false
+		return "", err
 	}
+	return EncodeSegWitAddress(hrp, 1, outputKey)
+}
 
-	// todo should check addrType
+// P2WPKHRedeemScript returns the redeem script OP_0 <hash160(publicKey)>
+// that wraps a P2WPKH output in P2SH, for a 33 bytes compressed public key.
+func P2WPKHRedeemScript(publicKey []byte) ([]byte, error) {
+	if err := ValidatePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	hash160, err := Hash160(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{0x00, hash160Length}, hash160...), nil
+}
 
-	addr2, err := Hash160ToB58Address(hash160, addrType)
+// RedeemScriptToP2SHAddress returns the base58check P2SH address paying to
+// the hash160 of a redeem script.
+func RedeemScriptToP2SHAddress(redeemScript []byte, prefix int) (string, error) {
+	hash160, err := Hash160(redeemScript)
 	if err != nil {
-		return false
+		return "", err
 	}
+	return Hash160ToB58Address(hash160, prefix)
+}
 
-	return strings.Compare(address, addr2) == 0
-}
\ No newline at end of file
+// PublicKeyToP2SHP2WPKHAddress returns the nested SegWit (P2SH-P2WPKH)
+// address of a 33 bytes compressed public key.
+func PublicKeyToP2SHP2WPKHAddress(publicKey []byte, prefix int) (string, error) {
+	redeemScript, err := P2WPKHRedeemScript(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return RedeemScriptToP2SHAddress(redeemScript, prefix)
+}
+
+// PublicKeyToP2PKHAddress returns the base58check P2PKH address of a
+// compressed or uncompressed public key.
+func PublicKeyToP2PKHAddress(publicKey []byte, prefix int) (string, error) {
+	if _, _, err := ParsePublicKey(publicKey); err != nil {
+		return "", err
+	}
+	hash160, err := Hash160(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return Hash160ToB58Address(hash160, prefix)
+}
+
+// DecodeB58Address decodes a base58check address and reports whether it
+// pays to a public key hash or a script hash of any known network, along
+// with the hash160. Unrecognized prefixes are reported as AddressUnknown.
+func DecodeB58Address(address string) (AddressType, []byte, error) {
+	prefix, hash160, err := decodeB58Address(address)
+	if err != nil {
+		return AddressUnknown, nil, err
+	}
+	for _, net := range Networks {
+		if addrKind := b58AddressKind(prefix, net); addrKind != AddressUnknown {
+			return addrKind, hash160, nil
+		}
+	}
+	return AddressUnknown, hash160, nil
+}
+
+// DecodeAddress decodes a base58check or native SegWit address of the given
+// network and reports the kind of output it pays to along with its hash or
+// witness program.
+func DecodeAddress(address string, net *Network) (AddressType, []byte, error) {
+	if net.HasSegWit() && len(address) > len(net.Bech32HRP) &&
+		strings.EqualFold(address[:len(net.Bech32HRP)+1], net.Bech32HRP+"1") {
+		return DecodeWitnessAddress(net.Bech32HRP, address)
+	}
+
+	prefix, hash160, err := decodeB58Address(address)
+	if err != nil {
+		return AddressUnknown, nil, err
+	}
+	addrKind := b58AddressKind(prefix, net)
+	if addrKind == AddressUnknown {
+		return AddressUnknown, nil, ErrAddressNetworkMismatch
+	}
+	return addrKind, hash160, nil
+}
+
+func decodeB58Address(address string) (int, []byte, error) {
+	data, err := decodeBase58Check(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) != 1+hash160Length {
+		return 0, nil, ErrInvalidB58Address
+	}
+	return int(data[0]), data[1:], nil
+}
+
+func b58AddressKind(prefix int, net *Network) AddressType {
+	switch prefix {
+	case net.P2PKHPrefix:
+		return AddressP2PKH
+	case net.P2SHPrefix:
+		return AddressP2SH
+	}
+	return AddressUnknown
+}
+
+// DecodeWitnessAddress decodes a native SegWit address and reports the kind
+// of output it pays to along with its witness program.
+func DecodeWitnessAddress(hrp string, address string) (AddressType, []byte, error) {
+	version, program, err := DecodeSegWitAddress(hrp, address)
+	if err != nil {
+		return AddressUnknown, nil, err
+	}
+
+	switch {
+	case version == 0 && len(program) == hash160Length:
+		return AddressP2WPKH, program, nil
+	case version == 0 && len(program) == witnessScriptHashLength:
+		return AddressP2WSH, program, nil
+	case version == 1 && len(program) == taprootKeyLength:
+		return AddressP2TR, program, nil
+	}
+	return AddressWitnessUnknown, program, nil
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestWitnessScriptToP2WSHAddress(t *testing.T) {
+	// BIP173: <G> OP_CHECKSIG
+	witnessScript, _ := hex.DecodeString("210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac")
+	program := "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"
+	tests := []struct {
+		hrp     string
+		address string
+	}{
+		{"bc", "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3"},
+		{"tb", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"},
+	}
+	for _, test := range tests {
+		address, err := WitnessScriptToP2WSHAddress(witnessScript, test.hrp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != test.address {
+			t.Errorf("%s: got %s, want %s", test.hrp, address, test.address)
+		}
+		addrType, decoded, err := DecodeWitnessAddress(test.hrp, address)
+		if err != nil || addrType != AddressP2WSH || hex.EncodeToString(decoded) != program {
+			t.Errorf("%s: decoded %d %x %v", address, addrType, decoded, err)
+		}
+	}
+
+	if _, err := WitnessScriptToP2WSHAddress(nil, "bc"); err != ErrInvalidWitnessScript {
+		t.Errorf("empty script: got %v, want %v", err, ErrInvalidWitnessScript)
+	}
+}
+
+func TestDecodeWitnessAddressInvalid(t *testing.T) {
+	// A version 0 program of 31 bytes is neither P2WPKH nor P2WSH
+	data, err := ConvertBits(make([]byte, 31), 8, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongLength, err := Bech32Encode("bc", append([]byte{0}, data...), Bech32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		hrp     string
+		address string
+		err     error
+	}{
+		{"tb", "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3", ErrBech32HRPMismatch},
+		{"bc", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", ErrBech32HRPMismatch},
+		{"bc", wrongLength, ErrInvalidWitnessProgramLength},
+	}
+	for _, test := range tests {
+		if _, _, err := DecodeWitnessAddress(test.hrp, test.address); err != test.err {
+			t.Errorf("%s: got %v, want %v", test.address, err, test.err)
+		}
+	}
+}
+
+func TestDecodeB58Address(t *testing.T) {
+	// hash160 of the compressed generator point, and the P2SH hash from the
+	// Bitcoin wiki
+	pubKeyHash := "751e76e8199196d454941c45d1b3a323f1433bd6"
+	scriptHash := "b472a266d0bd89c13706a4132ccfb16f7c3b9fcb"
+	tests := []struct {
+		address  string
+		addrType AddressType
+		hash160  string
+	}{
+		{"1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH", AddressP2PKH, pubKeyHash},
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", AddressP2SH, scriptHash},
+		{"mrCDrCybB6J1vRfbwM5hemdJz73FwDBC8r", AddressP2PKH, pubKeyHash},
+		{"2N9hLwkSqr1cPQAPxbrGVUjxyjD11G2e1he", AddressP2SH, scriptHash},
+	}
+	for _, test := range tests {
+		addrType, hash160, err := DecodeB58Address(test.address)
+		if err != nil || addrType != test.addrType || hex.EncodeToString(hash160) != test.hash160 {
+			t.Errorf("%s: got %d %x %v", test.address, addrType, hash160, err)
+		}
+		if !IsB58Address(test.address) {
+			t.Errorf("%s: IsB58Address is false", test.address)
+		}
+	}
+
+	// version 0x01 isn't used by any known network
+	addrType, _, err := DecodeB58Address("b1sYGBu5FKdxkL5FCSeJygmkchViYCqAq")
+	if err != nil || addrType != AddressUnknown {
+		t.Errorf("unknown prefix: got %d %v", addrType, err)
+	}
+
+	invalid := []struct {
+		address string
+		err     error
+	}{
+		{"1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMJ", ErrInvalidChecksum},
+		// a 19 bytes hash
+		{"13RJa7YdZQz3JHotw6gx1sco2AAPDrMZM", ErrInvalidB58Address},
+	}
+	for _, test := range invalid {
+		if _, _, err := DecodeB58Address(test.address); err != test.err {
+			t.Errorf("%s: got %v, want %v", test.address, err, test.err)
+		}
+	}
+	for _, address := range []string{"b1sYGBu5FKdxkL5FCSeJygmkchViYCqAq", "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMJ", "13RJa7YdZQz3JHotw6gx1sco2AAPDrMZM", "bc1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3qccfmv3", ""} {
+		if IsB58Address(address) {
+			t.Errorf("%q: IsB58Address is true", address)
+		}
+	}
+}
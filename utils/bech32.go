@@ -0,0 +1,302 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// Bech32Encoding selects the checksum constant of a bech32 string
+type Bech32Encoding int
+
+const (
+	// Bech32 is the original BIP173 encoding, used by witness version 0
+	Bech32 Bech32Encoding = iota + 1
+
+	// Bech32m is the BIP350 encoding, used by witness versions 1 to 16
+	Bech32m
+)
+
+const (
+	bech32Charset        = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Const          = 1
+	bech32mConst         = 0x2bc830a3
+	bech32MaxLength      = 90
+	bech32ChecksumLength = 6
+)
+
+var (
+	// ErrBech32MixedCase is returned when a string mixes upper and lower case
+	ErrBech32MixedCase = errors.New("Bech32 string mixes upper and lower case")
+
+	// ErrBech32InvalidLength is returned when a string is longer than 90
+	// characters or its data part is shorter than the checksum
+	ErrBech32InvalidLength = errors.New("Invalid bech32 string length")
+
+	// ErrBech32MissingSeparator is returned when there is no '1' separating
+	// the human readable part from the data
+	ErrBech32MissingSeparator = errors.New("Bech32 string has no separator")
+
+	// ErrBech32InvalidHRP is returned when the human readable part is empty or
+	// has characters outside [33, 126]
+	ErrBech32InvalidHRP = errors.New("Invalid bech32 human readable part")
+
+	// ErrBech32InvalidCharacter is returned when the data part has characters
+	// outside the bech32 charset
+	ErrBech32InvalidCharacter = errors.New("Invalid bech32 data character")
+
+	// ErrBech32InvalidChecksum is returned when the checksum matches neither
+	// bech32 nor bech32m
+	ErrBech32InvalidChecksum = errors.New("Invalid bech32 checksum")
+
+	// ErrBech32InvalidPadding is returned when converting between bit groups
+	// leaves non-zero or excess padding
+	ErrBech32InvalidPadding = errors.New("Invalid bech32 padding")
+
+	// ErrBech32HRPMismatch is returned when an address has an unexpected
+	// human readable part
+	ErrBech32HRPMismatch = errors.New("Bech32 human readable part mismatch")
+
+	// ErrInvalidWitnessVersion is returned when a witness version is above 16
+	ErrInvalidWitnessVersion = errors.New("Invalid witness version")
+
+	// ErrInvalidWitnessProgramLength is returned when a witness program isn't
+	// 2 to 40 bytes, or 20 or 32 bytes for version 0
+	ErrInvalidWitnessProgramLength = errors.New("Invalid witness program length")
+
+	// ErrWitnessEncodingMismatch is returned when a version 0 address isn't
+	// bech32 or a version 1+ address isn't bech32m
+	ErrWitnessEncodingMismatch = errors.New("Witness version doesn't match bech32 encoding")
+)
+
+// Bech32Encode encodes the hrp and 5 bits data groups with the given
+// checksum encoding.
+func Bech32Encode(hrp string, data []byte, encoding Bech32Encoding) (string, error) {
+	if len(hrp)+1+len(data)+bech32ChecksumLength > bech32MaxLength {
+		return "", ErrBech32InvalidLength
+	}
+	if err := validateHRP(hrp); err != nil {
+		return "", err
+	}
+	if strings.ToLower(hrp) != hrp && strings.ToUpper(hrp) != hrp {
+		return "", ErrBech32MixedCase
+	}
+	hrp = strings.ToLower(hrp)
+
+	var builder strings.Builder
+	builder.WriteString(hrp)
+	builder.WriteByte('1')
+	for _, b := range data {
+		if b >= 32 {
+			return "", ErrBech32InvalidCharacter
+		}
+		builder.WriteByte(bech32Charset[b])
+	}
+	for _, b := range bech32Checksum(hrp, data, encoding) {
+		builder.WriteByte(bech32Charset[b])
+	}
+	return builder.String(), nil
+}
+
+// Bech32Decode decodes a bech32 or bech32m string into its lower case hrp,
+// 5 bits data groups without the checksum, and the encoding used.
+func Bech32Decode(s string) (string, []byte, Bech32Encoding, error) {
+	if len(s) > bech32MaxLength {
+		return "", nil, 0, ErrBech32InvalidLength
+	}
+	// Case folding is done byte by byte, strings.ToLower would rewrite
+	// invalid UTF-8 that the hrp check should reject
+	var hasLower, hasUpper bool
+	folded := []byte(s)
+	for i, c := range folded {
+		switch {
+		case c >= 'a' && c <= 'z':
+			hasLower = true
+		case c >= 'A' && c <= 'Z':
+			hasUpper = true
+			folded[i] = c + 'a' - 'A'
+		}
+	}
+	if hasLower && hasUpper {
+		return "", nil, 0, ErrBech32MixedCase
+	}
+	lower := string(folded)
+
+	pos := strings.LastIndexByte(lower, '1')
+	if pos < 0 {
+		return "", nil, 0, ErrBech32MissingSeparator
+	}
+	hrp := lower[:pos]
+	if err := validateHRP(hrp); err != nil {
+		return "", nil, 0, err
+	}
+	if len(lower)-pos-1 < bech32ChecksumLength {
+		return "", nil, 0, ErrBech32InvalidLength
+	}
+
+	data := make([]byte, 0, len(lower)-pos-1)
+	for i := pos + 1; i < len(lower); i++ {
+		v := strings.IndexByte(bech32Charset, lower[i])
+		if v < 0 {
+			return "", nil, 0, ErrBech32InvalidCharacter
+		}
+		data = append(data, byte(v))
+	}
+
+	var encoding Bech32Encoding
+	switch bech32Polymod(hrp, data) {
+	case bech32Const:
+		encoding = Bech32
+	case bech32mConst:
+		encoding = Bech32m
+	default:
+		return "", nil, 0, ErrBech32InvalidChecksum
+	}
+
+	return hrp, data[:len(data)-bech32ChecksumLength], encoding, nil
+}
+
+// ConvertBits regroups data from fromBits to toBits bits per byte. With pad
+// the last group is zero padded, otherwise incomplete or non-zero padding is
+// an error.
+func ConvertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc, bits uint
+	maxValue := uint(1)<<toBits - 1
+	result := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, b := range data {
+		if uint(b)>>fromBits != 0 {
+			return nil, ErrBech32InvalidCharacter
+		}
+		acc = acc<<fromBits | uint(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte(acc>>bits&maxValue))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			result = append(result, byte(acc<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxValue != 0 {
+		return nil, ErrBech32InvalidPadding
+	}
+	return result, nil
+}
+
+// EncodeSegWitAddress encodes a witness version and program as a BIP173
+// (version 0) or BIP350 (versions 1 to 16) address.
+func EncodeSegWitAddress(hrp string, version byte, program []byte) (string, error) {
+	if err := validateWitnessProgram(version, program); err != nil {
+		return "", err
+	}
+
+	encoding := Bech32m
+	if version == 0 {
+		encoding = Bech32
+	}
+
+	data, err := ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return Bech32Encode(hrp, append([]byte{version}, data...), encoding)
+}
+
+// DecodeSegWitAddress decodes a native SegWit address, checking its hrp,
+// encoding, witness version and program length.
+func DecodeSegWitAddress(hrp string, address string) (byte, []byte, error) {
+	decodedHRP, data, encoding, err := Bech32Decode(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if decodedHRP != strings.ToLower(hrp) {
+		return 0, nil, ErrBech32HRPMismatch
+	}
+	if len(data) == 0 {
+		return 0, nil, ErrInvalidWitnessProgramLength
+	}
+
+	version := data[0]
+	if version > 16 {
+		return 0, nil, ErrInvalidWitnessVersion
+	}
+	if (version == 0) != (encoding == Bech32) {
+		return 0, nil, ErrWitnessEncodingMismatch
+	}
+
+	program, err := ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := validateWitnessProgram(version, program); err != nil {
+		return 0, nil, err
+	}
+	return version, program, nil
+}
+
+func validateHRP(hrp string) error {
+	if len(hrp) == 0 {
+		return ErrBech32InvalidHRP
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return ErrBech32InvalidHRP
+		}
+	}
+	return nil
+}
+
+func validateWitnessProgram(version byte, program []byte) error {
+	if version > 16 {
+		return ErrInvalidWitnessVersion
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return ErrInvalidWitnessProgramLength
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return ErrInvalidWitnessProgramLength
+	}
+	return nil
+}
+
+func bech32Polymod(hrp string, data []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	step := func(v byte) {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] >> 5)
+	}
+	step(0)
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] & 31)
+	}
+	for _, v := range data {
+		step(v)
+	}
+	return chk
+}
+
+func bech32Checksum(hrp string, data []byte, encoding Bech32Encoding) []byte {
+	constant := uint32(bech32Const)
+	if encoding == Bech32m {
+		constant = bech32mConst
+	}
+
+	values := append(append([]byte{}, data...), make([]byte, bech32ChecksumLength)...)
+	polymod := bech32Polymod(hrp, values) ^ constant
+	checksum := make([]byte, bech32ChecksumLength)
+	for i := range checksum {
+		checksum[i] = byte(polymod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
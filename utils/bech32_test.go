@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestBech32ValidVectors(t *testing.T) {
+	tests := []struct {
+		s        string
+		encoding Bech32Encoding
+	}{
+		// BIP173
+		{"A12UEL5L", Bech32},
+		{"a12uel5l", Bech32},
+		{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", Bech32},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", Bech32},
+		{"11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqc8247j", Bech32},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", Bech32},
+		{"?1ezyfcl", Bech32},
+		// BIP350
+		{"A1LQFN3A", Bech32m},
+		{"a1lqfn3a", Bech32m},
+		{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", Bech32m},
+		{"11llllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllludsr8", Bech32m},
+		{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", Bech32m},
+		{"?1v759aa", Bech32m},
+	}
+	for _, test := range tests {
+		hrp, data, encoding, err := Bech32Decode(test.s)
+		if err != nil {
+			t.Errorf("%s: %v", test.s, err)
+			continue
+		}
+		if encoding != test.encoding {
+			t.Errorf("%s: encoding %d, want %d", test.s, encoding, test.encoding)
+		}
+		encoded, err := Bech32Encode(hrp, data, encoding)
+		if err != nil {
+			t.Errorf("%s: %v", test.s, err)
+		} else if encoded != strings.ToLower(test.s) {
+			t.Errorf("%s: re-encoded as %s", test.s, encoded)
+		}
+	}
+}
+
+func TestBech32InvalidVectors(t *testing.T) {
+	tests := []struct {
+		s   string
+		err error
+	}{
+		// BIP173
+		{"\x201nwldj5", ErrBech32InvalidHRP},
+		{"\x7f1axkwrx", ErrBech32InvalidHRP},
+		{"\x801eym55h", ErrBech32InvalidHRP},
+		{"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", ErrBech32InvalidLength},
+		{"pzry9x0s0muk", ErrBech32MissingSeparator},
+		{"1pzry9x0s0muk", ErrBech32InvalidHRP},
+		{"x1b4n0q5v", ErrBech32InvalidCharacter},
+		{"li1dgmt3", ErrBech32InvalidLength},
+		{"de1lg7wt\xff", ErrBech32InvalidCharacter},
+		{"A1G7SGD8", ErrBech32InvalidChecksum},
+		{"10a06t8", ErrBech32InvalidHRP},
+		{"1qzzfhee", ErrBech32InvalidHRP},
+		// BIP350
+		{"\x201xj0phk", ErrBech32InvalidHRP},
+		{"\x7f1g6xzxy", ErrBech32InvalidHRP},
+		{"\x801vctc34", ErrBech32InvalidHRP},
+		{"qyrz8wqd2c9m", ErrBech32MissingSeparator},
+		{"1qyrz8wqd2c9m", ErrBech32InvalidHRP},
+		{"y1b0jsk6g", ErrBech32InvalidCharacter},
+		{"lt1igcx5c0", ErrBech32InvalidCharacter},
+		{"in1muywd", ErrBech32InvalidLength},
+		{"mm1crxm3i", ErrBech32InvalidCharacter},
+		{"au1s5cgom", ErrBech32InvalidCharacter},
+		{"M1VUXWEZ", ErrBech32InvalidChecksum},
+		{"16plkw9", ErrBech32InvalidHRP},
+		{"1p2gdwpf", ErrBech32InvalidHRP},
+		{"a1Lqfn3a", ErrBech32MixedCase},
+	}
+	for _, test := range tests {
+		if _, _, _, err := Bech32Decode(test.s); err != test.err {
+			t.Errorf("%q: got %v, want %v", test.s, err, test.err)
+		}
+	}
+}
+
+func TestSegWitAddressValidVectors(t *testing.T) {
+	tests := []struct {
+		address  string
+		pkScript string
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", "6002751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "5210751e76e8199196d454941c45d1b3a323"},
+		{"tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy", "0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+	for _, test := range tests {
+		hrp := strings.ToLower(test.address[:2])
+		version, program, err := DecodeSegWitAddress(hrp, test.address)
+		if err != nil {
+			t.Errorf("%s: %v", test.address, err)
+			continue
+		}
+		op := version
+		if version != 0 {
+			op += 0x50
+		}
+		pkScript := hex.EncodeToString(append([]byte{op, byte(len(program))}, program...))
+		if pkScript != test.pkScript {
+			t.Errorf("%s: script %s, want %s", test.address, pkScript, test.pkScript)
+		}
+		address, err := EncodeSegWitAddress(hrp, version, program)
+		if err != nil {
+			t.Errorf("%s: %v", test.address, err)
+		} else if address != strings.ToLower(test.address) {
+			t.Errorf("%s: re-encoded as %s", test.address, address)
+		}
+	}
+}
+
+func TestSegWitAddressInvalidVectors(t *testing.T) {
+	tests := []struct {
+		address string
+		err     error
+	}{
+		// BIP173
+		{"tc1qw508d6qejxtdg4y5r3zarvary0c5xw7kg3g4ty", ErrBech32HRPMismatch},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", ErrBech32InvalidChecksum},
+		{"BC13W508D6QEJXTDG4Y5R3ZARVARY0C5XW7KN40WF2", ErrInvalidWitnessVersion},
+		{"bc1rw5uspcuh", ErrWitnessEncodingMismatch},
+		{"bc10w508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kw5rljs90", ErrWitnessEncodingMismatch},
+		{"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P", ErrInvalidWitnessProgramLength},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sL5k7", ErrBech32MixedCase},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvqyzf3du", ErrWitnessEncodingMismatch},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3pjxtptv", ErrBech32InvalidPadding},
+		{"bc1gmk9yu", ErrInvalidWitnessProgramLength},
+		// BIP350
+		{"tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut", ErrBech32HRPMismatch},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", ErrWitnessEncodingMismatch},
+		{"tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf", ErrWitnessEncodingMismatch},
+		{"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL", ErrWitnessEncodingMismatch},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", ErrWitnessEncodingMismatch},
+		{"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47", ErrWitnessEncodingMismatch},
+		{"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4", ErrBech32InvalidCharacter},
+		{"BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R", ErrInvalidWitnessVersion},
+		{"bc1pw5dgrnzv", ErrInvalidWitnessProgramLength},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav", ErrInvalidWitnessProgramLength},
+		{"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P", ErrInvalidWitnessProgramLength},
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq", ErrBech32MixedCase},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf", ErrBech32InvalidPadding},
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j", ErrBech32InvalidPadding},
+	}
+	for _, test := range tests {
+		hrp := "bc"
+		if strings.HasPrefix(strings.ToLower(test.address), "t") {
+			hrp = "tb"
+		}
+		if _, _, err := DecodeSegWitAddress(hrp, test.address); err != test.err {
+			t.Errorf("%s: got %v, want %v", test.address, err, test.err)
+		}
+	}
+}
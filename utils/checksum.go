@@ -29,6 +29,9 @@ func AddChecksumToBytes(data []byte) ([]byte, error) {
 }
 
 func ValidateChecksum(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, ErrInvalidChecksum
+	}
 	cs1, err := Checksum(data[0 : len(data)-4])
 	if err != nil {
 		return nil, err
@@ -23,8 +23,14 @@ var (
 	ErrInvalidPublicKey = errors.New("Invalid public key")
 )
 
+// PublicKeyForPrivateKey returns the compressed public key of a 32 bytes
+// private key, or nil if the private key is invalid
 func PublicKeyForPrivateKey(key []byte) []byte {
-	return compressPublicKey(curve.ScalarBaseMult(key))
+	priv, err := crypto.PrivateKeyFromBytes(key)
+	if err != nil {
+		return nil
+	}
+	return priv.PublicKey().SerializeCompressed()
 }
 
 
@@ -41,7 +47,7 @@ func ValidatePrivateKey(key []byte) error {
 func ValidateChildPublicKey(key []byte) error {
 	x, y := expandPublicKey(key)
 
-	if x.Sign() == 0 || y.Sign() == 0 {
+	if x == nil || x.Sign() == 0 || y.Sign() == 0 {
 		return ErrInvalidPublicKey
 	}
 
@@ -49,69 +55,100 @@ func ValidateChildPublicKey(key []byte) error {
 }
 
 
-func AddPublicKeys(key1 []byte, key2 []byte) []byte {
-	x1, y1 := expandPublicKey(key1)
-	x2, y2 := expandPublicKey(key2)
-	return compressPublicKey(curve.Add(x1, y1, x2, y2))
+// ValidatePublicKey checks that key is a 33 bytes compressed public key of a
+// point on the curve.
+func ValidatePublicKey(key []byte) error {
+	if len(key) != PublicKeyCompressedLength {
+		return ErrInvalidPublicKey
+	}
+	_, _, err := ParsePublicKey(key)
+	return err
 }
 
-func AddPrivateKeys(key1 []byte, key2 []byte) []byte {
-	var key1Int big.Int
-	var key2Int big.Int
-	key1Int.SetBytes(key1)
-	key2Int.SetBytes(key2)
-
-	key1Int.Add(&key1Int, &key2Int)
-	key1Int.Mod(&key1Int, curve.Params().N)
-
-	b := key1Int.Bytes()
-	if len(b) < 32 {
-		extra := make([]byte, 32-len(b))
-		b = append(extra, b...)
+// VerifySignature reports whether sig is a valid signature of hash by the
+// compressed or uncompressed public key.
+func VerifySignature(publicKey []byte, hash []byte, sig *crypto.Signature) bool {
+	x, y, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return false
 	}
-	return b
+	return crypto.Verify(x, y, hash, sig)
 }
 
-func compressPublicKey(x *big.Int, y *big.Int) []byte {
-	var key bytes.Buffer
-
-	// Write header; 0x2 for even y value; 0x3 for odd
-	key.WriteByte(byte(0x2) + byte(y.Bit(0)))
-
-	// Write X coord; Pad the key so x is aligned with the LSB. Pad size is key length - header size (1) - xBytes size
-	xBytes := x.Bytes()
-	for i := 0; i < (PublicKeyCompressedLength - 1 - len(xBytes)); i++ {
-		key.WriteByte(0x0)
+// RecoverPublicKey returns the compressed public key that produced a 65 bytes
+// R || S || V recoverable signature of hash.
+func RecoverPublicKey(hash []byte, sig []byte) ([]byte, error) {
+	x, y, err := crypto.RecoverPublicKey(hash, sig)
+	if err != nil {
+		return nil, err
 	}
-	key.Write(xBytes)
-
-	return key.Bytes()
+	return compressPublicKey(x, y), nil
 }
 
-// As described at https://crypto.stackexchange.com/a/8916
-func expandPublicKey(key []byte) (*big.Int, *big.Int) {
-	Y := big.NewInt(0)
-	X := big.NewInt(0)
-	X.SetBytes(key[1:])
 
-	// y^2 = x^3 + ax^2 + b
-	// a = 0
-	// => y^2 = x^3 + b
-	ySquared := big.NewInt(0)
-	ySquared.Exp(X, big.NewInt(3), nil)
-	ySquared.Add(ySquared, curveParams.B)
+// AddPublicKeys returns the compressed sum of two public keys, or nil if
+// either key is invalid
+func AddPublicKeys(key1 []byte, key2 []byte) []byte {
+	pub1, err := crypto.ParsePublicKey(key1)
+	if err != nil {
+		return nil
+	}
+	pub2, err := crypto.ParsePublicKey(key2)
+	if err != nil {
+		return nil
+	}
+	sum, err := pub1.Add(pub2)
+	if err != nil {
+		return nil
+	}
+	return sum.SerializeCompressed()
+}
 
-	Y.ModSqrt(ySquared, curveParams.P)
+// MultiplyPublicKey returns the compressed public key scalar * key, or nil
+// if the key or the 32 bytes scalar is invalid
+func MultiplyPublicKey(key []byte, scalar []byte) []byte {
+	pub, err := crypto.ParsePublicKey(key)
+	if err != nil {
+		return nil
+	}
+	product, err := pub.TweakMul(scalar)
+	if err != nil {
+		return nil
+	}
+	return product.SerializeCompressed()
+}
 
-	Ymod2 := big.NewInt(0)
-	Ymod2.Mod(Y, big.NewInt(2))
+// AddPrivateKeys returns (key1 + key2) mod N, or nil if key1 isn't below N,
+// key2 isn't a valid private key or the sum is zero
+func AddPrivateKeys(key1 []byte, key2 []byte) []byte {
+	priv, err := crypto.PrivateKeyFromBytes(key2)
+	if err != nil {
+		return nil
+	}
+	sum, err := priv.TweakAdd(key1)
+	if err != nil {
+		return nil
+	}
+	return sum.Serialize()
+}
 
-	signY := uint64(key[0]) - 2
-	if signY != Ymod2.Uint64() {
-		Y.Sub(curveParams.P, Y)
+// compressPublicKey returns the compressed encoding of (x, y), or nil for the
+// point at infinity
+func compressPublicKey(x *big.Int, y *big.Int) []byte {
+	if x == nil || y == nil {
+		return nil
 	}
+	return SerializeCompressed(x, y)
+}
 
-	return X, Y
+// expandPublicKey returns the point of a compressed or uncompressed public
+// key, or nil, nil if the key is invalid
+func expandPublicKey(key []byte) (*big.Int, *big.Int) {
+	x, y, err := ParsePublicKey(key)
+	if err != nil {
+		return nil, nil
+	}
+	return x, y
 }
 
 func WIFEncode(prefix int, secret []byte, compressed bool) (string, error) {
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+// Network holds the parameters that make keys and addresses specific to a
+// chain.
+type Network struct {
+	Name              string
+	P2PKHPrefix       int    // base58check version of pay to public key hash addresses
+	P2SHPrefix        int    // base58check version of pay to script hash addresses
+	WIFPrefix         int    // base58check version of WIF private keys
+	Bech32HRP         string // human readable part of SegWit addresses, empty without SegWit
+	PrivateKeyVersion []byte // bip32 extended private key version, e.g. xprv
+	PublicKeyVersion  []byte // bip32 extended public key version, e.g. xpub
+	CoinType          uint32 // bip44 coin type
+
+	// ScriptVersions maps script types to their SLIP-132 versions, nil when
+	// the network only uses PrivateKeyVersion and PublicKeyVersion
+	ScriptVersions map[ScriptType]ExtendedKeyVersions
+}
+
+var (
+	// MainNet is the Bitcoin main network
+	MainNet = &Network{
+		Name:              "mainnet",
+		P2PKHPrefix:       0x00,
+		P2SHPrefix:        0x05,
+		WIFPrefix:         0x80,
+		Bech32HRP:         "bc",
+		PrivateKeyVersion: mustDecodeHex("0488ADE4"),
+		PublicKeyVersion:  mustDecodeHex("0488B21E"),
+		CoinType:          0,
+		ScriptVersions:    mainNetScriptVersions,
+	}
+
+	// TestNet is the Bitcoin test network
+	TestNet = &Network{
+		Name:              "testnet",
+		P2PKHPrefix:       0x6f,
+		P2SHPrefix:        0xc4,
+		WIFPrefix:         0xef,
+		Bech32HRP:         "tb",
+		PrivateKeyVersion: mustDecodeHex("04358394"),
+		PublicKeyVersion:  mustDecodeHex("043587CF"),
+		CoinType:          1,
+		ScriptVersions:    testNetScriptVersions,
+	}
+
+	// SigNet is the Bitcoin signet, which shares testnet's prefixes
+	SigNet = &Network{
+		Name:              "signet",
+		P2PKHPrefix:       0x6f,
+		P2SHPrefix:        0xc4,
+		WIFPrefix:         0xef,
+		Bech32HRP:         "tb",
+		PrivateKeyVersion: mustDecodeHex("04358394"),
+		PublicKeyVersion:  mustDecodeHex("043587CF"),
+		CoinType:          1,
+		ScriptVersions:    testNetScriptVersions,
+	}
+
+	// RegTest is the Bitcoin regression test network
+	RegTest = &Network{
+		Name:              "regtest",
+		P2PKHPrefix:       0x6f,
+		P2SHPrefix:        0xc4,
+		WIFPrefix:         0xef,
+		Bech32HRP:         "bcrt",
+		PrivateKeyVersion: mustDecodeHex("04358394"),
+		PublicKeyVersion:  mustDecodeHex("043587CF"),
+		CoinType:          1,
+		ScriptVersions:    testNetScriptVersions,
+	}
+
+	// LitecoinMainNet is the Litecoin main network. Its extended keys use the
+	// Ltpv/Ltub versions so they can't be mistaken for Bitcoin xprv/xpub keys
+	LitecoinMainNet = &Network{
+		Name:              "litecoin",
+		P2PKHPrefix:       0x30,
+		P2SHPrefix:        0x32,
+		WIFPrefix:         0xb0,
+		Bech32HRP:         "ltc",
+		PrivateKeyVersion: mustDecodeHex("019D9CFE"),
+		PublicKeyVersion:  mustDecodeHex("019DA462"),
+		CoinType:          2,
+	}
+
+	// DogecoinMainNet is the Dogecoin main network, which has no SegWit
+	DogecoinMainNet = &Network{
+		Name:              "dogecoin",
+		P2PKHPrefix:       0x1e,
+		P2SHPrefix:        0x16,
+		WIFPrefix:         0x9e,
+		Bech32HRP:         "",
+		PrivateKeyVersion: mustDecodeHex("02FAC398"),
+		PublicKeyVersion:  mustDecodeHex("02FACAFD"),
+		CoinType:          3,
+	}
+
+	// Networks lists the known networks. Lookups return the first match, so
+	// networks sharing parameters resolve to the earlier entry, e.g. signet
+	// and regtest extended keys to TestNet
+	Networks = []*Network{MainNet, TestNet, SigNet, RegTest, LitecoinMainNet, DogecoinMainNet}
+
+	// ErrUnknownNetwork is returned when no known network matches
+	ErrUnknownNetwork = errors.New("Unknown network")
+
+	// ErrSegWitNotSupported is returned when building a SegWit address for a
+	// network without a bech32 human readable part
+	ErrSegWitNotSupported = errors.New("Network doesn't support SegWit")
+
+	// ErrAddressNetworkMismatch is returned when an address belongs to
+	// another network
+	ErrAddressNetworkMismatch = errors.New("Address doesn't belong to the network")
+
+	// ErrExtendedKeyNetworkMismatch is returned when extended key version
+	// bytes belong to another network
+	ErrExtendedKeyNetworkMismatch = errors.New("Extended key version doesn't belong to the network")
+)
+
+// NetworkByName returns the known network with the given name.
+func NetworkByName(name string) (*Network, error) {
+	for _, net := range Networks {
+		if net.Name == name {
+			return net, nil
+		}
+	}
+	return nil, ErrUnknownNetwork
+}
+
+// HasSegWit reports whether the network has native SegWit addresses.
+func (net *Network) HasSegWit() bool {
+	return net.Bech32HRP != ""
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
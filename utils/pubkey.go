@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"math/big"
+
+	"github.com/icodeface/go-blockchain-kit/crypto"
+)
+
+const PublicKeyUncompressedLength = 65
+
+var (
+	// ErrInvalidPublicKeyFormat is returned when a public key has an unknown
+	// prefix or a length that doesn't match it
+	ErrInvalidPublicKeyFormat = crypto.ErrInvalidPublicKeyFormat
+
+	// ErrHybridPublicKey is returned when parsing a hybrid (0x06/0x07) public
+	// key without allowing them
+	ErrHybridPublicKey = crypto.ErrHybridPublicKey
+
+	// ErrPublicKeyNotOnCurve is returned when a public key isn't a point on
+	// the curve
+	ErrPublicKeyNotOnCurve = crypto.ErrPublicKeyNotOnCurve
+)
+
+// ParsePublicKey parses a 33 bytes compressed (0x02/0x03) or 65 bytes
+// uncompressed (0x04) public key and checks the point is on the curve.
+// Hybrid keys are rejected.
+func ParsePublicKey(key []byte) (x, y *big.Int, err error) {
+	pub, err := crypto.ParsePublicKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub.X, pub.Y, nil
+}
+
+// ParsePublicKeyAllowHybrid is like ParsePublicKey but also accepts 65 bytes
+// hybrid (0x06/0x07) public keys whose prefix matches the parity of y.
+func ParsePublicKeyAllowHybrid(key []byte) (x, y *big.Int, err error) {
+	pub, err := crypto.ParsePublicKeyAllowHybrid(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub.X, pub.Y, nil
+}
+
+// SerializeCompressed returns the 33 bytes compressed encoding of (x, y).
+func SerializeCompressed(x, y *big.Int) []byte {
+	return (&crypto.PublicKey{X: x, Y: y}).SerializeCompressed()
+}
+
+// SerializeUncompressed returns the 65 bytes uncompressed encoding of (x, y).
+func SerializeUncompressed(x, y *big.Int) []byte {
+	return (&crypto.PublicKey{X: x, Y: y}).SerializeUncompressed()
+}
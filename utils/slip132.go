@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ScriptType is the kind of output an extended key's addresses pay to, as
+// signalled by SLIP-132 version bytes.
+type ScriptType int
+
+const (
+	ScriptP2PKH      ScriptType = iota // xpub, bip44
+	ScriptP2SHP2WPKH                   // ypub, bip49
+	ScriptP2WPKH                       // zpub, bip84
+	ScriptP2SHP2WSH                    // Ypub, multisig nested SegWit
+	ScriptP2WSH                        // Zpub, multisig native SegWit
+)
+
+// ExtendedKeyVersions are the private and public bip32 version bytes of a
+// script type.
+type ExtendedKeyVersions struct {
+	Private []byte
+	Public  []byte
+}
+
+// ErrUnsupportedScriptType is returned when a network has no version bytes
+// for a script type
+var ErrUnsupportedScriptType = errors.New("Script type is not supported on the network")
+
+var (
+	mainNetScriptVersions = map[ScriptType]ExtendedKeyVersions{
+		ScriptP2PKH:      {mustDecodeHex("0488ADE4"), mustDecodeHex("0488B21E")},
+		ScriptP2SHP2WPKH: {mustDecodeHex("049D7878"), mustDecodeHex("049D7CB2")},
+		ScriptP2WPKH:     {mustDecodeHex("04B2430C"), mustDecodeHex("04B24746")},
+		ScriptP2SHP2WSH:  {mustDecodeHex("0295B005"), mustDecodeHex("0295B43F")},
+		ScriptP2WSH:      {mustDecodeHex("02AA7A99"), mustDecodeHex("02AA7ED3")},
+	}
+
+	testNetScriptVersions = map[ScriptType]ExtendedKeyVersions{
+		ScriptP2PKH:      {mustDecodeHex("04358394"), mustDecodeHex("043587CF")},
+		ScriptP2SHP2WPKH: {mustDecodeHex("044A4E28"), mustDecodeHex("044A5262")},
+		ScriptP2WPKH:     {mustDecodeHex("045F18BC"), mustDecodeHex("045F1CF6")},
+		ScriptP2SHP2WSH:  {mustDecodeHex("024285B5"), mustDecodeHex("024289EF")},
+		ScriptP2WSH:      {mustDecodeHex("02575048"), mustDecodeHex("02575483")},
+	}
+)
+
+// ExtendedKeyVersion returns the SLIP-132 version bytes of a script type on
+// the network. Networks without SLIP-132 versions only support ScriptP2PKH.
+func (net *Network) ExtendedKeyVersion(scriptType ScriptType, private bool) ([]byte, error) {
+	versions, ok := net.ScriptVersions[scriptType]
+	if !ok {
+		if scriptType != ScriptP2PKH {
+			return nil, ErrUnsupportedScriptType
+		}
+		versions = ExtendedKeyVersions{net.PrivateKeyVersion, net.PublicKeyVersion}
+	}
+	if private {
+		return versions.Private, nil
+	}
+	return versions.Public, nil
+}
+
+// ParseExtendedKeyVersion returns the network and script type of bip32
+// version bytes and whether they belong to a private key. Versions shared by
+// several networks resolve to the first one in Networks, so signet and
+// regtest keys come back as TestNet; use Network.ParseExtendedKeyVersion
+// when the network is known.
+func ParseExtendedKeyVersion(version []byte) (*Network, ScriptType, bool, error) {
+	for _, net := range Networks {
+		scriptType, private, err := net.ParseExtendedKeyVersion(version)
+		if err == nil {
+			return net, scriptType, private, nil
+		}
+	}
+	return nil, ScriptP2PKH, false, ErrUnknownNetwork
+}
+
+// ParseExtendedKeyVersion returns the script type of bip32 version bytes of
+// the network and whether they belong to a private key.
+func (net *Network) ParseExtendedKeyVersion(version []byte) (ScriptType, bool, error) {
+	if bytes.Equal(version, net.PrivateKeyVersion) {
+		return ScriptP2PKH, true, nil
+	}
+	if bytes.Equal(version, net.PublicKeyVersion) {
+		return ScriptP2PKH, false, nil
+	}
+	for scriptType, versions := range net.ScriptVersions {
+		if bytes.Equal(version, versions.Private) {
+			return scriptType, true, nil
+		}
+		if bytes.Equal(version, versions.Public) {
+			return scriptType, false, nil
+		}
+	}
+	return ScriptP2PKH, false, ErrExtendedKeyNetworkMismatch
+}
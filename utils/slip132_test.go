@@ -0,0 +1,37 @@
+package utils
+
+import "testing"
+
+func TestParseExtendedKeyVersion(t *testing.T) {
+	scriptTypes := []ScriptType{ScriptP2PKH, ScriptP2SHP2WPKH, ScriptP2WPKH, ScriptP2SHP2WSH, ScriptP2WSH}
+	for _, net := range []*Network{MainNet, TestNet} {
+		for _, scriptType := range scriptTypes {
+			for _, private := range []bool{true, false} {
+				version, err := net.ExtendedKeyVersion(scriptType, private)
+				if err != nil {
+					t.Fatalf("%s %d: %v", net.Name, scriptType, err)
+				}
+				gotNet, gotType, gotPrivate, err := ParseExtendedKeyVersion(version)
+				if err != nil || gotNet != net || gotType != scriptType || gotPrivate != private {
+					t.Errorf("%s %x: got %v %d %v %v", net.Name, version, gotNet, gotType, gotPrivate, err)
+				}
+			}
+		}
+	}
+
+	for _, net := range []*Network{SigNet, RegTest} {
+		scriptType, private, err := net.ParseExtendedKeyVersion(TestNet.PrivateKeyVersion)
+		if err != nil || scriptType != ScriptP2PKH || !private {
+			t.Errorf("%s: got %d %v %v", net.Name, scriptType, private, err)
+		}
+	}
+	if _, _, err := MainNet.ParseExtendedKeyVersion(TestNet.PublicKeyVersion); err != ErrExtendedKeyNetworkMismatch {
+		t.Errorf("testnet version on mainnet: got %v, want %v", err, ErrExtendedKeyNetworkMismatch)
+	}
+	if _, _, _, err := ParseExtendedKeyVersion([]byte{0, 0, 0, 0}); err != ErrUnknownNetwork {
+		t.Errorf("unknown version: got %v, want %v", err, ErrUnknownNetwork)
+	}
+	if _, err := DogecoinMainNet.ExtendedKeyVersion(ScriptP2WPKH, false); err != ErrUnsupportedScriptType {
+		t.Errorf("dogecoin zpub: got %v, want %v", err, ErrUnsupportedScriptType)
+	}
+}
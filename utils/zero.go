@@ -0,0 +1,9 @@
+package utils
+
+// Zero overwrites b with zeros, e.g. to wipe private key material once it's
+// no longer needed.
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestZero(t *testing.T) {
+	b := []byte{1, 2, 3, 4, 5}
+	Zero(b[1:4])
+	if !bytes.Equal(b, []byte{1, 0, 0, 0, 5}) {
+		t.Errorf("got %x", b)
+	}
+	// nil and empty slices are no-ops
+	Zero(nil)
+	Zero([]byte{})
+}